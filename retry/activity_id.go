@@ -0,0 +1,46 @@
+package retry
+
+import "strings"
+
+const activityIDKey = "ActivityID"
+
+// activityIDEnd are the characters ending an ActivityID
+const activityIDEnd = ", ;'\")"
+
+// parseActivityID extracts the ActivityID from a Cosmos DB error message, whether as ActivityID=<id> (gateway errors) or ActivityId: <id> (direct errors, and details nested in gateway errors), the first one being used if it is repeated. It returns an empty string if not available
+func parseActivityID(errMsg string) string {
+	for i := 0; i+len(activityIDKey) <= len(errMsg); i++ {
+		if !strings.EqualFold(errMsg[i:i+len(activityIDKey)], activityIDKey) {
+			continue
+		}
+		// the key must start a field, so that e.g. RelatedActivityID is not mistaken for it
+		if i > 0 && strings.IndexByte(activityIDEnd+"(", errMsg[i-1]) == -1 {
+			continue
+		}
+		value := strings.TrimLeft(errMsg[i+len(activityIDKey):], " ")
+		if value == "" || (value[0] != '=' && value[0] != ':') {
+			continue
+		}
+		value = strings.TrimLeft(value[1:], " ")
+		if end := strings.IndexAny(value, activityIDEnd); end != -1 {
+			value = value[:end]
+		}
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// LastActivityID returns the ActivityID of the most recent error handled by GetRetryType which carried one, so that it can be logged along with other trace IDs, e.g. for Azure support to trace the request server-side. See RetryEvent as well
+func (crp *CosmosRetryPolicy) LastActivityID() string {
+	id, _ := crp.lastActivityID.Load().(string)
+	return id
+}
+
+// recordActivityID keeps track of activityID, if any
+func (crp *CosmosRetryPolicy) recordActivityID(activityID string) {
+	if activityID != "" {
+		crp.lastActivityID.Store(activityID)
+	}
+}
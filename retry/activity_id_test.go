@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseActivityID(t *testing.T) {
+	type testCase struct {
+		name     string
+		errMsg   string
+		expected string
+	}
+
+	testCases := []testCase{
+		{"gateway error", rateLimitedErrMsg, "c268afb6-7367-4ff8-b06b-b7e2d1269f55"},
+		{"gateway error without RetryAfterMs", rateLimitedErrMsgWithoutRetryAfterMs, "c268afb6-7367-4ff8-b06b-b7e2d1269f55"},
+		{"direct error", directRateLimitedErrMsg, "0a3b7c2e-5d1f-4e0a-9a6b-3c2d1e0f9a8b"},
+		{"repeated in nested details", "Request rate is large: ActivityID=first, Additional details='TooManyRequests (429); ActivityId: second; Reason: ...'", "first"},
+		{"only in nested details", "Request rate is large: RetryAfterMs=5, Additional details='TooManyRequests (429); ActivityId: nested; Reason: ...'", "nested"},
+		{"empty, then repeated", "Request rate is large: ActivityID=, Additional details='ActivityId: nested'", "nested"},
+		{"last field", "Request rate is large: RetryAfterMs=5, ActivityID=last", "last"},
+		{"spaces around separator", "Request rate is large: ActivityId = spaced, RetryAfterMs=5", "spaced"},
+		{"lower case", "request rate is large: activityid: lower; x", "lower"},
+		{"key as part of another key", "Request rate is large: RelatedActivityId: related, ActivityId: actual", "actual"},
+		{"key without value", "ActivityID", ""},
+		{"not a Cosmos DB error", "error: today is not your day!", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, parseActivityID(tc.errMsg))
+		})
+	}
+}
+
+func TestLastActivityID(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.sleepFunc = func(time.Duration) {}
+	assert.Equal(t, "", p.LastActivityID())
+
+	p.Attempt(attemptsQuery{attempts: 1})
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+	assert.Equal(t, "c268afb6-7367-4ff8-b06b-b7e2d1269f55", p.LastActivityID())
+
+	p.Attempt(attemptsQuery{attempts: 2})
+	p.GetRetryType(errors.New(directRateLimitedErrMsg))
+	assert.Equal(t, "0a3b7c2e-5d1f-4e0a-9a6b-3c2d1e0f9a8b", p.LastActivityID())
+
+	// errors without ActivityID leave it as is
+	p.Attempt(attemptsQuery{attempts: 3})
+	p.GetRetryType(errors.New("error: today is not your day!"))
+	assert.Equal(t, "0a3b7c2e-5d1f-4e0a-9a6b-3c2d1e0f9a8b", p.LastActivityID())
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gocql/gocql"
+)
+
+const defaultSustainedThrottleThreshold = 10
+
+const sustainedThrottleAdvisory = "sustained 429s detected (%d consecutive rate limited errors); consider increasing provisioned throughput"
+const recoveryMessage = "recovered after %d throttled attempts"
+
+// recordThrottle tracks consecutive rate limited errors and logs a one-time advisory once SustainedThrottleThreshold is reached. Health check queries, and decisions made by Decide, are not accounted for
+func (crp *CosmosRetryPolicy) recordThrottle(ex *execution, rateLimited bool) {
+	if ex.healthCheck || ex.dryRun {
+		return
+	}
+	if !rateLimited {
+		atomic.StoreInt64(&crp.consecutiveThrottles, 0)
+		return
+	}
+	n := atomic.AddInt64(&crp.consecutiveThrottles, 1)
+	if crp.SustainedThrottleThreshold <= 0 || n < int64(crp.SustainedThrottleThreshold) {
+		return
+	}
+	if crp.Logger != nil && atomic.CompareAndSwapInt32(&crp.advised, 0, 1) {
+		crp.Logger.Printf(sustainedThrottleAdvisory, n)
+	}
+}
+
+var _ gocql.QueryObserver = (*CosmosRetryPolicy)(nil)
+var _ gocql.BatchObserver = (*CosmosRetryPolicy)(nil)
+
+// ObserveQuery implements gocql.QueryObserver. If LogRecovery is enabled, it logs the first successful query after sustained throttling (as per SustainedThrottleThreshold). Successful queries (other than health checks) close a half-open CircuitBreaker as well. Set it as the ClusterConfig (or Query) observer for this to take effect
+func (crp *CosmosRetryPolicy) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	crp.observe(ctx, q.Err)
+}
+
+// ObserveBatch implements gocql.BatchObserver. See ObserveQuery
+func (crp *CosmosRetryPolicy) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	crp.observe(ctx, b.Err)
+}
+
+func (crp *CosmosRetryPolicy) observe(ctx context.Context, err error) {
+	if ex, scoped := scopedExecution(ctx); scoped && err == nil {
+		crp.finish(ex, true)
+	}
+	if err == nil && crp.CircuitBreaker != nil && !IsHealthCheck(ctx) {
+		crp.CircuitBreaker.succeeded()
+	}
+	if err != nil || !crp.LogRecovery || IsHealthCheck(ctx) || atomic.LoadInt64(&crp.consecutiveThrottles) == 0 {
+		return
+	}
+	throttled := atomic.SwapInt64(&crp.consecutiveThrottles, 0)
+	if crp.Logger != nil && crp.SustainedThrottleThreshold > 0 && throttled >= int64(crp.SustainedThrottleThreshold) {
+		crp.Logger.Printf(recoveryMessage, throttled)
+	}
+}
@@ -0,0 +1,120 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+type capturingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (cl *capturingLogger) Printf(format string, v ...interface{}) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.messages = append(cl.messages, fmt.Sprintf(format, v...))
+}
+
+func (cl *capturingLogger) count(substr string) int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	n := 0
+	for _, m := range cl.messages {
+		if strings.Contains(m, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSustainedThrottleAdvisoryFiresOnce(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(-1)
+	p.SustainedThrottleThreshold = 3
+	p.Logger = logger
+
+	for i := 0; i < 2; i++ {
+		retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	}
+	assert.Equal(t, 0, logger.count("sustained 429s detected"), "advisory logged before threshold")
+
+	for i := 0; i < 5; i++ {
+		retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	}
+	assert.Equal(t, 1, logger.count("sustained 429s detected"), "advisory should be logged exactly once")
+}
+
+func TestSustainedThrottleAdvisoryNeedsConsecutiveThrottles(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(-1)
+	p.SustainedThrottleThreshold = 3
+	p.Logger = logger
+
+	for i := 0; i < 4; i++ {
+		retryTypeOf(p, errors.New(rateLimitedErrMsg))
+		retryTypeOf(p, &gocql.RequestErrReadTimeout{})
+	}
+	assert.Equal(t, 0, logger.count("sustained 429s detected"))
+}
+
+func TestSustainedThrottleAdvisoryDisabled(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(-1)
+	p.SustainedThrottleThreshold = 0
+	p.Logger = logger
+
+	for i := 0; i < 5; i++ {
+		retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	}
+	assert.Empty(t, logger.messages)
+}
+
+func TestRecoveryLoggedOnceAfterSustainedThrottling(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(-1)
+	p.SustainedThrottleThreshold = 3
+	p.LogRecovery = true
+	p.Logger = logger
+
+	for i := 0; i < 4; i++ {
+		retryTypeOf(p, errors.New(rateLimitedErrMsg))
+		p.ObserveQuery(context.Background(), gocql.ObservedQuery{Err: errors.New(rateLimitedErrMsg)})
+	}
+	assert.Equal(t, 0, logger.count("recovered after"))
+
+	for i := 0; i < 3; i++ {
+		p.ObserveQuery(context.Background(), gocql.ObservedQuery{})
+	}
+	assert.Equal(t, 1, logger.count("recovered after 4 throttled attempts"), "recovery should be logged exactly once")
+}
+
+func TestRecoveryNotLoggedWithoutSustainedThrottling(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(-1)
+	p.SustainedThrottleThreshold = 3
+	p.LogRecovery = true
+	p.Logger = logger
+
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	p.ObserveBatch(context.Background(), gocql.ObservedBatch{})
+	assert.Equal(t, 0, logger.count("recovered after"))
+}
+
+func TestRecoveryNotLoggedByDefault(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(-1)
+	p.SustainedThrottleThreshold = 1
+	p.Logger = logger
+
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	p.ObserveQuery(context.Background(), gocql.ObservedQuery{})
+	assert.Equal(t, 0, logger.count("recovered after"))
+}
@@ -0,0 +1,221 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// execution holds the retry state of a single query execution, from its first failed attempt until it succeeds or is given up on
+type execution struct {
+	attempts    int
+	consistency gocql.Consistency
+	healthCheck bool
+	idempotent  bool
+	requestID   string
+	// ctx is the context of the query, which cancels back-off sleeps
+	ctx context.Context
+	// query is the query being attempted, whose consistency level may be downgraded
+	query gocql.RetryableQuery
+	// start is the time of the first failed attempt, as per the policy clock
+	start     time.Time
+	totalWait time.Duration
+	// backOff is the back-off decided for the latest error, if hasBackOff, to be slept before the next attempt (see CosmosRetryPolicy.backOff). Immediate retries have none
+	backOff    time.Duration
+	hasBackOff bool
+	hostHops   int
+	// rateLimitedRetries is the number of retries of rate limited errors
+	rateLimitedRetries int
+	// unavailable is the number of consecutive RequestErrUnavailable errors
+	unavailable int
+	// rateLimited and notRateLimited record whether rate limited (429) errors, and other errors, have been decided for the execution
+	rateLimited    bool
+	notRateLimited bool
+	// activityID is the ActivityID of the latest error, if any
+	activityID string
+	// warned is set once SoftRetryWarnThreshold has been reported for the execution
+	warned bool
+	// releaseSlot releases the Limiter slot held by the execution, if any (see AcquireSlot)
+	releaseSlot func()
+	kind        ErrorKind
+	// decision is the latest retry decision made for the execution, and path the code path which handled it (one of the DecisionPath constants)
+	decision gocql.RetryType
+	path     string
+	// shared is set for copies of the execution shared by queries which are not scoped
+	shared bool
+	// dryRun is set for executions of Decide, whose decisions are neither slept nor accounted for by the policy
+	dryRun bool
+	// done is set once the execution has been given up on (or observed to succeed), so that the next attempt in the same scope starts afresh
+	done bool
+}
+
+// next accounts for another failed attempt, starting a new execution if the previous one is done
+func (ex *execution) next() {
+	if ex.done {
+		*ex = execution{}
+	}
+	ex.attempts++
+}
+
+type attemptScopeKey struct{}
+
+// WithAttemptScope returns a copy of ctx which scopes the attempt count (and back-off progression) of queries executed with it. By default, attempts are tracked per policy using the query attempt count, which is shared by every execution of the same gocql.Query, e.g. concurrent paging iterators. Use a separate scope for each of them
+//
+//	iter := query.WithContext(retry.WithAttemptScope(ctx)).Iter()
+//
+// A scope is reset once its query is given up on or, if the policy is registered as the gocql query observer, once it succeeds
+func WithAttemptScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attemptScopeKey{}, &execution{})
+}
+
+// scopedExecution returns the execution tracked by ctx, if it has been tagged using WithAttemptScope
+func scopedExecution(ctx context.Context) (*execution, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	ex, ok := ctx.Value(attemptScopeKey{}).(*execution)
+	return ex, ok
+}
+
+// queryContext returns the context of rq, or context.Background() if it has none, as may be the case for mocks and wrappers of gocql queries
+func queryContext(rq gocql.RetryableQuery) context.Context {
+	if ctx := rq.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// maxPendingExecutions bounds the number of executions handed off by Attempt and not claimed yet. It is only reached if Attempt is called without a subsequent GetRetryType on many goroutines, which gocql never does, in which case one of the (abandoned) executions is dropped
+const maxPendingExecutions = 1024
+
+// goroutineID returns the id of the calling goroutine, as reported in its stack trace
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// handOff passes ex from Attempt, made on the goroutine with the given id, to the GetRetryType call that gocql makes right after it, on the same goroutine. GetRetryType is not passed the query, hence executions are keyed by the goroutine they are attempted on, which is all that ties the two calls together: a concurrent query, scoped or not, never claims the execution of another one, whatever the order of their calls
+func (crp *CosmosRetryPolicy) handOff(id uint64, ex *execution) {
+	crp.pendingMu.Lock()
+	defer crp.pendingMu.Unlock()
+	if len(crp.pending) >= maxPendingExecutions {
+		for other, abandoned := range crp.pending {
+			crp.releaseSlot(abandoned)
+			delete(crp.pending, other)
+			break
+		}
+	}
+	if crp.pending == nil {
+		crp.pending = make(map[uint64]*execution)
+	}
+	crp.pending[id] = ex
+}
+
+// dropAbandoned drops the execution still pending on the goroutine with the given id, if any, which was attempted without a subsequent GetRetryType, releasing its Limiter slot
+func (crp *CosmosRetryPolicy) dropAbandoned(id uint64) {
+	crp.pendingMu.Lock()
+	defer crp.pendingMu.Unlock()
+	if abandoned, ok := crp.pending[id]; ok {
+		crp.releaseSlot(abandoned)
+		delete(crp.pending, id)
+	}
+}
+
+// claimExecution returns the execution handed off by Attempt on the calling goroutine or, if there is none (GetRetryType was called on its own), a copy of the shared one
+func (crp *CosmosRetryPolicy) claimExecution() *execution {
+	id := goroutineID()
+	crp.pendingMu.Lock()
+	if ex, ok := crp.pending[id]; ok {
+		delete(crp.pending, id)
+		crp.pendingMu.Unlock()
+		return ex
+	}
+	crp.pendingMu.Unlock()
+
+	crp.execMu.Lock()
+	defer crp.execMu.Unlock()
+	ex := crp.exec
+	return &ex
+}
+
+// sharedExecution returns a copy of the execution of rq, a query which is not scoped (see WithAttemptScope), updated for its number of attempts. Queries are told apart by their identity (see isTrackable), other queries share a single execution. It is a copy so that concurrent queries do not interfere with one another
+func (crp *CosmosRetryPolicy) sharedExecution(rq gocql.RetryableQuery) *execution {
+	attempts := rq.Attempts()
+	crp.execMu.Lock()
+	defer crp.execMu.Unlock()
+	base := &crp.exec
+	if isTrackable(rq) {
+		base = crp.trackedQuery(rq, crp.currentTime())
+	}
+	base.attempts = attempts
+	if attempts <= 1 {
+		base.start = time.Time{}
+		base.totalWait = 0
+		base.hostHops = 0
+		base.unavailable = 0
+		base.rateLimitedRetries = 0
+		base.rateLimited = false
+		base.notRateLimited = false
+		base.activityID = ""
+		base.warned = false
+	}
+	ex := *base
+	ex.shared = true
+	// the query is being attempted, hence not done, even if the previous unscoped query was
+	ex.done = false
+	return &ex
+}
+
+// storeExecution records a copy returned by sharedExecution as the latest state of the execution of its query and of the shared execution. Scoped executions are kept up to date in place
+func (crp *CosmosRetryPolicy) storeExecution(ex *execution) {
+	if !ex.shared {
+		return
+	}
+	crp.execMu.Lock()
+	defer crp.execMu.Unlock()
+	crp.exec = *ex
+	crp.exec.shared = false
+	crp.exec.ctx = nil
+	crp.exec.query = nil
+	// the Limiter slot, if any, is held by ex until its decision is made
+	crp.exec.releaseSlot = nil
+	if !isTrackable(ex.query) {
+		return
+	}
+	crp.recordQuery(ex.query, crp.exec, crp.currentTime())
+}
+
+// lastExecution returns a copy of the latest state of the shared execution
+func (crp *CosmosRetryPolicy) lastExecution() execution {
+	crp.execMu.Lock()
+	defer crp.execMu.Unlock()
+	return crp.exec
+}
+
+// Reset clears the retry state accumulated by queries which are not scoped (see WithAttemptScope), which are no longer tracked: their attempt count, cumulative back-off, host hops and run of consecutive rate limited errors, so that the next back-off starts from the base again. It is meant for reusing a policy across distinct, sequential queries, e.g. in tests or custom retry loops, and must not be called while queries are being retried. Scoped executions, metrics and counters are left as is
+func (crp *CosmosRetryPolicy) Reset() {
+	crp.pendingMu.Lock()
+	for _, ex := range crp.pending {
+		crp.releaseSlot(ex)
+	}
+	crp.pending = nil
+	crp.pendingMu.Unlock()
+
+	crp.execMu.Lock()
+	defer crp.execMu.Unlock()
+	crp.exec = execution{}
+	for rq := range crp.queries {
+		crp.untrackQuery(rq)
+	}
+	atomic.StoreInt64(&crp.consecutiveThrottles, 0)
+}
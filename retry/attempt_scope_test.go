@@ -0,0 +1,316 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// sharedAttemptsQuery mimics a gocql.Query whose attempt count is shared by every execution of it
+type sharedAttemptsQuery struct {
+	MockRetryableQuery
+	attempts *int64
+}
+
+func (sq sharedAttemptsQuery) Attempts() int {
+	return int(atomic.LoadInt64(sq.attempts))
+}
+
+// IsIdempotent allows timeouts to be retried
+func (sq sharedAttemptsQuery) IsIdempotent() bool {
+	return true
+}
+
+func TestAttemptScopeIsolatesConcurrentIterators(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.JitterMode = NoJitter
+	p.FixedBackOffTimeMs = 10
+	p.ImmediateRetries = 1
+
+	var shared int64
+	type result struct {
+		decisions []gocql.RetryType
+		totalWait time.Duration
+	}
+	results := make([]result, 2)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := WithAttemptScope(context.Background())
+			q := sharedAttemptsQuery{MockRetryableQuery{ctx: ctx}, &shared}
+			for {
+				// gocql bumps the (shared) query attempt count before consulting the policy
+				atomic.AddInt64(&shared, 1)
+				if !p.Attempt(q) {
+					break
+				}
+				results[i].decisions = append(results[i].decisions, p.GetRetryType(&gocql.RequestErrWriteTimeout{}))
+			}
+			ex, _ := scopedExecution(ctx)
+			results[i].totalWait = ex.totalWait
+		}(i)
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		// one immediate retry followed by two backed off ones, regardless of the other iterator
+		assert.Equal(t, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Retry}, r.decisions, "iterator %d", i)
+		assert.Equal(t, 20*time.Millisecond, r.totalWait, "iterator %d", i)
+	}
+	assert.Equal(t, 40*time.Millisecond, p.BlockedTime())
+}
+
+func TestAttemptScopeStartsAfreshOnceDone(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	p.FixedBackOffTimeMs = 1
+	ctx := WithAttemptScope(context.Background())
+	q := idempotentQuery{MockRetryableQuery{ctx: ctx}, true}
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, p.Attempt(q))
+		p.GetRetryType(&gocql.RequestErrReadTimeout{})
+	}
+	assert.False(t, p.Attempt(q), "scope should be out of retries")
+	assert.True(t, p.Attempt(q), "next execution in the scope should start afresh")
+	p.GetRetryType(&gocql.RequestErrReadTimeout{})
+
+	// a success observed for the scope also starts afresh
+	p.ObserveQuery(ctx, gocql.ObservedQuery{})
+	assert.True(t, p.Attempt(q))
+	p.GetRetryType(&gocql.RequestErrReadTimeout{})
+	ex, _ := scopedExecution(ctx)
+	assert.Equal(t, 1, ex.attempts)
+}
+
+func TestInterleavedScopesDecideOnTheirOwnState(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.GrowingBackOffTimeMs = 1
+	p.JitterMode = NoJitter
+	p.SleepDisabled = true
+	var last RetryEvent
+	p.OnRetry = func(e RetryEvent) { last = e }
+	err := errors.New(rateLimitedErrMsgWithoutRetryAfterMs)
+
+	// iterators of the same query, each with its own scope
+	var shared int64
+	a := sharedAttemptsQuery{MockRetryableQuery{ctx: WithAttemptScope(context.Background())}, &shared}
+	b := sharedAttemptsQuery{MockRetryableQuery{ctx: WithAttemptScope(context.Background())}, &shared}
+	runA, stopA := worker()
+	defer stopA()
+	runB, stopB := worker()
+	defer stopB()
+
+	// b fails 6 times before a fails for the first time
+	for i := 0; i < 6; i++ {
+		runB(func() {
+			assert.True(t, p.Attempt(b))
+			p.GetRetryType(err)
+		})
+	}
+	// b is attempted while a is between its Attempt and GetRetryType calls, and decided on last
+	runA(func() { assert.True(t, p.Attempt(a)) })
+	runB(func() { assert.True(t, p.Attempt(b)) })
+	runA(func() { p.GetRetryType(err) })
+	assert.Equal(t, 1, last.Attempt)
+	assert.Equal(t, time.Millisecond, last.BackOff)
+	runB(func() { p.GetRetryType(err) })
+	assert.Equal(t, 7, last.Attempt)
+	assert.Equal(t, 7*time.Millisecond, last.BackOff)
+}
+
+// run with -race to check that unscoped queries sharing a policy do not race on its state
+func TestConcurrentUnscopedQueriesBackOffPerOwnAttempts(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.GrowingBackOffTimeMs = 1
+	// a stable salt, so that back-offs only differ by the attempt count
+	p.ActivityIDJitter = true
+	p.EnableTracing(64)
+
+	const queries = 32
+	var wg sync.WaitGroup
+	for i := 1; i <= queries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := WithRequestID(context.Background(), fmt.Sprint(i))
+			if p.Attempt(attemptsQuery{MockRetryableQuery{ctx: ctx}, i}) {
+				p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	salt, _ := p.activityIDJitter("c268afb6-7367-4ff8-b06b-b7e2d1269f55")
+	for i := 1; i <= queries; i++ {
+		entries := p.Trace(fmt.Sprint(i))
+		if assert.Len(t, entries, 1, "query %d", i) {
+			assert.Equal(t, i, entries[0].Attempt, "query %d", i)
+			assert.Equal(t, time.Duration(i+salt)*time.Millisecond, entries[0].BackOff, "query %d", i)
+		}
+	}
+}
+
+// worker runs the functions passed to run on a goroutine of its own, one at a time, run returning once the function has returned
+func worker() (run func(func()), stop func()) {
+	fns, done := make(chan func()), make(chan struct{})
+	go func() {
+		for fn := range fns {
+			fn()
+			done <- struct{}{}
+		}
+	}()
+	return func(fn func()) {
+		fns <- fn
+		<-done
+	}, func() { close(fns) }
+}
+
+func TestInterleavedQueriesDecideOnTheirOwnState(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.SleepDisabled = true
+	p.JitterMode = NoJitter
+	p.ConsistencyBackOff = map[gocql.Consistency]time.Duration{gocql.Quorum: 300 * time.Millisecond}
+	p.DowngradeConsistencyAfter = 1
+	p.DowngradeConsistency = gocql.LocalOne
+	var last RetryEvent
+	p.OnRetry = func(e RetryEvent) { last = e }
+
+	var downgradedA, downgradedB []gocql.Consistency
+	a := consistencyQuery{MockRetryableQuery{consistency: gocql.Quorum}, 1, &downgradedA}
+	b := consistencyQuery{MockRetryableQuery{consistency: gocql.One}, 7, &downgradedB}
+	runA, stopA := worker()
+	defer stopA()
+	runB, stopB := worker()
+	defer stopB()
+
+	// b is attempted while a is between its Attempt and GetRetryType calls, and decided on last
+	runA(func() { assert.True(t, p.Attempt(a)) })
+	runB(func() { assert.True(t, p.Attempt(b)) })
+	runA(func() { assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs))) })
+	assert.Equal(t, 1, last.Attempt)
+	assert.Equal(t, 300*time.Millisecond, last.BackOff, "back-off should be as per the consistency of a")
+	runB(func() { assert.Equal(t, gocql.Retry, p.GetRetryType(&gocql.RequestErrUnavailable{})) })
+	assert.Equal(t, 7, last.Attempt)
+	assert.Equal(t, time.Duration(0), last.BackOff)
+
+	assert.Empty(t, downgradedA)
+	assert.Equal(t, []gocql.Consistency{gocql.LocalOne}, downgradedB, "the query of b should be downgraded")
+}
+
+func TestAttemptLeavesPendingExecutionsAlone(t *testing.T) {
+	p := NewCosmosRetryPolicy(10)
+	p.SleepDisabled = true
+	p.EnableTracing(10)
+	query := func(requestID string, attempts int) gocql.RetryableQuery {
+		return attemptsQuery{MockRetryableQuery{ctx: WithRequestID(context.Background(), requestID)}, attempts}
+	}
+
+	attempted, decided := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(decided)
+		assert.True(t, p.Attempt(query("a", 1)))
+		close(attempted)
+		// a slow caller, still between Attempt and GetRetryType when "b" is attempted
+		time.Sleep(150 * time.Millisecond)
+		p.GetRetryType(errors.New(rateLimitedErrMsg))
+	}()
+	<-attempted
+
+	start := time.Now()
+	assert.True(t, p.Attempt(query("b", 7)))
+	assert.True(t, time.Since(start) < 50*time.Millisecond, "Attempt should not wait for the pending execution to be claimed")
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+	<-decided
+
+	for requestID, attempts := range map[string]int{"a": 1, "b": 7} {
+		entries := p.Trace(requestID)
+		if assert.Len(t, entries, 1, requestID) {
+			assert.Equal(t, attempts, entries[0].Attempt, requestID)
+		}
+	}
+}
+
+func TestAbandonedExecutionsAreBounded(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.Limiter = NewLimiter(maxPendingExecutions+1, 0)
+
+	// Attempts without GetRetryType, which gocql never makes, on as many goroutines
+	for i := 0; i <= maxPendingExecutions; i++ {
+		run, stop := worker()
+		run(func() { assert.True(t, p.Attempt(attemptsQuery{attempts: 1})) })
+		stop()
+	}
+	assert.Len(t, p.pending, maxPendingExecutions)
+	// the slot of the dropped execution is released
+	assert.True(t, p.Limiter.Acquire())
+	assert.False(t, p.Limiter.Acquire())
+
+	p.Reset()
+	assert.Empty(t, p.pending)
+	assert.True(t, p.Limiter.Acquire(), "slots should be released")
+}
+
+func TestAbandonedExecutionIsReplacedOnItsGoroutine(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.Limiter = NewLimiter(1, 0)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, p.Attempt(attemptsQuery{attempts: 1}), "slot of the abandoned execution should be released")
+	}
+	assert.Len(t, p.pending, 1)
+}
+
+func TestReset(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.GrowingBackOffTimeMs = 1
+	// a stable salt, so that back-offs only differ by the attempt count
+	p.ActivityIDJitter = true
+	p.sleepFunc = func(time.Duration) {}
+	var last RetryEvent
+	p.OnRetry = func(e RetryEvent) { last = e }
+	salt, _ := p.activityIDJitter("c268afb6-7367-4ff8-b06b-b7e2d1269f55")
+	err := errors.New(rateLimitedErrMsgWithoutRetryAfterMs)
+
+	for i := 1; i <= 5; i++ {
+		assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{}, i}))
+		p.GetRetryType(err)
+	}
+	assert.Equal(t, time.Duration(5+salt)*time.Millisecond, last.BackOff)
+	assert.Equal(t, int64(5), p.consecutiveThrottles)
+
+	// without an Attempt, the accumulated state carries over
+	p.GetRetryType(err)
+	assert.Equal(t, time.Duration(5+salt)*time.Millisecond, last.BackOff)
+
+	p.Reset()
+	ex := p.lastExecution()
+	assert.Equal(t, 0, ex.attempts)
+	assert.Equal(t, time.Duration(0), ex.totalWait)
+	assert.Equal(t, int64(0), p.consecutiveThrottles)
+
+	p.GetRetryType(err)
+	assert.Equal(t, time.Duration(salt)*time.Millisecond, last.BackOff, "back-off should restart from the base")
+}
+
+func TestResetReleasesLimiterSlot(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.FixedBackOffTimeMs = 0
+	p.Limiter = NewLimiter(1, 0)
+
+	// an Attempt without GetRetryType
+	assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{}, 1}))
+	assert.False(t, p.Limiter.Acquire(), "slot should be held by the pending execution")
+
+	p.Reset()
+	assert.True(t, p.Limiter.Acquire(), "slot should be released")
+}
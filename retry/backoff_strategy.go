@@ -0,0 +1,42 @@
+package retry
+
+import "math"
+
+// BackOffStrategy defines how the growing back-off (used with infinite retries) grows with the number of attempts
+type BackOffStrategy int
+
+const (
+	// Linear grows the back-off as GrowingBackOffTimeMs * attempts (default)
+	Linear BackOffStrategy = iota
+	// Exponential grows the back-off as GrowingBackOffTimeMs * 2^attempts
+	Exponential
+)
+
+func (bs BackOffStrategy) String() string {
+	if bs == Exponential {
+		return "exponential"
+	}
+	return "linear"
+}
+
+// growingBackOff returns the growing back-off (in ms, before jitter and MaxBackOffTimeMs) for the given number of attempts, as per BackOffStrategy. The exponential back-off saturates rather than overflowing
+func (crp *CosmosRetryPolicy) growingBackOff(attempts int) int {
+	if crp.BackOffStrategy != Exponential {
+		return crp.GrowingBackOffTimeMs * attempts
+	}
+	if attempts < 0 {
+		attempts = 0
+	}
+	if attempts >= 31 || crp.GrowingBackOffTimeMs > math.MaxInt32>>uint(attempts) {
+		return math.MaxInt32
+	}
+	return crp.GrowingBackOffTimeMs << uint(attempts)
+}
+
+// capBackOff caps the growing back-off (in ms) as per MaxBackOffTimeMs
+func (crp *CosmosRetryPolicy) capBackOff(backOff int) int {
+	if crp.MaxBackOffTimeMs > 0 && backOff > crp.MaxBackOffTimeMs {
+		return crp.MaxBackOffTimeMs
+	}
+	return backOff
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackOffStrategies(t *testing.T) {
+	type testCase struct {
+		name     string
+		strategy BackOffStrategy
+		expected []time.Duration
+	}
+
+	testCases := []testCase{
+		{"linear", Linear, []time.Duration{100, 200, 300, 400, 500, 600}},
+		{"exponential", Exponential, []time.Duration{200, 400, 800, 1600, 3200, 6400}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(-1, WithGrowingBackOff(100*time.Millisecond), WithMaxBackOff(0))
+			p.BackOffStrategy = tc.strategy
+			p.randFunc = func(int) int { return 0 }
+
+			for attempts := 1; attempts <= 6; attempts++ {
+				p.exec.attempts = attempts
+				assert.Equal(te, tc.expected[attempts-1]*time.Millisecond, retryAfter(te, p, rateLimitedErrMsgWithoutRetryAfterMs), "attempt %d", attempts)
+			}
+		})
+	}
+}
+
+func TestExponentialBackOffCapped(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1, WithGrowingBackOff(100*time.Millisecond), WithMaxBackOff(time.Second))
+	p.BackOffStrategy = Exponential
+	p.randFunc = func(int) int { return 0 }
+
+	p.exec.attempts = 3
+	assert.Equal(t, 800*time.Millisecond, retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs))
+	p.exec.attempts = 4
+	assert.Equal(t, time.Second, retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs))
+	p.exec.attempts = 1000
+	assert.Equal(t, time.Second, retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs))
+}
+
+func TestExponentialBackOffSaturates(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.BackOffStrategy = Exponential
+	assert.Equal(t, math.MaxInt32, p.growingBackOff(30))
+	assert.Equal(t, math.MaxInt32, p.growingBackOff(1000))
+}
+
+func TestBackOffStrategyString(t *testing.T) {
+	assert.Equal(t, "linear", Linear.String())
+	assert.Equal(t, "exponential", Exponential.String())
+}
@@ -0,0 +1,9 @@
+package retry
+
+import "github.com/gocql/gocql"
+
+// isCounterBatch reports whether rq is a COUNTER batch, whose mutations would be applied twice if it was retried after reaching the coordinator, whether or not its statements are marked idempotent
+func isCounterBatch(rq gocql.RetryableQuery) bool {
+	b, ok := rq.(*gocql.Batch)
+	return ok && b.Type == gocql.CounterBatch
+}
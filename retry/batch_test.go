@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func batchOf(typ gocql.BatchType, idempotent bool) *gocql.Batch {
+	b := gocql.NewBatch(typ)
+	b.Entries = append(b.Entries, gocql.BatchEntry{Stmt: "UPDATE t SET c = c + 1 WHERE id = 1", Idempotent: idempotent})
+	return b
+}
+
+func TestBatchRetries(t *testing.T) {
+	type testCase struct {
+		name         string
+		batch        *gocql.Batch
+		err          error
+		expected     gocql.RetryType
+		expectedPath string
+	}
+
+	testCases := []testCase{
+		{"logged batch timed out writing the batch log", batchOf(gocql.LoggedBatch, false), &gocql.RequestErrWriteTimeout{WriteType: "BATCH_LOG"}, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"logged batch timed out", batchOf(gocql.LoggedBatch, false), &gocql.RequestErrWriteTimeout{WriteType: "BATCH"}, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"idempotent logged batch timed out", batchOf(gocql.LoggedBatch, true), &gocql.RequestErrWriteTimeout{WriteType: "BATCH"}, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"unlogged batch timed out", batchOf(gocql.UnloggedBatch, false), &gocql.RequestErrWriteTimeout{WriteType: "UNLOGGED_BATCH"}, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"idempotent unlogged batch timed out", batchOf(gocql.UnloggedBatch, true), &gocql.RequestErrWriteTimeout{WriteType: "UNLOGGED_BATCH"}, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"counter batch timed out", batchOf(gocql.CounterBatch, false), &gocql.RequestErrWriteTimeout{WriteType: "COUNTER"}, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"counter batch marked idempotent timed out", batchOf(gocql.CounterBatch, true), &gocql.RequestErrWriteTimeout{WriteType: "COUNTER"}, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"counter batch marked idempotent timed out on the client", batchOf(gocql.CounterBatch, true), gocql.ErrTimeoutNoResponse, gocql.Rethrow, DecisionPathNotRetryable},
+		{"counter batch unavailable", batchOf(gocql.CounterBatch, false), &gocql.RequestErrUnavailable{}, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"rate limited counter batch", batchOf(gocql.CounterBatch, false), errors.New(rateLimitedErrMsg), gocql.Retry, DecisionPathServerHinted},
+		{"rate limited unlogged batch", batchOf(gocql.UnloggedBatch, false), errors.New(rateLimitedErrMsg), gocql.Retry, DecisionPathServerHinted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.RetryClientTimeouts = true
+			var slept time.Duration
+			p.sleepFunc = func(d time.Duration) { slept += d }
+
+			assert.True(te, p.Attempt(tc.batch))
+			assert.Equal(te, tc.expected, p.GetRetryType(tc.err))
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+			if tc.expectedPath == DecisionPathServerHinted {
+				assert.Equal(te, 42*time.Millisecond, slept)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// backOff decides to back off for d (as per BackOffBoundsByKind and SleepTransform, and shortened so as not to outlast the deadline of the query context or, without deadline, capped by AbsoluteMaxSleepMs) before the next attempt of ex, without sleeping. It returns false if doing so would exceed MaxElapsedTimeMs or if the deadline is (about to be) reached, recording the decision path. The back-off is left to sleep (or to the caller of Decide)
+func (crp *CosmosRetryPolicy) backOff(ex *execution, d time.Duration) bool {
+	d = crp.sleepDuration(crp.boundBackOff(ex.kind, crp.clampBackOff(d)))
+	d, ok := untilDeadline(ex.ctx, d)
+	if !ok {
+		crp.recordPath(ex, DecisionPathDeadline)
+		return false
+	}
+	d = crp.capWithoutDeadline(ex.ctx, d)
+	if !crp.withinElapsedBudget(ex, d) {
+		crp.recordPath(ex, DecisionPathElapsedBudget)
+		return false
+	}
+	ex.backOff = d
+	ex.hasBackOff = true
+	return true
+}
+
+// sleep blocks the calling worker for the back-off decided for ex (see backOff) and accounts for it in ex. It returns false without sleeping if doing so would exceed MaxBlockedTimeMs, and as soon as the context of the query is done, so that no time is wasted on a query which will be discarded anyway, recording the decision path. With SleepDisabled, the back-off is accounted for without sleeping
+func (crp *CosmosRetryPolicy) sleep(ex *execution) bool {
+	d := ex.backOff
+	if crp.SleepDisabled {
+		// the back-off is left to the caller, see RetryEvent
+		ex.totalWait += d
+		return true
+	}
+	reserved := atomic.AddInt64(&crp.blockedNanos, int64(d))
+	if crp.MaxBlockedTimeMs > 0 && reserved > int64(time.Duration(crp.MaxBlockedTimeMs)*time.Millisecond) {
+		atomic.AddInt64(&crp.blockedNanos, -int64(d))
+		crp.recordPath(ex, DecisionPathBlockedLimit)
+		return false
+	}
+	atomic.AddInt64(&crp.blockedWorkers, 1)
+
+	start := crp.currentTime()
+	completed := crp.pause(ex.ctx, d)
+	slept := d
+	if !completed {
+		// only account for the part of the back-off actually slept
+		slept = crp.currentTime().Sub(start)
+	}
+
+	atomic.AddInt64(&crp.blockedWorkers, -1)
+	atomic.AddInt64(&crp.blockedNanos, -int64(d))
+	atomic.AddInt64(&crp.totalBlockedNanos, int64(slept))
+	ex.totalWait += slept
+	if !completed {
+		crp.recordPath(ex, DecisionPathContextDone)
+	}
+	return completed
+}
+
+// minDeadlineRemaining is the time left before the deadline of a query context below which it is not worth retrying the query
+const minDeadlineRemaining = time.Millisecond
+
+// untilDeadline returns d, shortened to the time left before the deadline of ctx (if any), so that the retry is attempted right before the deadline rather than after it. ok is false if the deadline is about to be reached. A context which is done already is left to the back-off sleep to report. The deadline is compared to the real time (rather than the policy clock) since it is the one ctx expires as per
+func untilDeadline(ctx context.Context, d time.Duration) (time.Duration, bool) {
+	if ctx == nil || ctx.Err() != nil {
+		return d, true
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return d, true
+	}
+	remaining := time.Until(deadline)
+	if remaining < minDeadlineRemaining {
+		return 0, false
+	}
+	if d > remaining {
+		return remaining, true
+	}
+	return d, true
+}
+
+// capWithoutDeadline caps d at AbsoluteMaxSleepMs, if set, provided ctx has no deadline to shorten it to
+func (crp *CosmosRetryPolicy) capWithoutDeadline(ctx context.Context, d time.Duration) time.Duration {
+	if crp.AbsoluteMaxSleepMs <= 0 {
+		return d
+	}
+	if ctx != nil {
+		if _, ok := ctx.Deadline(); ok {
+			return d
+		}
+	}
+	if max := time.Duration(crp.AbsoluteMaxSleepMs) * time.Millisecond; d > max {
+		return max
+	}
+	return d
+}
+
+// wait blocks for d or until ctx (if any) is done, whichever happens first, so that even a back-off of several seconds returns as soon as ctx is cancelled (without waking up periodically to check it). It reports whether d has elapsed
+func wait(ctx context.Context, d time.Duration) bool {
+	if ctx == nil {
+		time.Sleep(d)
+		return true
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// BlockedWorkers returns the number of workers currently blocked in a back-off sleep
+func (crp *CosmosRetryPolicy) BlockedWorkers() int {
+	return int(atomic.LoadInt64(&crp.blockedWorkers))
+}
+
+// BlockedTime returns the cumulative time workers have spent blocked in back-off sleeps
+func (crp *CosmosRetryPolicy) BlockedTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&crp.totalBlockedNanos))
+}
@@ -0,0 +1,212 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockedTimeLimitFreesWorkers(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	// each rate limited error backs off for 42ms, so only two workers fit within the limit at once
+	p.MaxBlockedTimeMs = 100
+
+	const workers = 10
+	var retried, rethrown int64
+	var maxBlocked int64
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			begin := time.Now()
+			switch retryTypeOf(p, errors.New(rateLimitedErrMsg)) {
+			case gocql.Retry:
+				atomic.AddInt64(&retried, 1)
+			case gocql.Rethrow:
+				atomic.AddInt64(&rethrown, 1)
+				assert.True(t, time.Since(begin) < 42*time.Millisecond, "freed worker should not have slept")
+			}
+			if b := int64(p.BlockedWorkers()); b > atomic.LoadInt64(&maxBlocked) {
+				atomic.StoreInt64(&maxBlocked, b)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, int64(workers), retried+rethrown)
+	assert.True(t, retried >= 1, "at least one worker should have backed off")
+	assert.True(t, rethrown >= 1, "workers past the limit should have been freed")
+	assert.True(t, atomic.LoadInt64(&maxBlocked) <= 2, "more workers blocked than the limit allows")
+	assert.Equal(t, 0, p.BlockedWorkers())
+	assert.Equal(t, time.Duration(retried)*42*time.Millisecond, p.BlockedTime())
+}
+
+func TestBlockedTimeUnlimitedByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, gocql.Retry, retryTypeOf(p, errors.New(rateLimitedErrMsg)))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 5*42*time.Millisecond, p.BlockedTime())
+}
+
+func TestBackOffCancelledWithContext(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.FixedBackOffTimeMs = 5000
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(20*time.Millisecond, cancel)
+	assert.True(t, p.Attempt(MockRetryableQuery{ctx: ctx}))
+
+	begin := time.Now()
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs)))
+	assert.True(t, time.Since(begin) < time.Second, "back-off should have been cut short by the context")
+	assert.Equal(t, DecisionPathContextDone, p.LastDecisionPath())
+	assert.Equal(t, 0, p.BlockedWorkers())
+	assert.True(t, p.BlockedTime() < time.Second)
+
+	// a query whose context is already done does not back off at all
+	assert.True(t, p.Attempt(MockRetryableQuery{ctx: ctx}))
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs)))
+	assert.Equal(t, DecisionPathContextDone, p.LastDecisionPath())
+}
+
+func TestLongRetryAfterCancelledPromptly(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	errMsg := strings.Replace(rateLimitedErrMsg, "RetryAfterMs=42", "RetryAfterMs=10000", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelled := make(chan time.Time, 1)
+	time.AfterFunc(100*time.Millisecond, func() {
+		cancelled <- time.Now()
+		cancel()
+	})
+	assert.True(t, p.Attempt(MockRetryableQuery{ctx: ctx}))
+
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New(errMsg)))
+	returned := time.Now()
+	assert.True(t, returned.Sub(<-cancelled) < 50*time.Millisecond, "a 10s back-off should return within 50ms of the context being cancelled")
+	assert.Equal(t, DecisionPathContextDone, p.LastDecisionPath())
+	assert.Equal(t, 0, p.BlockedWorkers())
+}
+
+func TestBackOffShortenedToDeadline(t *testing.T) {
+	type testCase struct {
+		name         string
+		timeout      time.Duration
+		expected     gocql.RetryType
+		expectedPath string
+		maxSleep     time.Duration
+	}
+
+	// RetryAfterMs is 42ms
+	testCases := []testCase{
+		{"deadline after RetryAfterMs", time.Minute, gocql.Retry, DecisionPathServerHinted, 42 * time.Millisecond},
+		{"deadline before RetryAfterMs", 20 * time.Millisecond, gocql.Retry, DecisionPathServerHinted, 20 * time.Millisecond},
+		{"deadline about to be reached", 500 * time.Microsecond, gocql.Rethrow, DecisionPathDeadline, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			var slept []time.Duration
+			p.sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			assert.True(te, p.Attempt(MockRetryableQuery{ctx: ctx}))
+			assert.Equal(te, tc.expected, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+			if tc.maxSleep == 0 {
+				assert.Empty(te, slept, "should not back off")
+				return
+			}
+			if assert.Len(te, slept, 1) {
+				assert.True(te, slept[0] <= tc.maxSleep && slept[0] > tc.maxSleep-10*time.Millisecond, "slept %v, expected about %v", slept[0], tc.maxSleep)
+			}
+		})
+	}
+}
+
+func TestBackOffWakesUpAtDeadline(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.FixedBackOffTimeMs = 5000
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.True(t, p.Attempt(MockRetryableQuery{ctx: ctx}))
+
+	begin := time.Now()
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs)))
+	assert.True(t, time.Since(begin) < time.Second, "back-off should have been shortened to the deadline")
+}
+
+func TestAbsoluteMaxSleep(t *testing.T) {
+	type testCase struct {
+		name        string
+		timeout     time.Duration
+		absoluteMax int
+		expectedMin time.Duration
+		expectedMax time.Duration
+	}
+
+	// RetryAfterMs is 5s
+	testCases := []testCase{
+		{"no deadline, capped", 0, 10, 10 * time.Millisecond, 10 * time.Millisecond},
+		{"no deadline, below the cap", 0, 10000, 5 * time.Second, 5 * time.Second},
+		{"no deadline, no cap", 0, 0, 5 * time.Second, 5 * time.Second},
+		{"deadline before the cap", 100 * time.Millisecond, 10, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"deadline after the cap", time.Minute, 10, 5 * time.Second, 5 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.AbsoluteMaxSleepMs = tc.absoluteMax
+			var slept []time.Duration
+			p.sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			assert.True(te, p.Attempt(MockRetryableQuery{ctx: ctx}))
+			assert.Equal(te, gocql.Retry, p.GetRetryType(errors.New(strings.Replace(rateLimitedErrMsg, "RetryAfterMs=42", "RetryAfterMs=5000", 1))))
+			if assert.Len(te, slept, 1) {
+				assert.True(te, slept[0] >= tc.expectedMin && slept[0] <= tc.expectedMax, "slept %v, expected between %v and %v", slept[0], tc.expectedMin, tc.expectedMax)
+			}
+		})
+	}
+}
+
+func TestAbsoluteMaxSleepWithoutContext(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.AbsoluteMaxSleepMs = 10
+	p.SleepDisabled = true
+
+	retryType, backOff := p.Decide(errors.New(strings.Replace(rateLimitedErrMsg, "RetryAfterMs=42", "RetryAfterMs=5000", 1)), 1, true)
+	assert.Equal(t, gocql.Retry, retryType)
+	assert.Equal(t, 10*time.Millisecond, backOff)
+}
@@ -0,0 +1,36 @@
+package retry
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/gocql/gocql"
+)
+
+// EnableChaos makes the policy treat the given fraction (0 to 1) of retry decisions as rate limited errors without RetryAfterMs, regardless of the actual error, so applications can validate how they cope with throttling. It is meant for load and chaos testing only and is deliberately not configurable via fields or ParseConfig
+func (crp *CosmosRetryPolicy) EnableChaos(throttleFraction float64) {
+	atomic.StoreUint64(&crp.chaosFraction, math.Float64bits(throttleFraction))
+	atomic.StoreInt32(&crp.chaosEnabled, 1)
+}
+
+// DisableChaos turns off throttling simulation enabled using EnableChaos
+func (crp *CosmosRetryPolicy) DisableChaos() {
+	atomic.StoreInt32(&crp.chaosEnabled, 0)
+}
+
+// chaosThrottled reports whether the current decision should simulate throttling
+func (crp *CosmosRetryPolicy) chaosThrottled() bool {
+	if atomic.LoadInt32(&crp.chaosEnabled) == 0 {
+		return false
+	}
+	return crp.randFloat64() < math.Float64frombits(atomic.LoadUint64(&crp.chaosFraction))
+}
+
+// simulateThrottle backs off as if a rate limited error without RetryAfterMs was returned
+func (crp *CosmosRetryPolicy) simulateThrottle(ex *execution) gocql.RetryType {
+	if !crp.backOff(ex, crp.fallbackBackOff(ex.consistency, ex.attempts, "")) {
+		return gocql.Rethrow
+	}
+	crp.recordPath(ex, DecisionPathChaosThrottle)
+	return gocql.Retry
+}
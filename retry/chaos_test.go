@@ -0,0 +1,40 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChaosThrottleFraction(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.FixedBackOffTimeMs = 0
+	p.EnableChaos(0.3)
+
+	const decisions = 5000
+	throttled := 0
+	for i := 0; i < decisions; i++ {
+		if retryTypeOf(p, errors.New("error: today is not your day!")) == gocql.Retry {
+			assert.Equal(t, DecisionPathChaosThrottle, p.LastDecisionPath())
+			throttled++
+		}
+	}
+
+	fraction := float64(throttled) / decisions
+	assert.InDelta(t, 0.3, fraction, 0.05, "injected throttle fraction not honored")
+}
+
+func TestChaosDisabled(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.FixedBackOffTimeMs = 0
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, gocql.Rethrow, retryTypeOf(p, errors.New("error: today is not your day!")))
+	}
+
+	p.EnableChaos(1)
+	assert.Equal(t, gocql.Retry, retryTypeOf(p, errors.New("error: today is not your day!")))
+	p.DisableChaos()
+	assert.Equal(t, gocql.Rethrow, retryTypeOf(p, errors.New("error: today is not your day!")))
+}
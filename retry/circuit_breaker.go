@@ -0,0 +1,173 @@
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed - errors are retried as usual
+	CircuitClosed CircuitState = iota
+	// CircuitOpen - errors are rethrown without being retried, until the cool-down period is over
+	CircuitOpen
+	// CircuitHalfOpen - the cool-down period is over and a rate limited error is retried as a probe: the breaker opens again if another rate limited error follows, and closes once any other error is retried or a query succeeds
+	CircuitHalfOpen
+)
+
+func (cs CircuitState) String() string {
+	switch cs {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops retries across queries during sustained throttling, so that they do not waste RUs and worsen the overload: once Threshold rate limited (429) errors are seen within Window, it opens, and every error is rethrown right away (see DecisionPathCircuitOpen) until CoolDown has elapsed. It is safe for concurrent use, and may be shared by several policies. See CosmosRetryPolicy.CircuitBreaker
+type CircuitBreaker struct {
+	// Threshold is the number of rate limited errors within Window which opens the breaker. The breaker never opens if it is 0 or less
+	Threshold int
+	// Window is the period rate limited errors are counted over. The count restarts once it has elapsed since the first error counted
+	Window time.Duration
+	// CoolDown is how long the breaker stays open before half-opening
+	CoolDown time.Duration
+
+	mu          sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	throttled   int
+	openedAt    time.Time
+	probing     bool
+	now         func() time.Time
+}
+
+// NewCircuitBreaker returns a CircuitBreaker which opens once threshold rate limited errors are seen within window, for coolDown
+func NewCircuitBreaker(threshold int, window, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Window: window, CoolDown: coolDown}
+}
+
+// State returns the current state of the breaker
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.coolDown(cb.currentTime())
+	return cb.state
+}
+
+func (cb *CircuitBreaker) currentTime() time.Time {
+	if cb.now == nil {
+		return time.Now()
+	}
+	return cb.now()
+}
+
+// coolDown half-opens the breaker once it has been open for CoolDown. cb.mu must be held
+func (cb *CircuitBreaker) coolDown(now time.Time) {
+	if cb.state == CircuitOpen && now.Sub(cb.openedAt) >= cb.CoolDown {
+		cb.state = CircuitHalfOpen
+		cb.probing = false
+	}
+}
+
+// open opens the breaker. cb.mu must be held
+func (cb *CircuitBreaker) open(now time.Time) {
+	cb.state = CircuitOpen
+	cb.openedAt = now
+	cb.throttled = 0
+}
+
+// close closes the breaker. cb.mu must be held
+func (cb *CircuitBreaker) close() {
+	cb.state = CircuitClosed
+	cb.throttled = 0
+	cb.probing = false
+}
+
+// admit accounts for an error about to be retried, rate limited or not, and reports whether it may be retried
+func (cb *CircuitBreaker) admit(rateLimited bool) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	now := cb.currentTime()
+	cb.coolDown(now)
+
+	switch cb.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if !rateLimited {
+			cb.close()
+			return true
+		}
+		if !cb.probing {
+			cb.probing = true
+			return true
+		}
+		cb.open(now)
+		return false
+	}
+
+	if !rateLimited || cb.Threshold <= 0 {
+		return true
+	}
+	if cb.throttled == 0 || now.Sub(cb.windowStart) >= cb.Window {
+		cb.windowStart = now
+		cb.throttled = 0
+	}
+	cb.throttled++
+	if cb.throttled >= cb.Threshold {
+		cb.open(now)
+		return false
+	}
+	return true
+}
+
+// allows reports whether an error would be retried, without accounting for it
+func (cb *CircuitBreaker) allows() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	now := cb.currentTime()
+	return cb.state != CircuitOpen || now.Sub(cb.openedAt) >= cb.CoolDown
+}
+
+// succeeded closes a half-open breaker, as a query succeeded
+func (cb *CircuitBreaker) succeeded() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.coolDown(cb.currentTime())
+	if cb.state == CircuitHalfOpen {
+		cb.close()
+	}
+}
+
+// circuitClosed reports whether the error of ex may be retried as per CircuitBreaker (if configured), accounting for rate limited errors unless ex is an execution of Decide. Other errors are only accounted for once they are found to be retryable (see closeCircuit). Health check queries bypass the breaker, and are not accounted for
+func (crp *CosmosRetryPolicy) circuitClosed(ex *execution) bool {
+	if crp.CircuitBreaker == nil || ex.healthCheck {
+		return true
+	}
+	var allowed bool
+	if ex.dryRun || ex.kind != RateLimitedError {
+		allowed = crp.CircuitBreaker.allows()
+	} else {
+		allowed = crp.CircuitBreaker.admit(true)
+	}
+	if !allowed {
+		crp.recordPath(ex, DecisionPathCircuitOpen)
+	}
+	return allowed
+}
+
+// closeCircuit closes a half-open CircuitBreaker (if configured) once the error of ex, other than a rate limited one, is to be retried as per retryType, as the service is no longer throttling. Errors which are not retried (e.g. fatal ones) leave it half-open. Health check queries, and decisions made by Decide, are not accounted for
+func (crp *CosmosRetryPolicy) closeCircuit(ex *execution, retryType gocql.RetryType) {
+	if crp.CircuitBreaker == nil || ex.healthCheck || ex.dryRun || ex.kind == RateLimitedError || !isRetry(retryType) {
+		return
+	}
+	crp.CircuitBreaker.admit(false)
+}
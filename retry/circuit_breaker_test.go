@@ -0,0 +1,150 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// breakerPolicy returns a policy whose CircuitBreaker opens after 3 rate limited errors within a second, for a minute, as per the returned clock
+func breakerPolicy() (*CosmosRetryPolicy, *time.Time) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := NewCosmosRetryPolicy(-1)
+	p.sleepFunc = func(time.Duration) {}
+	p.CircuitBreaker = NewCircuitBreaker(3, time.Second, time.Minute)
+	p.CircuitBreaker.now = func() time.Time { return now }
+	return p, &now
+}
+
+func TestCircuitBreakerOpensOnBurstOfThrottling(t *testing.T) {
+	p, now := breakerPolicy()
+	throttled := errors.New(rateLimitedErrMsg)
+
+	assert.Equal(t, gocql.Retry, retryTypeOf(p, throttled))
+	assert.Equal(t, gocql.Retry, retryTypeOf(p, throttled))
+	assert.Equal(t, CircuitClosed, p.CircuitBreaker.State())
+
+	*now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, gocql.Rethrow, retryTypeOf(p, throttled))
+	assert.Equal(t, DecisionPathCircuitOpen, p.LastDecisionPath())
+	assert.Equal(t, CircuitOpen, p.CircuitBreaker.State())
+
+	// every error fails fast while the breaker is open
+	assert.Equal(t, gocql.Rethrow, retryTypeOf(p, &gocql.RequestErrUnavailable{}))
+	assert.Equal(t, DecisionPathCircuitOpen, p.LastDecisionPath())
+	retryType, backOff := p.Decide(throttled, 1, true)
+	assert.Equal(t, gocql.Rethrow, retryType)
+	assert.Equal(t, time.Duration(0), backOff)
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	p, now := breakerPolicy()
+	throttled := errors.New(rateLimitedErrMsg)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, gocql.Retry, retryTypeOf(p, throttled), "error %d", i)
+		*now = now.Add(600 * time.Millisecond)
+	}
+	assert.Equal(t, CircuitClosed, p.CircuitBreaker.State())
+}
+
+func TestCircuitBreakerHalfOpensAfterCoolDown(t *testing.T) {
+	type testCase struct {
+		name          string
+		next          error
+		expectedState CircuitState
+		expectedType  gocql.RetryType
+	}
+
+	testCases := []testCase{
+		{"rate limited error re-opens", errors.New(rateLimitedErrMsg), CircuitOpen, gocql.Rethrow},
+		{"other error closes", &gocql.RequestErrUnavailable{}, CircuitClosed, gocql.Retry},
+		{"fatal error leaves it half-open", errors.New("error: today is not your day!"), CircuitHalfOpen, gocql.Rethrow},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p, now := breakerPolicy()
+			throttled := errors.New(rateLimitedErrMsg)
+			for i := 0; i < 3; i++ {
+				retryTypeOf(p, throttled)
+			}
+			assert.Equal(te, CircuitOpen, p.CircuitBreaker.State())
+
+			*now = now.Add(59 * time.Second)
+			assert.Equal(te, CircuitOpen, p.CircuitBreaker.State())
+			*now = now.Add(time.Second)
+			assert.Equal(te, CircuitHalfOpen, p.CircuitBreaker.State())
+			retryType, _ := p.Decide(throttled, 1, true)
+			assert.Equal(te, gocql.Retry, retryType)
+
+			// the probe
+			assert.Equal(te, gocql.Retry, retryTypeOf(p, throttled))
+			assert.Equal(te, CircuitHalfOpen, p.CircuitBreaker.State())
+
+			assert.Equal(te, tc.expectedType, retryTypeOf(p, tc.next))
+			assert.Equal(te, tc.expectedState, p.CircuitBreaker.State())
+		})
+	}
+}
+
+func TestCircuitBreakerClosedBySuccess(t *testing.T) {
+	p, now := breakerPolicy()
+	for i := 0; i < 3; i++ {
+		retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	}
+	// successes do not close an open breaker
+	p.ObserveQuery(context.Background(), gocql.ObservedQuery{})
+	assert.Equal(t, CircuitOpen, p.CircuitBreaker.State())
+
+	*now = now.Add(time.Minute)
+	p.ObserveQuery(context.Background(), gocql.ObservedQuery{})
+	assert.Equal(t, CircuitClosed, p.CircuitBreaker.State())
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cb := NewCircuitBreaker(0, time.Second, time.Minute)
+	for i := 0; i < 100; i++ {
+		assert.True(t, cb.admit(true))
+	}
+	assert.Equal(t, CircuitClosed, cb.State())
+
+	p := NewCosmosRetryPolicy(-1)
+	p.sleepFunc = func(time.Duration) {}
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, gocql.Retry, retryTypeOf(p, errors.New(rateLimitedErrMsg)))
+	}
+}
+
+func TestCircuitBreakerConcurrentUse(t *testing.T) {
+	cb := NewCircuitBreaker(50, time.Hour, time.Hour)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if cb.admit(true) {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 49, admitted)
+	assert.Equal(t, CircuitOpen, cb.State())
+}
+
+func TestCircuitStateString(t *testing.T) {
+	assert.Equal(t, "closed", CircuitClosed.String())
+	assert.Equal(t, "open", CircuitOpen.String())
+	assert.Equal(t, "half-open", CircuitHalfOpen.String())
+	assert.Equal(t, "unknown", CircuitState(42).String())
+}
@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Decision is the retry decision of a Classifier
+type Decision int
+
+const (
+	// DecisionDefault defers to the built-in handling of the error
+	DecisionDefault Decision = iota
+	// DecisionRetry retries the query on the same host, after the back-off returned along with it
+	DecisionRetry
+	// DecisionRetryNextHost retries the query on the next host (subject to MaxHostHops), after the back-off returned along with it
+	DecisionRetryNextHost
+	// DecisionRethrow returns the error to the caller
+	DecisionRethrow
+	// DecisionIgnore ignores the error, as per gocql.Ignore
+	DecisionIgnore
+)
+
+func (d Decision) String() string {
+	switch d {
+	case DecisionRetry:
+		return "retry"
+	case DecisionRetryNextHost:
+		return "retry-next-host"
+	case DecisionRethrow:
+		return "rethrow"
+	case DecisionIgnore:
+		return "ignore"
+	}
+	return "default"
+}
+
+// Classifier decides how errors are retried, ahead of the built-in handling (including ClassificationRules), e.g. to treat a specific substatus as fatal or to retry errors the policy does not recognize. It returns DecisionDefault for errors it leaves to the built-in handling
+type Classifier interface {
+	Classify(err error) (Decision, time.Duration)
+}
+
+// ClassifierFunc adapts a function to a Classifier
+type ClassifierFunc func(err error) (Decision, time.Duration)
+
+// Classify calls f(err)
+func (f ClassifierFunc) Classify(err error) (Decision, time.Duration) {
+	return f(err)
+}
+
+// applyDecision makes the retry decision of the Classifier, backing off for the given duration before retrying
+func (crp *CosmosRetryPolicy) applyDecision(ex *execution, decision Decision, backOff time.Duration) gocql.RetryType {
+	crp.recordPath(ex, DecisionPathClassifier)
+	switch decision {
+	case DecisionRethrow:
+		return gocql.Rethrow
+	case DecisionIgnore:
+		return gocql.Ignore
+	}
+	if !crp.backOff(ex, backOff) {
+		return gocql.Rethrow
+	}
+	if decision == DecisionRetryNextHost {
+		return crp.nextHost(ex)
+	}
+	return gocql.Retry
+}
@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// fatalSubStatusClassifier rethrows rate limited errors with a given substatus, and retries errors containing a custom marker
+type fatalSubStatusClassifier struct {
+	subStatus int
+}
+
+func (c fatalSubStatusClassifier) Classify(err error) (Decision, time.Duration) {
+	if _, subStatus, ok := ParseCosmosError(err.Error()); ok && subStatus == c.subStatus {
+		return DecisionRethrow, 0
+	}
+	if err.Error() == "custom: busy" {
+		return DecisionRetry, 5 * time.Millisecond
+	}
+	return DecisionDefault, 0
+}
+
+func TestClassifierOverridesDefaultDecision(t *testing.T) {
+	type testCase struct {
+		name         string
+		err          error
+		expectedType gocql.RetryType
+		expectedPath string
+		expectedWait time.Duration
+	}
+
+	testCases := []testCase{
+		{"substatus treated as fatal", errors.New(rateLimitedErrMsg), gocql.Rethrow, DecisionPathClassifier, 0},
+		{"new retriable pattern", errors.New("custom: busy"), gocql.Retry, DecisionPathClassifier, 5 * time.Millisecond},
+		{"other substatus left to the built-in handling", errors.New("Request rate is large: ActivityID=abc, RetryAfterMs=12, Additional details='TooManyRequests (429); Substatus: 3201'"), gocql.Retry, DecisionPathServerHinted, 12 * time.Millisecond},
+		{"timeouts left to the built-in handling", &gocql.RequestErrUnavailable{}, gocql.Retry, DecisionPathTimeoutImmediate, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.Classifier = fatalSubStatusClassifier{subStatus: 3200}
+			p.sleepFunc = func(time.Duration) {}
+
+			assert.Equal(te, tc.expectedType, retryTypeOf(p, tc.err))
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+			assert.Equal(te, tc.expectedWait, p.BlockedTime())
+		})
+	}
+}
+
+func TestClassifierFuncDecisions(t *testing.T) {
+	type testCase struct {
+		decision     Decision
+		expectedType gocql.RetryType
+	}
+
+	testCases := []testCase{
+		{DecisionRetry, gocql.Retry},
+		{DecisionRetryNextHost, gocql.RetryNextHost},
+		{DecisionRethrow, gocql.Rethrow},
+		{DecisionIgnore, gocql.Ignore},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.decision.String(), func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.Classifier = ClassifierFunc(func(error) (Decision, time.Duration) { return tc.decision, 0 })
+
+			assert.Equal(te, tc.expectedType, retryTypeOf(p, errors.New("error: today is not your day!")))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package retry
+
+import (
+	"errors"
+
+	"github.com/gocql/gocql"
+)
+
+// clientTimeoutErrors are the gocql errors returned when no response was received from the server in time
+var clientTimeoutErrors = []error{gocql.ErrTimeoutNoResponse}
+
+// isClientTimeout reports whether err is a client-side timeout
+func isClientTimeout(err error) bool {
+	for _, timeoutErr := range clientTimeoutErrors {
+		if errors.Is(err, timeoutErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIdempotent reports whether rq has been marked idempotent. Queries which do not expose it are not considered idempotent, and neither are COUNTER batches. Batches are idempotent if all of their statements are, which makes UNLOGGED batches (which may be partially applied) safe to retry as well
+func isIdempotent(rq gocql.RetryableQuery) bool {
+	if isCounterBatch(rq) {
+		return false
+	}
+	q, ok := rq.(interface{ IsIdempotent() bool })
+	return ok && q.IsIdempotent()
+}
+
+// retryClientTimeout retries a client-side timeout as per RetryClientTimeouts, provided the query is idempotent
+func (crp *CosmosRetryPolicy) retryClientTimeout(ex *execution) gocql.RetryType {
+	crp.recordThrottle(ex, false)
+	if !ex.idempotent {
+		crp.recordPath(ex, DecisionPathNotRetryable)
+		return gocql.Rethrow
+	}
+	return crp.retryTimeout(ex)
+}
+
+// isTimeout reports whether err is, or wraps, a timeout (or unavailable) error reported by the server
+func isTimeout(err error) bool {
+	var (
+		readTimeout  *gocql.RequestErrReadTimeout
+		writeTimeout *gocql.RequestErrWriteTimeout
+		unavailable  *gocql.RequestErrUnavailable
+	)
+	return errors.As(err, &readTimeout) || errors.As(err, &writeTimeout) || errors.As(err, &unavailable)
+}
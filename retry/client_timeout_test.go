@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+type idempotentQuery struct {
+	MockRetryableQuery
+	idempotent bool
+}
+
+func (iq idempotentQuery) IsIdempotent() bool {
+	return iq.idempotent
+}
+
+func TestClientTimeoutRetries(t *testing.T) {
+	type testCase struct {
+		name         string
+		enabled      bool
+		query        gocql.RetryableQuery
+		err          error
+		expectedType gocql.RetryType
+		expectedPath string
+	}
+
+	testCases := []testCase{
+		{"idempotent query is retried", true, idempotentQuery{idempotent: true}, gocql.ErrTimeoutNoResponse, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"wrapped timeout is retried", true, idempotentQuery{idempotent: true}, fmt.Errorf("exec: %w", gocql.ErrTimeoutNoResponse), gocql.Retry, DecisionPathTimeoutImmediate},
+		{"non idempotent query is rethrown", true, idempotentQuery{idempotent: false}, gocql.ErrTimeoutNoResponse, gocql.Rethrow, DecisionPathNotRetryable},
+		{"query without idempotency info is rethrown", true, MockRetryableQuery{}, gocql.ErrTimeoutNoResponse, gocql.Rethrow, DecisionPathNotRetryable},
+		{"disabled by default", false, idempotentQuery{idempotent: true}, gocql.ErrTimeoutNoResponse, gocql.Rethrow, DecisionPathNotRetryable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.RetryClientTimeouts = tc.enabled
+
+			assert.True(te, p.Attempt(tc.query))
+			assert.Equal(te, tc.expectedType, p.GetRetryType(tc.err))
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// currentTime returns the current time as per the policy clock, which defaults to time.Now
+func (crp *CosmosRetryPolicy) currentTime() time.Time {
+	if crp.now == nil {
+		return time.Now()
+	}
+	return crp.now()
+}
+
+// pause blocks for d or until ctx (if any) is done, as per the policy sleep function, which defaults to a real sleep. It reports whether d has elapsed. Injected sleep functions are only interrupted by a context which is done already
+func (crp *CosmosRetryPolicy) pause(ctx context.Context, d time.Duration) bool {
+	if crp.sleepFunc == nil {
+		return wait(ctx, d)
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	crp.sleepFunc(d)
+	return true
+}
+
+// randInt returns a random number in [0, n) as per the policy random function, which defaults to the random number generators of the policy (see randSource)
+func (crp *CosmosRetryPolicy) randInt(n int) int {
+	if crp.randFunc == nil {
+		r := crp.rand.get()
+		defer crp.rand.put(r)
+		return r.Intn(n)
+	}
+	return crp.randFunc(n)
+}
+
+// randFloat64 returns a random number in [0.0, 1.0) drawn from the random number generators of the policy
+func (crp *CosmosRetryPolicy) randFloat64() float64 {
+	r := crp.rand.get()
+	defer crp.rand.put(r)
+	return r.Float64()
+}
+
+// randSource hands out the random number generators of a policy, seeded on first use. A generator is used by one goroutine at a time, so that concurrent retries neither race on it nor contend on the lock of the global math/rand source
+type randSource struct {
+	once sync.Once
+	pool sync.Pool
+}
+
+func (rs *randSource) get() *rand.Rand {
+	rs.once.Do(func() {
+		seed := time.Now().UnixNano()
+		rs.pool.New = func() interface{} {
+			// generators created concurrently are seeded differently
+			return rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		}
+	})
+	return rs.pool.Get().(*rand.Rand)
+}
+
+func (rs *randSource) put(r *rand.Rand) {
+	rs.pool.Put(r)
+}
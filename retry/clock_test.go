@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRetryTypeSleepsRetryAfterMs(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	var slept []time.Duration
+	p.sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+
+	assert.Equal(t, gocql.Retry, retryTypeOf(p, errors.New(rateLimitedErrMsg)))
+	assert.Equal(t, []time.Duration{42 * time.Millisecond}, slept)
+	assert.Equal(t, 42*time.Millisecond, p.BlockedTime())
+}
+
+func TestGrowingBackOffSaltFromRandFunc(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	var slept []time.Duration
+	p.sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+	p.randFunc = func(n int) int {
+		assert.Equal(t, growingBackOffSaltMillis, n)
+		return 250
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		assert.True(t, p.Attempt(attemptsQuery{attempts: attempt}))
+		p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs))
+	}
+	// a real sleep would have taken over 6s
+	assert.Equal(t, []time.Duration{1250 * time.Millisecond, 2250 * time.Millisecond, 3250 * time.Millisecond}, slept)
+}
+
+func TestRandConcurrentUse(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	var wg sync.WaitGroup
+	seen := make([]map[int]bool, 8)
+	for i := range seen {
+		seen[i] = map[int]bool{}
+		wg.Add(1)
+		go func(seen map[int]bool) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				n := p.randInt(growingBackOffSaltMillis)
+				assert.True(t, n >= 0 && n < growingBackOffSaltMillis)
+				seen[n] = true
+				f := p.randFloat64()
+				assert.True(t, f >= 0 && f < 1)
+			}
+		}(seen[i])
+	}
+	wg.Wait()
+	for _, s := range seen {
+		assert.True(t, len(s) > 1)
+	}
+}
+
+func TestRandNotShared(t *testing.T) {
+	// the policy generators do not draw from the global source
+	rand.Seed(1)
+	expected := rand.Int()
+	rand.Seed(1)
+	p := NewCosmosRetryPolicy(-1)
+	for i := 0; i < 100; i++ {
+		p.randInt(growingBackOffSaltMillis)
+		p.randFloat64()
+	}
+	assert.Equal(t, expected, rand.Int())
+}
@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Clone returns an independent copy of the policy, e.g. to derive the policies of several sessions (say, one for reads and one for writes) from a base one, without either of them mutating the other. Settings are copied, including SubStatusBackOff, ConsistencyBackOff, BackOffBoundsByKind, RetryAfterBuckets and ClassificationRules, as well as chaos testing, tracing and retry stats (without the recorded traces and stats). The state accumulated by queries, metrics and counters start afresh. Limiter, CircuitBreaker, Classifier, Logger and the callbacks are shared with the original, replace them to tell sessions apart
+func (crp *CosmosRetryPolicy) Clone() *CosmosRetryPolicy {
+	clone := &CosmosRetryPolicy{
+		MaxRetryCount:                   crp.MaxRetryCount,
+		FixedBackOffTimeMs:              crp.FixedBackOffTimeMs,
+		GrowingBackOffTimeMs:            crp.GrowingBackOffTimeMs,
+		MaxRateLimitedRetries:           crp.MaxRateLimitedRetries,
+		BackOffStrategy:                 crp.BackOffStrategy,
+		JitterMode:                      crp.JitterMode,
+		MaxBackOffTimeMs:                crp.MaxBackOffTimeMs,
+		MinBackOffTimeMs:                crp.MinBackOffTimeMs,
+		SustainedThrottleThreshold:      crp.SustainedThrottleThreshold,
+		SoftRetryWarnThreshold:          crp.SoftRetryWarnThreshold,
+		RetryWindow:                     crp.RetryWindow,
+		MaxBlockedTimeMs:                crp.MaxBlockedTimeMs,
+		MaxElapsedTimeMs:                crp.MaxElapsedTimeMs,
+		AbsoluteMaxSleepMs:              crp.AbsoluteMaxSleepMs,
+		ImmediateRetries:                crp.ImmediateRetries,
+		ThrottleErrorCode:               crp.ThrottleErrorCode,
+		DirectThrottleMarker:            crp.DirectThrottleMarker,
+		DirectBackOffTimeMs:             crp.DirectBackOffTimeMs,
+		PrimaryRegion:                   crp.PrimaryRegion,
+		SecondaryRegionBackOffMs:        crp.SecondaryRegionBackOffMs,
+		ActivityIDJitter:                crp.ActivityIDJitter,
+		JitterFraction:                  crp.JitterFraction,
+		HonorRetryAfterOnAnyError:       crp.HonorRetryAfterOnAnyError,
+		ParseFailureAction:              crp.ParseFailureAction,
+		UnknownErrorPolicy:              crp.UnknownErrorPolicy,
+		RetryNextHostOnUnavailable:      crp.RetryNextHostOnUnavailable,
+		DowngradeConsistencyAfter:       crp.DowngradeConsistencyAfter,
+		DowngradeConsistency:            crp.DowngradeConsistency,
+		RetryNextHostOnConnectionErrors: crp.RetryNextHostOnConnectionErrors,
+		MaxHostHops:                     crp.MaxHostHops,
+		LogRecovery:                     crp.LogRecovery,
+		LogDecisions:                    crp.LogDecisions,
+		IgnoreErrors:                    crp.IgnoreErrors,
+		RetryClientTimeouts:             crp.RetryClientTimeouts,
+		NotReadyMarker:                  crp.NotReadyMarker,
+		NotReadyBackOffTimeMs:           crp.NotReadyBackOffTimeMs,
+		SleepTransform:                  crp.SleepTransform,
+		SleepDisabled:                   crp.SleepDisabled,
+		Classifier:                      crp.Classifier,
+		Limiter:                         crp.Limiter,
+		CircuitBreaker:                  crp.CircuitBreaker,
+		OnRetry:                         crp.OnRetry,
+		OnThrottleExhausted:             crp.OnThrottleExhausted,
+		Logger:                          crp.Logger,
+		now:                             crp.now,
+		sleepFunc:                       crp.sleepFunc,
+		randFunc:                        crp.randFunc,
+	}
+	if crp.SubStatusBackOff != nil {
+		clone.SubStatusBackOff = make(map[int]time.Duration, len(crp.SubStatusBackOff))
+		for subStatus, backOff := range crp.SubStatusBackOff {
+			clone.SubStatusBackOff[subStatus] = backOff
+		}
+	}
+	if crp.ConsistencyBackOff != nil {
+		clone.ConsistencyBackOff = make(map[gocql.Consistency]time.Duration, len(crp.ConsistencyBackOff))
+		for consistency, backOff := range crp.ConsistencyBackOff {
+			clone.ConsistencyBackOff[consistency] = backOff
+		}
+	}
+	if crp.BackOffBoundsByKind != nil {
+		clone.BackOffBoundsByKind = make(map[ErrorKind][2]time.Duration, len(crp.BackOffBoundsByKind))
+		for kind, bounds := range crp.BackOffBoundsByKind {
+			clone.BackOffBoundsByKind[kind] = bounds
+		}
+	}
+	if crp.RetryAfterBuckets != nil {
+		clone.RetryAfterBuckets = append([]time.Duration(nil), crp.RetryAfterBuckets...)
+	}
+	if crp.ClassificationRules != nil {
+		clone.ClassificationRules = append([]ClassificationRule(nil), crp.ClassificationRules...)
+	}
+
+	atomic.StoreUint64(&clone.chaosFraction, atomic.LoadUint64(&crp.chaosFraction))
+	atomic.StoreInt32(&clone.chaosEnabled, atomic.LoadInt32(&crp.chaosEnabled))
+	crp.tracer.Lock()
+	maxRequests := crp.tracer.maxRequests
+	crp.tracer.Unlock()
+	clone.EnableTracing(maxRequests)
+	crp.retryStats.Lock()
+	maxQueries := len(crp.retryStats.stats)
+	crp.retryStats.Unlock()
+	clone.EnableRetryStats(maxQueries)
+	return clone
+}
+
+// WithOverrides returns a Clone of the policy customized by the provided options (applied in order), leaving the policy itself as is. It panics if an option, or the resulting settings, are invalid
+func (crp *CosmosRetryPolicy) WithOverrides(opts ...Option) *CosmosRetryPolicy {
+	clone := crp.Clone()
+	for _, opt := range opts {
+		if err := opt(clone); err != nil {
+			panic(err)
+		}
+	}
+	if err := clone.Validate(); err != nil {
+		panic(err)
+	}
+	return clone
+}
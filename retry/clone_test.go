@@ -0,0 +1,161 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// fullyConfiguredPolicy returns a policy with every exported field set
+func fullyConfiguredPolicy() *CosmosRetryPolicy {
+	p := NewCosmosRetryPolicy(5)
+	p.MaxRateLimitedRetries = 4
+	p.BackOffStrategy = Exponential
+	p.JitterMode = FullJitter
+	p.MinBackOffTimeMs = 10
+	p.SoftRetryWarnThreshold = 2
+	p.SubStatusBackOff = map[int]time.Duration{3200: time.Second}
+	p.ConsistencyBackOff = map[gocql.Consistency]time.Duration{gocql.Quorum: time.Second}
+	p.RetryWindow = func(time.Time) bool { return true }
+	p.MaxBlockedTimeMs = 60000
+	p.MaxElapsedTimeMs = 30000
+	p.AbsoluteMaxSleepMs = 10000
+	p.ImmediateRetries = 1
+	p.DirectBackOffTimeMs = 100
+	p.PrimaryRegion = "West US"
+	p.SecondaryRegionBackOffMs = 200
+	p.ActivityIDJitter = true
+	p.JitterFraction = 0.1
+	p.HonorRetryAfterOnAnyError = true
+	p.ParseFailureAction = FailOpen
+	p.UnknownErrorPolicy = RetryUnknownErrors
+	p.RetryNextHostOnUnavailable = true
+	p.DowngradeConsistencyAfter = 2
+	p.DowngradeConsistency = gocql.LocalOne
+	p.RetryNextHostOnConnectionErrors = true
+	p.MaxHostHops = 3
+	p.LogRecovery = true
+	p.LogDecisions = true
+	p.IgnoreErrors = IgnoreReadTimeouts
+	p.RetryClientTimeouts = true
+	p.NotReadyMarker = "unconfigured table"
+	p.SleepTransform = func(d time.Duration) time.Duration { return d }
+	p.SleepDisabled = true
+	p.BackOffBoundsByKind = map[ErrorKind][2]time.Duration{RateLimitedError: {time.Millisecond, time.Second}}
+	p.RetryAfterBuckets = []time.Duration{time.Millisecond, time.Second}
+	p.Classifier = fatalSubStatusClassifier{subStatus: 3200}
+	p.ClassificationRules = []ClassificationRule{{Pattern: regexp.MustCompile("overloaded"), Kind: TransientError, RetryType: gocql.Retry}}
+	p.Limiter = NewLimiter(1, time.Second)
+	p.CircuitBreaker = NewCircuitBreaker(10, time.Second, time.Second)
+	p.OnRetry = func(RetryEvent) {}
+	p.OnThrottleExhausted = func(int, string) {}
+	p.Logger = &capturingLogger{}
+	return p
+}
+
+func TestCloneCopiesEveryExportedField(t *testing.T) {
+	p := fullyConfiguredPolicy()
+	clone := p.Clone()
+
+	original := reflect.ValueOf(p).Elem()
+	cloned := reflect.ValueOf(clone).Elem()
+	for i := 0; i < original.NumField(); i++ {
+		field := original.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		t.Run(field.Name, func(te *testing.T) {
+			// a field left unset here would not be checked
+			assert.False(te, original.Field(i).IsZero(), "field not set by fullyConfiguredPolicy")
+			if field.Type.Kind() == reflect.Func {
+				assert.Equal(te, original.Field(i).Pointer(), cloned.Field(i).Pointer())
+				return
+			}
+			assert.Equal(te, original.Field(i).Interface(), cloned.Field(i).Interface())
+		})
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	p := fullyConfiguredPolicy()
+	clone := p.Clone()
+
+	clone.MaxRetryCount = 1
+	clone.FixedBackOffTimeMs = 1
+	clone.PrimaryRegion = "East US"
+	clone.SubStatusBackOff[3200] = time.Millisecond
+	clone.ConsistencyBackOff[gocql.One] = time.Millisecond
+	clone.BackOffBoundsByKind[TransientError] = [2]time.Duration{0, time.Millisecond}
+	clone.ClassificationRules[0].BackOff = time.Millisecond
+	clone.RetryAfterBuckets[0] = 2 * time.Millisecond
+	clone.ClassificationRules = append(clone.ClassificationRules, DefaultClassificationRules()...)
+
+	assert.Equal(t, 5, p.MaxRetryCount)
+	assert.Equal(t, defaultFixedBackOffTimeMs, p.FixedBackOffTimeMs)
+	assert.Equal(t, "West US", p.PrimaryRegion)
+	assert.Equal(t, map[int]time.Duration{3200: time.Second}, p.SubStatusBackOff)
+	assert.Equal(t, map[gocql.Consistency]time.Duration{gocql.Quorum: time.Second}, p.ConsistencyBackOff)
+	assert.Equal(t, map[ErrorKind][2]time.Duration{RateLimitedError: {time.Millisecond, time.Second}}, p.BackOffBoundsByKind)
+	assert.Equal(t, []time.Duration{time.Millisecond, time.Second}, p.RetryAfterBuckets)
+	assert.Len(t, p.ClassificationRules, 1)
+	assert.Equal(t, time.Duration(0), p.ClassificationRules[0].BackOff)
+}
+
+func TestCloneStartsAfresh(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.sleepFunc = func(time.Duration) {}
+	p.EnableTracing(10)
+	p.EnableRetryStats(10)
+	p.EnableChaos(0)
+
+	ctx := WithRequestID(context.Background(), "request")
+	for attempt := 1; attempt <= 4; attempt++ {
+		if p.Attempt(attemptsQuery{MockRetryableQuery{ctx: ctx}, attempt}) {
+			p.GetRetryType(errors.New(rateLimitedErrMsg))
+		}
+	}
+	assert.NotEmpty(t, p.Trace("request"))
+	assert.NotEmpty(t, p.RecentRetries())
+	assert.NotZero(t, p.Metrics().RateLimitedRetries)
+
+	clone := p.Clone()
+	assert.Empty(t, clone.Trace("request"))
+	assert.Empty(t, clone.RecentRetries())
+	assert.Equal(t, Metrics{}, clone.Metrics())
+	assert.Equal(t, execution{}, clone.lastExecution())
+	assert.Equal(t, "", clone.LastActivityID())
+	assert.Equal(t, time.Duration(0), clone.LastRetryAfterMs())
+
+	// tracing, retry stats and chaos testing are carried over
+	for attempt := 1; attempt <= 4; attempt++ {
+		if clone.Attempt(attemptsQuery{MockRetryableQuery{ctx: ctx}, attempt}) {
+			clone.GetRetryType(errors.New(rateLimitedErrMsg))
+		}
+	}
+	assert.Len(t, clone.Trace("request"), len(p.Trace("request")))
+	assert.Len(t, clone.RecentRetries(), len(p.RecentRetries()))
+	assert.Equal(t, int32(1), clone.chaosEnabled)
+}
+
+func TestWithOverrides(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	writes := p.WithOverrides(WithFixedBackOff(time.Second), WithMaxElapsedTime(10*time.Second))
+
+	assert.Equal(t, 1000, writes.FixedBackOffTimeMs)
+	assert.Equal(t, 10000, writes.MaxElapsedTimeMs)
+	assert.Equal(t, 3, writes.MaxRetryCount)
+	assert.Equal(t, defaultFixedBackOffTimeMs, p.FixedBackOffTimeMs)
+	assert.Equal(t, 0, p.MaxElapsedTimeMs)
+}
+
+func TestWithOverridesPanicsOnInvalidOptions(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	assert.Panics(t, func() { p.WithOverrides(WithFixedBackOff(-time.Second)) })
+	assert.Equal(t, defaultFixedBackOffTimeMs, p.FixedBackOffTimeMs)
+}
@@ -0,0 +1,34 @@
+package retry
+
+import (
+	"crypto/tls"
+
+	"github.com/gocql/gocql"
+)
+
+// DefaultCosmosPort is the port of the Cassandra API endpoint of a Cosmos DB account
+const DefaultCosmosPort = 10350
+
+// NewCosmosClusterWithAuth returns a gocql.ClusterConfig ready to connect to the Cassandra API of a Cosmos DB account: TLS (with host verification, as mandated by Cosmos DB), password authentication, LOCAL_QUORUM consistency and crp as the retry policy (see NewCosmosCluster). crp is registered as the query and batch observer as well, so that LogRecovery and WithAttemptScope work out of the box. A port of 0 means DefaultCosmosPort. The returned config can be customized further before creating a session
+func NewCosmosClusterWithAuth(contactPoint, username, password string, port int, crp *CosmosRetryPolicy) *gocql.ClusterConfig {
+	if crp == nil {
+		crp = DefaultPolicy()
+	}
+	cluster := NewCosmosCluster(crp, contactPoint)
+	if port == 0 {
+		port = DefaultCosmosPort
+	}
+	cluster.Port = port
+	cluster.ProtoVersion = 4
+	cluster.Consistency = gocql.LocalQuorum
+	cluster.Authenticator = gocql.PasswordAuthenticator{Username: username, Password: password}
+	cluster.SslOpts = &gocql.SslOptions{
+		Config:                 &tls.Config{ServerName: contactPoint, MinVersion: tls.VersionTLS12},
+		EnableHostVerification: true,
+	}
+	if crp != nil {
+		cluster.QueryObserver = crp
+		cluster.BatchObserver = crp
+	}
+	return cluster
+}
@@ -0,0 +1,41 @@
+package retry
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCosmosClusterWithAuth(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	cluster := NewCosmosClusterWithAuth("myaccount.cassandra.cosmos.azure.com", "myaccount", "secret", 0, p)
+
+	assert.Equal(t, []string{"myaccount.cassandra.cosmos.azure.com"}, cluster.Hosts)
+	assert.Equal(t, DefaultCosmosPort, cluster.Port)
+	assert.Equal(t, gocql.LocalQuorum, cluster.Consistency)
+	assert.True(t, cluster.RetryPolicy == p)
+	assert.True(t, cluster.QueryObserver == p)
+	assert.True(t, cluster.BatchObserver == p)
+	assert.Equal(t, gocql.PasswordAuthenticator{Username: "myaccount", Password: "secret"}, cluster.Authenticator)
+	if assert.NotNil(t, cluster.SslOpts) && assert.NotNil(t, cluster.SslOpts.Config) {
+		assert.True(t, cluster.SslOpts.EnableHostVerification)
+		assert.Equal(t, "myaccount.cassandra.cosmos.azure.com", cluster.SslOpts.ServerName)
+		assert.Equal(t, uint16(tls.VersionTLS12), cluster.SslOpts.MinVersion)
+	}
+}
+
+func TestNewCosmosClusterWithAuthCustomPortAndDefaultPolicy(t *testing.T) {
+	defer SetDefaultPolicy(nil)
+	cluster := NewCosmosClusterWithAuth("localhost", "user", "secret", 9042, nil)
+	assert.Equal(t, 9042, cluster.Port)
+	assert.Nil(t, cluster.RetryPolicy)
+	assert.Nil(t, cluster.QueryObserver)
+
+	def := NewCosmosRetryPolicy(3)
+	SetDefaultPolicy(def)
+	cluster = NewCosmosClusterWithAuth("localhost", "user", "secret", 9042, nil)
+	assert.True(t, cluster.RetryPolicy == def)
+	assert.True(t, cluster.QueryObserver == def)
+}
@@ -0,0 +1,412 @@
+package retry
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const configFieldSeparator = ";"
+const configKeyValueSeparator = "="
+const configListSeparator = ","
+const configPairSeparator = ":"
+const configRangeSeparator = ".."
+
+// configField describes how a tunable of CosmosRetryPolicy is rendered to, and parsed from, its config representation
+type configField struct {
+	key string
+	get func(crp *CosmosRetryPolicy) string
+	set func(crp *CosmosRetryPolicy, value string) error
+}
+
+func intField(key string, field func(crp *CosmosRetryPolicy) *int) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string { return strconv.Itoa(*field(crp)) },
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			*field(crp) = v
+			return nil
+		},
+	}
+}
+
+func boolField(key string, field func(crp *CosmosRetryPolicy) *bool) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string { return strconv.FormatBool(*field(crp)) },
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			*field(crp) = v
+			return nil
+		},
+	}
+}
+
+func floatField(key string, field func(crp *CosmosRetryPolicy) *float64) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string { return strconv.FormatFloat(*field(crp), 'g', -1, 64) },
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			*field(crp) = v
+			return nil
+		},
+	}
+}
+
+func stringField(key string, field func(crp *CosmosRetryPolicy) *string) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string { return url.QueryEscape(*field(crp)) },
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			v, err := url.QueryUnescape(value)
+			if err != nil {
+				return err
+			}
+			*field(crp) = v
+			return nil
+		},
+	}
+}
+
+func consistencyField(key string, field func(crp *CosmosRetryPolicy) *gocql.Consistency) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string { return field(crp).String() },
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			var c gocql.Consistency
+			if err := c.UnmarshalText([]byte(value)); err != nil {
+				return err
+			}
+			*field(crp) = c
+			return nil
+		},
+	}
+}
+
+func consistencyDurationsField(key string, field func(crp *CosmosRetryPolicy) *map[gocql.Consistency]time.Duration) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string {
+			m := *field(crp)
+			levels := make([]gocql.Consistency, 0, len(m))
+			for c := range m {
+				levels = append(levels, c)
+			}
+			sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+
+			pairs := make([]string, 0, len(levels))
+			for _, c := range levels {
+				pairs = append(pairs, c.String()+configPairSeparator+m[c].String())
+			}
+			return strings.Join(pairs, configListSeparator)
+		},
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			if value == "" {
+				*field(crp) = nil
+				return nil
+			}
+			m := map[gocql.Consistency]time.Duration{}
+			for _, pair := range strings.Split(value, configListSeparator) {
+				kv := strings.SplitN(pair, configPairSeparator, 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("malformed entry %q", pair)
+				}
+				var c gocql.Consistency
+				if err := c.UnmarshalText([]byte(kv[0])); err != nil {
+					return err
+				}
+				d, err := time.ParseDuration(kv[1])
+				if err != nil {
+					return err
+				}
+				m[c] = d
+			}
+			*field(crp) = m
+			return nil
+		},
+	}
+}
+
+func subStatusDurationsField(key string, field func(crp *CosmosRetryPolicy) *map[int]time.Duration) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string {
+			m := *field(crp)
+			codes := make([]int, 0, len(m))
+			for code := range m {
+				codes = append(codes, code)
+			}
+			sort.Ints(codes)
+
+			pairs := make([]string, 0, len(codes))
+			for _, code := range codes {
+				pairs = append(pairs, strconv.Itoa(code)+configPairSeparator+m[code].String())
+			}
+			return strings.Join(pairs, configListSeparator)
+		},
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			if value == "" {
+				*field(crp) = nil
+				return nil
+			}
+			m := map[int]time.Duration{}
+			for _, pair := range strings.Split(value, configListSeparator) {
+				kv := strings.SplitN(pair, configPairSeparator, 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("malformed entry %q", pair)
+				}
+				code, err := strconv.Atoi(kv[0])
+				if err != nil {
+					return err
+				}
+				d, err := time.ParseDuration(kv[1])
+				if err != nil {
+					return err
+				}
+				m[code] = d
+			}
+			*field(crp) = m
+			return nil
+		},
+	}
+}
+
+func kindBoundsField(key string, field func(crp *CosmosRetryPolicy) *map[ErrorKind][2]time.Duration) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string {
+			m := *field(crp)
+			kinds := make([]ErrorKind, 0, len(m))
+			for k := range m {
+				kinds = append(kinds, k)
+			}
+			sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+			pairs := make([]string, 0, len(kinds))
+			for _, k := range kinds {
+				pairs = append(pairs, k.String()+configPairSeparator+m[k][0].String()+configRangeSeparator+m[k][1].String())
+			}
+			return strings.Join(pairs, configListSeparator)
+		},
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			if value == "" {
+				*field(crp) = nil
+				return nil
+			}
+			m := map[ErrorKind][2]time.Duration{}
+			for _, pair := range strings.Split(value, configListSeparator) {
+				kv := strings.SplitN(pair, configPairSeparator, 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("malformed entry %q", pair)
+				}
+				kind, ok := parseErrorKind(kv[0])
+				if !ok {
+					return fmt.Errorf("unknown error kind %q", kv[0])
+				}
+				bounds := strings.SplitN(kv[1], configRangeSeparator, 2)
+				if len(bounds) != 2 {
+					return fmt.Errorf("malformed bounds %q", kv[1])
+				}
+				floor, err := time.ParseDuration(bounds[0])
+				if err != nil {
+					return err
+				}
+				ceiling, err := time.ParseDuration(bounds[1])
+				if err != nil {
+					return err
+				}
+				m[kind] = [2]time.Duration{floor, ceiling}
+			}
+			*field(crp) = m
+			return nil
+		},
+	}
+}
+
+// rulesField renders rules as pattern:kind:retry type:back-off entries. Patterns are escaped, so that they may contain any separator. Rules without a Pattern never match, and are left out
+func rulesField(key string, field func(crp *CosmosRetryPolicy) *[]ClassificationRule) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string {
+			entries := make([]string, 0, len(*field(crp)))
+			for _, rule := range *field(crp) {
+				if rule.Pattern == nil {
+					continue
+				}
+				entries = append(entries, strings.Join([]string{url.QueryEscape(rule.Pattern.String()), rule.Kind.String(), strconv.Itoa(int(rule.RetryType)), rule.BackOff.String()}, configPairSeparator))
+			}
+			return strings.Join(entries, configListSeparator)
+		},
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			if value == "" {
+				*field(crp) = nil
+				return nil
+			}
+			var rules []ClassificationRule
+			for _, entry := range strings.Split(value, configListSeparator) {
+				parts := strings.Split(entry, configPairSeparator)
+				if len(parts) != 4 {
+					return fmt.Errorf("malformed rule %q", entry)
+				}
+				expr, err := url.QueryUnescape(parts[0])
+				if err != nil {
+					return err
+				}
+				pattern, err := regexp.Compile(expr)
+				if err != nil {
+					return err
+				}
+				kind, ok := parseErrorKind(parts[1])
+				if !ok {
+					return fmt.Errorf("unknown error kind %q", parts[1])
+				}
+				retryType, err := strconv.ParseUint(parts[2], 10, 8)
+				if err != nil {
+					return err
+				}
+				backOff, err := time.ParseDuration(parts[3])
+				if err != nil {
+					return err
+				}
+				rules = append(rules, ClassificationRule{Pattern: pattern, Kind: kind, RetryType: gocql.RetryType(retryType), BackOff: backOff})
+			}
+			*field(crp) = rules
+			return nil
+		},
+	}
+}
+
+func durationsField(key string, field func(crp *CosmosRetryPolicy) *[]time.Duration) configField {
+	return configField{
+		key: key,
+		get: func(crp *CosmosRetryPolicy) string {
+			durations := make([]string, 0, len(*field(crp)))
+			for _, d := range *field(crp) {
+				durations = append(durations, d.String())
+			}
+			return strings.Join(durations, configListSeparator)
+		},
+		set: func(crp *CosmosRetryPolicy, value string) error {
+			if value == "" {
+				*field(crp) = nil
+				return nil
+			}
+			var durations []time.Duration
+			for _, v := range strings.Split(value, configListSeparator) {
+				d, err := time.ParseDuration(v)
+				if err != nil {
+					return err
+				}
+				durations = append(durations, d)
+			}
+			*field(crp) = durations
+			return nil
+		},
+	}
+}
+
+// configFields lists the serializable tunables in the order they appear in MarshalConfig output
+var configFields = []configField{
+	intField("max_retry_count", func(crp *CosmosRetryPolicy) *int { return &crp.MaxRetryCount }),
+	intField("fixed_backoff_ms", func(crp *CosmosRetryPolicy) *int { return &crp.FixedBackOffTimeMs }),
+	intField("growing_backoff_ms", func(crp *CosmosRetryPolicy) *int { return &crp.GrowingBackOffTimeMs }),
+	intField("max_rate_limited_retries", func(crp *CosmosRetryPolicy) *int { return &crp.MaxRateLimitedRetries }),
+	intField("backoff_strategy", func(crp *CosmosRetryPolicy) *int { return (*int)(&crp.BackOffStrategy) }),
+	intField("max_backoff_ms", func(crp *CosmosRetryPolicy) *int { return &crp.MaxBackOffTimeMs }),
+	intField("min_backoff_ms", func(crp *CosmosRetryPolicy) *int { return &crp.MinBackOffTimeMs }),
+	intField("sustained_throttle_threshold", func(crp *CosmosRetryPolicy) *int { return &crp.SustainedThrottleThreshold }),
+	intField("soft_retry_warn_threshold", func(crp *CosmosRetryPolicy) *int { return &crp.SoftRetryWarnThreshold }),
+	boolField("log_recovery", func(crp *CosmosRetryPolicy) *bool { return &crp.LogRecovery }),
+	boolField("log_decisions", func(crp *CosmosRetryPolicy) *bool { return &crp.LogDecisions }),
+	intField("max_blocked_time_ms", func(crp *CosmosRetryPolicy) *int { return &crp.MaxBlockedTimeMs }),
+	intField("max_elapsed_time_ms", func(crp *CosmosRetryPolicy) *int { return &crp.MaxElapsedTimeMs }),
+	intField("absolute_max_sleep_ms", func(crp *CosmosRetryPolicy) *int { return &crp.AbsoluteMaxSleepMs }),
+	boolField("sleep_disabled", func(crp *CosmosRetryPolicy) *bool { return &crp.SleepDisabled }),
+	intField("immediate_retries", func(crp *CosmosRetryPolicy) *int { return &crp.ImmediateRetries }),
+	intField("throttle_error_code", func(crp *CosmosRetryPolicy) *int { return &crp.ThrottleErrorCode }),
+	stringField("direct_throttle_marker", func(crp *CosmosRetryPolicy) *string { return &crp.DirectThrottleMarker }),
+	intField("direct_backoff_ms", func(crp *CosmosRetryPolicy) *int { return &crp.DirectBackOffTimeMs }),
+	stringField("primary_region", func(crp *CosmosRetryPolicy) *string { return &crp.PrimaryRegion }),
+	intField("secondary_region_backoff_ms", func(crp *CosmosRetryPolicy) *int { return &crp.SecondaryRegionBackOffMs }),
+	boolField("activity_id_jitter", func(crp *CosmosRetryPolicy) *bool { return &crp.ActivityIDJitter }),
+	boolField("honor_retry_after_on_any_error", func(crp *CosmosRetryPolicy) *bool { return &crp.HonorRetryAfterOnAnyError }),
+	floatField("jitter_fraction", func(crp *CosmosRetryPolicy) *float64 { return &crp.JitterFraction }),
+	intField("jitter_mode", func(crp *CosmosRetryPolicy) *int { return (*int)(&crp.JitterMode) }),
+	intField("parse_failure_action", func(crp *CosmosRetryPolicy) *int { return (*int)(&crp.ParseFailureAction) }),
+	intField("unknown_error_policy", func(crp *CosmosRetryPolicy) *int { return (*int)(&crp.UnknownErrorPolicy) }),
+	boolField("retry_next_host_on_unavailable", func(crp *CosmosRetryPolicy) *bool { return &crp.RetryNextHostOnUnavailable }),
+	boolField("retry_next_host_on_connection_errors", func(crp *CosmosRetryPolicy) *bool { return &crp.RetryNextHostOnConnectionErrors }),
+	intField("max_host_hops", func(crp *CosmosRetryPolicy) *int { return &crp.MaxHostHops }),
+	intField("downgrade_consistency_after", func(crp *CosmosRetryPolicy) *int { return &crp.DowngradeConsistencyAfter }),
+	consistencyField("downgrade_consistency", func(crp *CosmosRetryPolicy) *gocql.Consistency { return &crp.DowngradeConsistency }),
+	boolField("retry_client_timeouts", func(crp *CosmosRetryPolicy) *bool { return &crp.RetryClientTimeouts }),
+	intField("ignore_errors", func(crp *CosmosRetryPolicy) *int { return (*int)(&crp.IgnoreErrors) }),
+	stringField("not_ready_marker", func(crp *CosmosRetryPolicy) *string { return &crp.NotReadyMarker }),
+	intField("not_ready_backoff_ms", func(crp *CosmosRetryPolicy) *int { return &crp.NotReadyBackOffTimeMs }),
+	subStatusDurationsField("substatus_backoff", func(crp *CosmosRetryPolicy) *map[int]time.Duration { return &crp.SubStatusBackOff }),
+	consistencyDurationsField("consistency_backoff", func(crp *CosmosRetryPolicy) *map[gocql.Consistency]time.Duration { return &crp.ConsistencyBackOff }),
+	kindBoundsField("backoff_bounds", func(crp *CosmosRetryPolicy) *map[ErrorKind][2]time.Duration { return &crp.BackOffBoundsByKind }),
+	durationsField("retry_after_buckets", func(crp *CosmosRetryPolicy) *[]time.Duration { return &crp.RetryAfterBuckets }),
+	rulesField("classification_rules", func(crp *CosmosRetryPolicy) *[]ClassificationRule { return &crp.ClassificationRules }),
+}
+
+// MarshalConfig returns a compact representation of the policy tunables e.g. max_retry_count=5;fixed_backoff_ms=5000;... which can be parsed back using ParseConfig. Functions and loggers (RetryWindow, Logger) are not included
+func (crp *CosmosRetryPolicy) MarshalConfig() string {
+	fields := make([]string, 0, len(configFields))
+	for _, f := range configFields {
+		fields = append(fields, f.key+configKeyValueSeparator+f.get(crp))
+	}
+	return strings.Join(fields, configFieldSeparator)
+}
+
+// ParseConfig returns a CosmosRetryPolicy configured as per the representation produced by MarshalConfig. Tunables missing from config retain their default values, as per NewCosmosRetryPolicyWithOptions (e.g. a MaxRetryCount of 3). It returns an error if config is malformed or describes an invalid policy, see Validate
+func ParseConfig(config string) (*CosmosRetryPolicy, error) {
+	crp := NewCosmosRetryPolicyWithOptions()
+	if strings.TrimSpace(config) == "" {
+		return crp, nil
+	}
+
+	for _, kv := range strings.Split(config, configFieldSeparator) {
+		parts := strings.SplitN(kv, configKeyValueSeparator, 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid config entry %q: expected key%svalue", kv, configKeyValueSeparator)
+		}
+		f, ok := lookupConfigField(strings.TrimSpace(parts[0]))
+		if !ok {
+			return nil, fmt.Errorf("unknown config key %q", parts[0])
+		}
+		if err := f.set(crp, strings.TrimSpace(parts[1])); err != nil {
+			return nil, fmt.Errorf("invalid value for config key %q: %v", f.key, err)
+		}
+	}
+	if err := crp.Validate(); err != nil {
+		return nil, err
+	}
+	return crp, nil
+}
+
+func lookupConfigField(key string) (configField, bool) {
+	for _, f := range configFields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return configField{}, false
+}
@@ -0,0 +1,181 @@
+package retry
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalConfig(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.ConsistencyBackOff = map[gocql.Consistency]time.Duration{gocql.All: 4 * time.Second, gocql.Quorum: 1500 * time.Millisecond}
+	p.BackOffBoundsByKind = map[ErrorKind][2]time.Duration{RateLimitedError: {time.Second, 30 * time.Second}}
+	p.ClassificationRules = []ClassificationRule{{Pattern: regexp.MustCompile("overloaded|busy"), Kind: TransientError, RetryType: gocql.Retry, BackOff: 250 * time.Millisecond}}
+
+	expected := "max_retry_count=5;fixed_backoff_ms=5000;growing_backoff_ms=1000;max_rate_limited_retries=0;backoff_strategy=0;max_backoff_ms=30000;min_backoff_ms=0;sustained_throttle_threshold=10;soft_retry_warn_threshold=0;log_recovery=false;log_decisions=false;max_blocked_time_ms=0;max_elapsed_time_ms=0;absolute_max_sleep_ms=0;sleep_disabled=false;immediate_retries=0;throttle_error_code=4097;direct_throttle_marker=StatusCode%3A+429;direct_backoff_ms=0;primary_region=;secondary_region_backoff_ms=0;activity_id_jitter=false;honor_retry_after_on_any_error=false;jitter_fraction=0;jitter_mode=0;parse_failure_action=0;unknown_error_policy=0;retry_next_host_on_unavailable=false;retry_next_host_on_connection_errors=false;max_host_hops=0;downgrade_consistency_after=0;downgrade_consistency=ANY;retry_client_timeouts=false;ignore_errors=0;not_ready_marker=;not_ready_backoff_ms=10000;substatus_backoff=;consistency_backoff=QUORUM:1.5s,ALL:4s;backoff_bounds=rate-limited:1s..30s;retry_after_buckets=;classification_rules=overloaded%7Cbusy:transient:0:250ms"
+	assert.Equal(t, expected, p.MarshalConfig())
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	type testCase struct {
+		name   string
+		policy *CosmosRetryPolicy
+	}
+
+	custom := NewCosmosRetryPolicy(-1)
+	custom.FixedBackOffTimeMs = 250
+	custom.GrowingBackOffTimeMs = 750
+	custom.MaxRateLimitedRetries = 4
+	custom.BackOffStrategy = Exponential
+	custom.MaxBackOffTimeMs = 60000
+	custom.MinBackOffTimeMs = 100
+	custom.SustainedThrottleThreshold = 0
+	custom.SoftRetryWarnThreshold = 3
+	custom.MaxBlockedTimeMs = 30000
+	custom.MaxElapsedTimeMs = 120000
+	custom.AbsoluteMaxSleepMs = 20000
+	custom.SleepDisabled = true
+	custom.IgnoreErrors = IgnoreReadTimeoutsWithData | IgnoreReadFailures
+	custom.LogRecovery = true
+	custom.ImmediateRetries = 2
+	custom.ThrottleErrorCode = 0
+	custom.DirectThrottleMarker = "status=429; retry later"
+	custom.DirectBackOffTimeMs = 800
+	custom.PrimaryRegion = "West US"
+	custom.SecondaryRegionBackOffMs = 15000
+	custom.ActivityIDJitter = true
+	custom.HonorRetryAfterOnAnyError = true
+	custom.JitterFraction = 0.25
+	custom.JitterMode = FullJitter
+	custom.ParseFailureAction = FailOpen
+	custom.UnknownErrorPolicy = RetryUnknownErrors
+	custom.RetryNextHostOnUnavailable = true
+	custom.RetryNextHostOnConnectionErrors = true
+	custom.MaxHostHops = 3
+	custom.DowngradeConsistencyAfter = 2
+	custom.DowngradeConsistency = gocql.LocalOne
+	custom.RetryClientTimeouts = true
+	custom.NotReadyMarker = "unconfigured table"
+	custom.NotReadyBackOffTimeMs = 20000
+	custom.BackOffBoundsByKind = map[ErrorKind][2]time.Duration{NotReadyError: {5 * time.Second, 0}, TransientError: {0, 2 * time.Second}}
+	custom.SubStatusBackOff = map[int]time.Duration{3200: 500 * time.Millisecond, 3201: 10 * time.Second}
+	custom.ConsistencyBackOff = map[gocql.Consistency]time.Duration{gocql.LocalQuorum: 2 * time.Second, gocql.One: 10 * time.Millisecond}
+	custom.RetryAfterBuckets = []time.Duration{5 * time.Millisecond, 100 * time.Millisecond, 1500 * time.Millisecond}
+	custom.ClassificationRules = []ClassificationRule{
+		{Pattern: regexp.MustCompile(`(?i)status=(503|429); retry:\s*later,\s*maybe`), Kind: RateLimitedError, RetryType: gocql.RetryNextHost, BackOff: 1500 * time.Millisecond},
+		{Pattern: regexp.MustCompile("unauthorized"), Kind: UnclassifiedError, RetryType: gocql.Rethrow},
+		// never matches, and is not serialized
+		{Kind: TransientError, RetryType: gocql.Retry},
+	}
+
+	testCases := []testCase{
+		{"round trip for default policy", NewCosmosRetryPolicy(3)},
+		{"round trip for customized policy", custom},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			parsed, err := ParseConfig(tc.policy.MarshalConfig())
+			assert.NoError(te, err)
+			assert.Equal(te, tc.policy.MaxRetryCount, parsed.MaxRetryCount)
+			assert.Equal(te, tc.policy.FixedBackOffTimeMs, parsed.FixedBackOffTimeMs)
+			assert.Equal(te, tc.policy.GrowingBackOffTimeMs, parsed.GrowingBackOffTimeMs)
+			assert.Equal(te, tc.policy.MaxRateLimitedRetries, parsed.MaxRateLimitedRetries)
+			assert.Equal(te, tc.policy.BackOffStrategy, parsed.BackOffStrategy)
+			assert.Equal(te, tc.policy.MaxBackOffTimeMs, parsed.MaxBackOffTimeMs)
+			assert.Equal(te, tc.policy.MinBackOffTimeMs, parsed.MinBackOffTimeMs)
+			assert.Equal(te, tc.policy.SustainedThrottleThreshold, parsed.SustainedThrottleThreshold)
+			assert.Equal(te, tc.policy.SoftRetryWarnThreshold, parsed.SoftRetryWarnThreshold)
+			assert.Equal(te, tc.policy.LogRecovery, parsed.LogRecovery)
+			assert.Equal(te, tc.policy.MaxBlockedTimeMs, parsed.MaxBlockedTimeMs)
+			assert.Equal(te, tc.policy.MaxElapsedTimeMs, parsed.MaxElapsedTimeMs)
+			assert.Equal(te, tc.policy.AbsoluteMaxSleepMs, parsed.AbsoluteMaxSleepMs)
+			assert.Equal(te, tc.policy.SleepDisabled, parsed.SleepDisabled)
+			assert.Equal(te, tc.policy.IgnoreErrors, parsed.IgnoreErrors)
+			assert.Equal(te, tc.policy.ImmediateRetries, parsed.ImmediateRetries)
+			assert.Equal(te, tc.policy.ThrottleErrorCode, parsed.ThrottleErrorCode)
+			assert.Equal(te, tc.policy.DirectThrottleMarker, parsed.DirectThrottleMarker)
+			assert.Equal(te, tc.policy.DirectBackOffTimeMs, parsed.DirectBackOffTimeMs)
+			assert.Equal(te, tc.policy.PrimaryRegion, parsed.PrimaryRegion)
+			assert.Equal(te, tc.policy.SecondaryRegionBackOffMs, parsed.SecondaryRegionBackOffMs)
+			assert.Equal(te, tc.policy.ActivityIDJitter, parsed.ActivityIDJitter)
+			assert.Equal(te, tc.policy.HonorRetryAfterOnAnyError, parsed.HonorRetryAfterOnAnyError)
+			assert.Equal(te, tc.policy.JitterFraction, parsed.JitterFraction)
+			assert.Equal(te, tc.policy.JitterMode, parsed.JitterMode)
+			assert.Equal(te, tc.policy.ParseFailureAction, parsed.ParseFailureAction)
+			assert.Equal(te, tc.policy.UnknownErrorPolicy, parsed.UnknownErrorPolicy)
+			assert.Equal(te, tc.policy.RetryNextHostOnUnavailable, parsed.RetryNextHostOnUnavailable)
+			assert.Equal(te, tc.policy.RetryNextHostOnConnectionErrors, parsed.RetryNextHostOnConnectionErrors)
+			assert.Equal(te, tc.policy.MaxHostHops, parsed.MaxHostHops)
+			assert.Equal(te, tc.policy.DowngradeConsistencyAfter, parsed.DowngradeConsistencyAfter)
+			assert.Equal(te, tc.policy.DowngradeConsistency, parsed.DowngradeConsistency)
+			assert.Equal(te, tc.policy.RetryClientTimeouts, parsed.RetryClientTimeouts)
+			assert.Equal(te, tc.policy.NotReadyMarker, parsed.NotReadyMarker)
+			assert.Equal(te, tc.policy.NotReadyBackOffTimeMs, parsed.NotReadyBackOffTimeMs)
+			assert.Equal(te, tc.policy.SubStatusBackOff, parsed.SubStatusBackOff)
+			assert.Equal(te, tc.policy.ConsistencyBackOff, parsed.ConsistencyBackOff)
+			assert.Equal(te, tc.policy.BackOffBoundsByKind, parsed.BackOffBoundsByKind)
+			assert.Equal(te, tc.policy.RetryAfterBuckets, parsed.RetryAfterBuckets)
+			var expectedRules []ClassificationRule
+			for _, rule := range tc.policy.ClassificationRules {
+				if rule.Pattern != nil {
+					expectedRules = append(expectedRules, rule)
+				}
+			}
+			if assert.Len(te, parsed.ClassificationRules, len(expectedRules)) {
+				for i, rule := range expectedRules {
+					assert.Equal(te, rule.Pattern.String(), parsed.ClassificationRules[i].Pattern.String())
+					assert.Equal(te, rule.Kind, parsed.ClassificationRules[i].Kind)
+					assert.Equal(te, rule.RetryType, parsed.ClassificationRules[i].RetryType)
+					assert.Equal(te, rule.BackOff, parsed.ClassificationRules[i].BackOff)
+				}
+			}
+			assert.Equal(te, tc.policy.MarshalConfig(), parsed.MarshalConfig())
+		})
+	}
+}
+
+func TestParseConfigMalformed(t *testing.T) {
+	testCases := map[string]string{
+		"missing separator":       "max_retry_count",
+		"unknown key":             "max_retry_count=5;color=blue",
+		"non numeric value":       "fixed_backoff_ms=fast",
+		"invalid consistency":     "consistency_backoff=STRONGEST:1s",
+		"invalid duration":        "consistency_backoff=QUORUM:soon",
+		"malformed map entry":     "consistency_backoff=QUORUM",
+		"trailing separator key":  "max_retry_count=5;",
+		"invalid escaping":        "direct_throttle_marker=%zz",
+		"invalid boolean":         "activity_id_jitter=maybe",
+		"unknown error kind":      "backoff_bounds=fatal:1s..2s",
+		"malformed bounds":        "backoff_bounds=transient:1s",
+		"invalid bucket":          "retry_after_buckets=1ms,soon",
+		"unordered buckets":       "retry_after_buckets=1s,1ms",
+		"malformed rule":          "classification_rules=busy:transient:0",
+		"invalid rule pattern":    "classification_rules=%28busy:transient:0:1s",
+		"unknown rule error kind": "classification_rules=busy:fatal:0:1s",
+		"invalid rule retry type": "classification_rules=busy:transient:retry:1s",
+		"invalid rule back-off":   "classification_rules=busy:transient:0:soon",
+		"invalid max retry count": "max_retry_count=-2",
+		"negative back-off":       "fixed_backoff_ms=-1",
+	}
+
+	for name, config := range testCases {
+		t.Run(name, func(te *testing.T) {
+			p, err := ParseConfig(config)
+			assert.Error(te, err)
+			assert.Nil(te, p)
+		})
+	}
+}
+
+func TestParseConfigDefaults(t *testing.T) {
+	for _, config := range []string{"", "fixed_backoff_ms=5000"} {
+		parsed, err := ParseConfig(config)
+		assert.NoError(t, err)
+		assert.Equal(t, defaultMaxRetryCount, parsed.MaxRetryCount, "config %q", config)
+		assert.Equal(t, NewCosmosRetryPolicyWithOptions().MarshalConfig(), parsed.MarshalConfig(), "config %q", config)
+	}
+}
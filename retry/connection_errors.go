@@ -0,0 +1,39 @@
+package retry
+
+import (
+	"errors"
+	"io"
+	"net"
+
+	"github.com/gocql/gocql"
+)
+
+// connectionErrors are the errors returned when the connection to the coordinator failed, rather than the coordinator itself
+var connectionErrors = []error{gocql.ErrConnectionClosed, gocql.ErrNoStreams, io.EOF, io.ErrUnexpectedEOF}
+
+// isConnectionError reports whether err is, or wraps, a coordinator connection error, including network errors such as a connection reset or refused
+func isConnectionError(err error) bool {
+	for _, connErr := range connectionErrors {
+		if errors.Is(err, connErr) {
+			return true
+		}
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// wasNotSent reports whether the query was certainly not sent to the coordinator, which is the case if no stream was available, or if the connection could not even be established
+func wasNotSent(err error) bool {
+	var opErr *net.OpError
+	return errors.Is(err, gocql.ErrNoStreams) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// retryConnectionError retries a coordinator connection error on the next host as per RetryNextHostOnConnectionErrors. Unless the query was certainly not sent, it is only retried if idempotent, as the coordinator may have executed it already
+func (crp *CosmosRetryPolicy) retryConnectionError(ex *execution, err error) gocql.RetryType {
+	crp.recordThrottle(ex, false)
+	if !ex.idempotent && !wasNotSent(err) {
+		crp.recordPath(ex, DecisionPathNotIdempotent)
+		return gocql.Rethrow
+	}
+	return crp.nextHost(ex)
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryNextHostOnConnectionErrors(t *testing.T) {
+	type testCase struct {
+		name         string
+		err          error
+		idempotent   bool
+		expected     gocql.RetryType
+		expectedPath string
+	}
+
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	testCases := []testCase{
+		{"unavailable error", &gocql.RequestErrUnavailable{}, false, gocql.RetryNextHost, DecisionPathNextHost},
+		{"connection closed waiting for response", gocql.ErrConnectionClosed, true, gocql.RetryNextHost, DecisionPathNextHost},
+		{"wrapped connection closed", fmt.Errorf("exec: %w", gocql.ErrConnectionClosed), true, gocql.RetryNextHost, DecisionPathNextHost},
+		{"connection reset", reset, true, gocql.RetryNextHost, DecisionPathNextHost},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true, gocql.RetryNextHost, DecisionPathNextHost},
+		{"connection closed for a query which is not idempotent", gocql.ErrConnectionClosed, false, gocql.Rethrow, DecisionPathNotIdempotent},
+		{"connection reset for a query which is not idempotent", reset, false, gocql.Rethrow, DecisionPathNotIdempotent},
+		{"connection refused for a query which is not idempotent", refused, false, gocql.RetryNextHost, DecisionPathNextHost},
+		{"no streams for a query which is not idempotent", gocql.ErrNoStreams, false, gocql.RetryNextHost, DecisionPathNextHost},
+		{"other error", errors.New("error: today is not your day!"), true, gocql.Rethrow, DecisionPathNotRetryable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.RetryNextHostOnUnavailable = true
+			p.RetryNextHostOnConnectionErrors = true
+
+			assert.True(te, p.Attempt(idempotentQuery{MockRetryableQuery{}, tc.idempotent}))
+			assert.Equal(te, tc.expected, p.GetRetryType(tc.err))
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+		})
+	}
+}
+
+func TestConnectionErrorsRethrownByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	assert.True(t, p.Attempt(idempotentQuery{MockRetryableQuery{}, true}))
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(gocql.ErrConnectionClosed))
+	assert.Equal(t, TransientError, p.ClassifyError(gocql.ErrConnectionClosed))
+}
+
+func TestConnectionErrorsBoundedByMaxHostHops(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.RetryNextHostOnConnectionErrors = true
+	p.MaxHostHops = 1
+
+	p.Attempt(attemptsQuery{attempts: 1})
+	assert.Equal(t, gocql.RetryNextHost, p.GetRetryType(gocql.ErrConnectionClosed))
+	p.Attempt(attemptsQuery{attempts: 2})
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(gocql.ErrConnectionClosed))
+	assert.Equal(t, DecisionPathHostHopsExhausted, p.LastDecisionPath())
+}
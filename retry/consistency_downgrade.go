@@ -0,0 +1,29 @@
+package retry
+
+import (
+	"errors"
+
+	"github.com/gocql/gocql"
+)
+
+// countUnavailable accounts for err in the run of consecutive RequestErrUnavailable errors of ex
+func (crp *CosmosRetryPolicy) countUnavailable(ex *execution, err error) {
+	var unavailable *gocql.RequestErrUnavailable
+	if errors.As(err, &unavailable) {
+		ex.unavailable++
+		return
+	}
+	ex.unavailable = 0
+}
+
+// downgradeConsistency sets the consistency level of the query of ex to DowngradeConsistency, once it has failed with DowngradeConsistencyAfter consecutive RequestErrUnavailable errors
+func (crp *CosmosRetryPolicy) downgradeConsistency(ex *execution) {
+	if crp.DowngradeConsistencyAfter <= 0 || ex.unavailable < crp.DowngradeConsistencyAfter || ex.query == nil {
+		return
+	}
+	if ex.consistency == crp.DowngradeConsistency {
+		return
+	}
+	ex.query.SetConsistency(crp.DowngradeConsistency)
+	ex.consistency = crp.DowngradeConsistency
+}
@@ -0,0 +1,89 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// consistencyQuery records the consistency levels set on it
+type consistencyQuery struct {
+	MockRetryableQuery
+	attempts int
+	set      *[]gocql.Consistency
+}
+
+func (cq consistencyQuery) Attempts() int {
+	return cq.attempts
+}
+
+// IsIdempotent allows timeouts to be retried
+func (cq consistencyQuery) IsIdempotent() bool {
+	return true
+}
+
+func (cq consistencyQuery) SetConsistency(c gocql.Consistency) {
+	*cq.set = append(*cq.set, c)
+}
+
+func TestDowngradeConsistency(t *testing.T) {
+	type testCase struct {
+		name     string
+		after    int
+		errs     []error
+		expected []gocql.Consistency
+	}
+
+	unavailable := &gocql.RequestErrUnavailable{}
+	testCases := []testCase{
+		{"disabled by default", 0, []error{unavailable, unavailable, unavailable}, nil},
+		{"below the threshold", 3, []error{unavailable, unavailable}, nil},
+		{"at the threshold", 3, []error{unavailable, unavailable, unavailable}, []gocql.Consistency{gocql.LocalOne}},
+		{"downgraded once", 2, []error{unavailable, unavailable, unavailable, unavailable}, []gocql.Consistency{gocql.LocalOne}},
+		{"wrapped unavailable errors", 2, []error{unavailable, fmt.Errorf("exec: %w", unavailable)}, []gocql.Consistency{gocql.LocalOne}},
+		{"unavailable errors must be consecutive", 2, []error{unavailable, &gocql.RequestErrReadTimeout{}, unavailable}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(10)
+			p.DowngradeConsistencyAfter = tc.after
+			p.DowngradeConsistency = gocql.LocalOne
+
+			var set []gocql.Consistency
+			q := consistencyQuery{MockRetryableQuery{consistency: gocql.LocalQuorum, ctx: WithAttemptScope(context.Background())}, 0, &set}
+			for i, err := range tc.errs {
+				assert.True(te, p.Attempt(q))
+				assert.Equal(te, gocql.Retry, p.GetRetryType(err), "error %d", i)
+				if len(set) > 0 {
+					// gocql reports the downgraded level from then on
+					q.consistency = set[len(set)-1]
+				}
+			}
+			assert.Equal(te, tc.expected, set)
+		})
+	}
+}
+
+func TestDowngradeConsistencyOfUnscopedQueries(t *testing.T) {
+	p := NewCosmosRetryPolicy(10)
+	p.DowngradeConsistencyAfter = 2
+	p.DowngradeConsistency = gocql.One
+
+	var set []gocql.Consistency
+	for attempt := 1; attempt <= 2; attempt++ {
+		assert.True(t, p.Attempt(consistencyQuery{MockRetryableQuery{consistency: gocql.Quorum}, attempt, &set}))
+		assert.Equal(t, gocql.Retry, p.GetRetryType(&gocql.RequestErrUnavailable{}))
+	}
+	assert.Equal(t, []gocql.Consistency{gocql.One}, set)
+
+	// a new query starts counting afresh
+	set = nil
+	assert.True(t, p.Attempt(consistencyQuery{MockRetryableQuery{consistency: gocql.Quorum}, 1, &set}))
+	p.GetRetryType(&gocql.RequestErrUnavailable{})
+	assert.Empty(t, set)
+	assert.Nil(t, p.lastExecution().query, "the query should not be retained")
+}
@@ -1,54 +1,410 @@
 package retry
 
 import (
-	"math/rand"
+	"container/list"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gocql/gocql"
 )
 
-// CosmosRetryPolicy implements gcql.RetryPolicy. Retires only if query attempts are less than or equal to max retry config or max retry config is set to -1 (infinite retries). For RequestErrReadTimeout, RequestErrUnavailable, RequestErrWriteTimeout the request is retried immediately. For rate limited (429) errors, retries are eexecuted after waiting for a duration of RetryAfterMs. If not available, time between retries is increased as per GrowingBackOffTimeMs. If MaxRetryCount is -1 (inifinite) then retry back-off is as per FixedBackOffTimeMs
+// CosmosRetryPolicy implements gcql.RetryPolicy. Retires only if query attempts are less than or equal to max retry config or max retry config is set to -1 (infinite retries). For RequestErrReadTimeout, RequestErrUnavailable, RequestErrWriteTimeout the request is retried immediately (up to ImmediateRetries times, if configured). Read timeouts, and write timeouts of writes which are not safe to repeat (any but BATCH_LOG, e.g. COUNTER), are only retried for queries marked idempotent (see gocql.Query.Idempotent). Unavailable errors are retried regardless, since the query was not executed. For rate limited (429) errors, retries are executed after waiting for a duration of RetryAfterMs. If not available, retries back off as per FixedBackOffTimeMs, or, if MaxRetryCount is -1 (infinite), for a time increased as per GrowingBackOffTimeMs
 type CosmosRetryPolicy struct {
 	MaxRetryCount        int
 	FixedBackOffTimeMs   int
 	GrowingBackOffTimeMs int
-	numAttempts          int
+	// MaxRateLimitedRetries bounds the number of retries of a single query caused by rate limited (429) errors, regardless of MaxRetryCount, since sustained throttling is rarely resolved by retrying. Queries are told apart as per WithAttemptScope. 0 means no limit other than MaxRetryCount
+	MaxRateLimitedRetries int
+	// BackOffStrategy defines how the growing back-off grows with the number of attempts. Defaults to Linear
+	BackOffStrategy BackOffStrategy
+	// JitterMode defines how the fixed and growing back-offs are jittered. Defaults to EqualJitter
+	JitterMode JitterMode
+	// MaxBackOffTimeMs caps the growing back-off used with infinite retries. Defaults to 30s, 0 (or less) means no cap
+	MaxBackOffTimeMs int
+	// MinBackOffTimeMs is the floor of the back-off provided by the server (RetryAfterMs), which may be as small as 1ms under heavy throttling. Smaller back-offs are raised to a random value between MinBackOffTimeMs and 1.5 times it (MinBackOffTimeMs exactly with NoJitter). 0 (default) means RetryAfterMs is honored as is
+	MinBackOffTimeMs int
+	// SustainedThrottleThreshold is the number of consecutive rate limited (429) errors after which a one-time advisory is logged. 0 disables it
+	SustainedThrottleThreshold int
+	// SoftRetryWarnThreshold is the attempt count of a query at which a warning is logged, once per query, as an early sign of a persistent problem, before MaxRetryCount is reached. Retries are not affected. 0 (default) disables it
+	SoftRetryWarnThreshold int
+	// SubStatusBackOff overrides the back-off of rate limited errors, keyed by their Cosmos DB substatus code, e.g. 3200 for provisioned throughput (RU) throttling as opposed to throttling of metadata or system operations. It takes precedence over RetryAfterMs. Errors with other (or no) substatus codes are backed off as usual
+	SubStatusBackOff map[int]time.Duration
+	// ConsistencyBackOff overrides the back-off used when RetryAfterMs is not available, keyed by the consistency level of the query
+	ConsistencyBackOff map[gocql.Consistency]time.Duration
+	// RetryWindow, if set, is evaluated against the current time before each retry. Retries are not attempted when it returns false. See DailyWindow
+	RetryWindow func(time.Time) bool
+	// MaxBlockedTimeMs bounds the total back-off time workers may be sleeping through at any given moment. Once reached, rate limited errors are rethrown instead of blocking yet another worker. 0 means no limit
+	MaxBlockedTimeMs int
+	// MaxElapsedTimeMs bounds the cumulative back-off of a single query across its retries (or, if greater, the time elapsed since its first failed attempt, including the time spent executing it), regardless of the number of attempts. Once a back-off would exceed it, the error is rethrown instead. Queries are told apart as per WithAttemptScope. 0 means no limit
+	MaxElapsedTimeMs int
+	// AbsoluteMaxSleepMs caps every back-off sleep, however long the server asks to back off for, for queries whose context has no deadline (e.g. background jobs), so that they remain responsive. Queries with a deadline are backed off until it at most instead. 0 means no cap
+	AbsoluteMaxSleepMs int
+	// ImmediateRetries is the number of retries of timeout and unavailable errors executed without delay. Subsequent retries back off the same way as rate limited errors without RetryAfterMs. 0 means such errors are always retried immediately
+	ImmediateRetries int
+	// ThrottleErrorCode is the CQL protocol error code (of gocql.RequestError errors) which identifies rate limited errors regardless of their message. Defaults to Overloaded (0x1001), which the Cassandra API uses for 429s. 0 disables it
+	ThrottleErrorCode int
+	// DirectThrottleMarker identifies rate limited errors reported in direct connectivity mode. Empty disables direct mode detection
+	DirectThrottleMarker string
+	// DirectBackOffTimeMs is the back-off for direct mode rate limited errors without RetryAfterMs. 0 means the same back-off as gateway errors is used
+	DirectBackOffTimeMs int
+	// PrimaryRegion is the name of the preferred region, e.g. "West US". Rate limited errors without RetryAfterMs which were served by another region (as per the RegionName in the error) are backed off as per SecondaryRegionBackOffMs, expecting the primary to recover. Empty (default) disables it
+	PrimaryRegion string
+	// SecondaryRegionBackOffMs is the back-off for rate limited errors served by a region other than PrimaryRegion. 0 means the usual back-off is used
+	SecondaryRegionBackOffMs int
+	// ActivityIDJitter derives the back-off jitter from the ActivityID of rate limited errors (instead of a random value), so that requests throttled by the same event back off at stable, yet different, offsets
+	ActivityIDJitter bool
+	// JitterFraction, if set (between 0 and 1), jitters the fixed and growing back-offs by up to ±JitterFraction of their base value, instead of adding a random salt of up to 2s to the growing back-off. Combined with ActivityIDJitter, the jitter is derived from the ActivityID
+	JitterFraction float64
+	// HonorRetryAfterOnAnyError backs off as per RetryAfterMs whenever an error carries it, even if it is not a rate limited (429) error
+	HonorRetryAfterOnAnyError bool
+	// ParseFailureAction defines how errors which look like throttling, but cannot be classified, are handled. Defaults to FailClosed (rethrow)
+	ParseFailureAction ParseFailureAction
+	// UnknownErrorPolicy defines how errors which are not classified, e.g. network blips surfaced by gocql as plain errors, are handled. Defaults to RethrowUnknownErrors
+	UnknownErrorPolicy UnknownErrorPolicy
+	// RetryNextHostOnUnavailable retries RequestErrUnavailable errors on the next host rather than the same one
+	RetryNextHostOnUnavailable bool
+	// DowngradeConsistencyAfter is the number of consecutive RequestErrUnavailable errors of a query after which it is retried at DowngradeConsistency, e.g. when replicas of a region are down. 0 (default) disables it, queries are then retried at their own consistency level
+	DowngradeConsistencyAfter int
+	// DowngradeConsistency is the consistency level queries are retried at once DowngradeConsistencyAfter is reached, e.g. gocql.LocalOne
+	DowngradeConsistency gocql.Consistency
+	// RetryNextHostOnConnectionErrors retries errors of the connection to the coordinator (e.g. gocql.ErrConnectionClosed, or a connection reset) on the next host, rather than rethrowing them. Unless the query was certainly not sent, only idempotent queries are retried
+	RetryNextHostOnConnectionErrors bool
+	// MaxHostHops bounds the number of times a single query may be retried on the next host. Once reached, the error is rethrown. 0 means no limit, except for write forbidden (403) errors, which are retried on 3 hosts at most
+	MaxHostHops int
+	// LogRecovery logs the first successful query after sustained throttling. Requires the policy to be registered as the gocql query (and batch) observer
+	LogRecovery bool
+	// LogDecisions logs every decision made by GetRetryType, along with the attempt number, the back-off and the RetryAfterMs carried by the error (if any). Requires Logger
+	LogDecisions bool
+	// IgnoreErrors resolves the given read errors to gocql.Ignore, returning whatever the query got instead of an error. It is strictly opt-in, as reads may return partial (or no) data. 0 (default) ignores none
+	IgnoreErrors IgnoredErrors
+	// RetryClientTimeouts retries client-side timeouts, where no response was received from the server (e.g. gocql.ErrTimeoutNoResponse), the same way as RequestErrWriteTimeout. Only idempotent queries are retried
+	RetryClientTimeouts bool
+	// NotReadyMarker identifies (case-insensitively) errors returned while a keyspace or table is still being created, e.g. "unconfigured table". Such errors are retried after NotReadyBackOffTimeMs. Empty (default) disables detection
+	NotReadyMarker string
+	// NotReadyBackOffTimeMs is the back-off for errors matching NotReadyMarker
+	NotReadyBackOffTimeMs int
+	// SleepTransform, if set, is applied to every computed back-off right before sleeping, e.g. to scale or clamp waits globally during incidents. Defaults to identity
+	SleepTransform func(time.Duration) time.Duration
+	// SleepDisabled makes GetRetryType return its decision right away, without sleeping, for callers which manage back-offs themselves, e.g. in their own scheduler. The back-off to honor is reported as the BackOff of the RetryEvent passed to OnRetry, and is accounted for in MaxElapsedTimeMs as if it had been slept (but not in MaxBlockedTimeMs)
+	SleepDisabled bool
+	// BackOffBoundsByKind clamps every back-off to a [floor, ceiling] range, keyed by the ErrorKind of the error (as per ClassifyError). A ceiling of 0 means no ceiling. Back-offs of other kinds are left as computed
+	BackOffBoundsByKind map[ErrorKind][2]time.Duration
+	// RetryAfterBuckets are the upper bounds of the buckets of RetryAfterHistogram, in increasing order. Defaults to DefaultRetryAfterBuckets
+	RetryAfterBuckets []time.Duration
+	// Classifier, if set, decides how errors are retried ahead of the built-in handling, which applies to errors it returns DecisionDefault for
+	Classifier Classifier
+	// ClassificationRules are evaluated in order, before DefaultClassificationRules and the rest of the built-in logic. The first rule matching the error message decides how it is handled
+	ClassificationRules []ClassificationRule
+	// Limiter, if set, is acquired before each retry, which is deferred until a slot frees up or rethrown if none does. For queries executed with WithAttemptScope, the slot is released once the retry fails again or is given up on, or once it succeeds (provided the policy is registered as the gocql query observer). The success of other queries goes unnoticed, hence their slot is released once the retry decision (including its back-off) is made
+	Limiter Limiter
+	// CircuitBreaker, if set, rethrows errors without retrying them while it is open, i.e. during sustained throttling across queries. Register the policy as the gocql query observer for successful queries to close a half-open breaker
+	CircuitBreaker *CircuitBreaker
+	// OnRetry, if set, is called with every decision made by GetRetryType, right before it returns. It is called on the goroutine of the query, hence it should be quick. Panics are recovered from and ignored
+	OnRetry func(RetryEvent)
+	// OnThrottleExhausted, if set, is called when Attempt denies a further retry of a query (as per MaxRetryCount or RetryWindow) whose errors have all been rate limited (429) errors, a strong signal that the container is under-provisioned, e.g. to alert or to scale it up. It is passed the number of attempts of the query and the ActivityID of its latest error. It is called on the goroutine of the query, hence it should be quick. Panics are recovered from and ignored
+	OnThrottleExhausted func(attempts int, lastActivityID string)
+	// Logger receives diagnostic messages. Nothing is logged if it is nil
+	Logger Logger
+
+	exec                 execution
+	execMu               sync.Mutex
+	queries              map[gocql.RetryableQuery]*trackedExecution
+	queryOrder           list.List
+	finishedQueries      int
+	pending              map[uint64]*execution
+	pendingMu            sync.Mutex
+	now                  func() time.Time
+	sleepFunc            func(time.Duration)
+	randFunc             func(int) int
+	rand                 randSource
+	blockedNanos         int64
+	blockedWorkers       int64
+	totalBlockedNanos    int64
+	lastRetryAfterNanos  int64
+	consecutiveThrottles int64
+	advised              int32
+	warnedNegative       int32
+	warnedWriteForbidden int32
+	decisionPath         atomic.Value
+	lastActivityID       atomic.Value
+	chaosEnabled         int32
+	chaosFraction        uint64
+	counters             retryCounters
+	tracer               tracer
+	retryStats           retryStats
+	retryAfterHistogram  retryAfterHistogram
 }
 
+const defaultMaxRetryCount = 3
 const defaultGrowingBackOffTimeMs = 1000
+const defaultMaxBackOffTimeMs = 30000
 const defaultFixedBackOffTimeMs = 5000
 
-// NewCosmosRetryPolicy returns a CosmosRetryPolicy with default values for growing and fixed back-off time (in ms)
-func NewCosmosRetryPolicy(maxRetryCount int) *CosmosRetryPolicy {
-	return &CosmosRetryPolicy{MaxRetryCount: maxRetryCount, FixedBackOffTimeMs: defaultFixedBackOffTimeMs, GrowingBackOffTimeMs: defaultGrowingBackOffTimeMs}
+// NewCosmosRetryPolicy returns a CosmosRetryPolicy with default values for growing and fixed back-off time (in ms), customized by the provided options (applied in order). It satisfies RetryPolicy, which code depending on the policy may accept instead, e.g. to inject a MockRetryPolicy in tests. It never fails: invalid options are ignored, and a maxRetryCount below -1 is taken to be -1 (infinite retries), with a warning logged (once, using the standard log package since the policy has no Logger yet). See BuildCosmosRetryPolicy for a variant which returns an error instead. NewCosmosRetryPolicyWithOptions, which takes the max retry count as an option like any other setting, is preferred for new code, whereas this form is kept for compatibility
+func NewCosmosRetryPolicy(maxRetryCount int, opts ...Option) *CosmosRetryPolicy {
+	crp := newCosmosRetryPolicy(maxRetryCount)
+	var problems []string
+	for _, opt := range opts {
+		if err := opt(crp); err != nil {
+			problems = append(problems, err.Error()+" (ignored)")
+		}
+	}
+	if crp.MaxRetryCount < -1 {
+		problems = append(problems, fmt.Sprintf("invalid MaxRetryCount %d: must be -1 (infinite) or more (using -1)", crp.MaxRetryCount))
+		crp.MaxRetryCount = -1
+	}
+	if len(problems) > 0 {
+		log.Printf(misconfigurationWarning, strings.Join(problems, "; "))
+	}
+	return crp
+}
+
+// misconfigurationWarning is logged by NewCosmosRetryPolicy for the invalid settings it ignores or corrects
+const misconfigurationWarning = "cosmos retry policy misconfigured: %s"
+
+// NewCosmosRetryPolicyWithOptions returns a CosmosRetryPolicy configured by the provided options alone (applied in order), e.g. NewCosmosRetryPolicyWithOptions(WithMaxRetryCount(5), WithFixedBackOff(time.Second)). It is the preferred constructor, as every setting is configured the same way. MaxRetryCount defaults to 3 and the other settings to the defaults of NewCosmosRetryPolicy. Invalid options are ignored with a warning, like NewCosmosRetryPolicy
+func NewCosmosRetryPolicyWithOptions(opts ...Option) *CosmosRetryPolicy {
+	return NewCosmosRetryPolicy(defaultMaxRetryCount, opts...)
+}
+
+// BuildCosmosRetryPolicy is like NewCosmosRetryPolicy, but returns the error of the first invalid option (e.g. a negative back-off) or setting, see Validate, rather than ignoring or correcting it. Use it to reject misconfiguration, e.g. at startup
+func BuildCosmosRetryPolicy(maxRetryCount int, opts ...Option) (*CosmosRetryPolicy, error) {
+	crp := newCosmosRetryPolicy(maxRetryCount)
+	for _, opt := range opts {
+		if err := opt(crp); err != nil {
+			return nil, err
+		}
+	}
+	if err := crp.Validate(); err != nil {
+		return nil, err
+	}
+	return crp, nil
+}
+
+// newCosmosRetryPolicy returns a CosmosRetryPolicy with the given max retry count and default values for every other setting
+func newCosmosRetryPolicy(maxRetryCount int) *CosmosRetryPolicy {
+	return &CosmosRetryPolicy{MaxRetryCount: maxRetryCount, FixedBackOffTimeMs: defaultFixedBackOffTimeMs, GrowingBackOffTimeMs: defaultGrowingBackOffTimeMs, MaxBackOffTimeMs: defaultMaxBackOffTimeMs, SustainedThrottleThreshold: defaultSustainedThrottleThreshold, ThrottleErrorCode: defaultThrottleErrorCode, DirectThrottleMarker: defaultDirectThrottleMarker, NotReadyBackOffTimeMs: defaultNotReadyBackOffTimeMs}
 }
 
-// Attempt decides whether to retry or not. Retries only if query attempts are less than or equal to max retry config or max retry config is set to -1 (infinite retries)
+// recommended values, as per the Azure Cosmos DB Cassandra API guidance (and the defaults of its Java retry policy extension)
+const (
+	recommendedMaxRetryCount  = 3
+	recommendedFixedBackOff   = 5 * time.Second
+	recommendedGrowingBackOff = time.Second
+	recommendedMaxBackOff     = 30 * time.Second
+)
+
+// NewRecommendedCosmosRetryPolicy returns a CosmosRetryPolicy tuned as recommended for the Cassandra API of Cosmos DB: up to 3 retries, backed off as per RetryAfterMs or, if not available, 5s apart. It is a good starting point if unsure which values to pick, and can be customized by the provided options (applied after the recommended ones)
+func NewRecommendedCosmosRetryPolicy(opts ...Option) *CosmosRetryPolicy {
+	recommended := []Option{WithFixedBackOff(recommendedFixedBackOff), WithGrowingBackOff(recommendedGrowingBackOff), WithMaxBackOff(recommendedMaxBackOff)}
+	return NewCosmosRetryPolicy(recommendedMaxRetryCount, append(recommended, opts...)...)
+}
+
+// Attempt decides whether to retry or not. Retries only if query attempts are less than or equal to max retry config or max retry config is set to -1 (infinite retries), and the current time is within RetryWindow (if configured). Attempts of queries executed with a context tagged using WithAttemptScope are counted per scope. The state of the attempt is handed to the GetRetryType call which follows it on the same goroutine (as gocql makes it), so that concurrent queries sharing the policy back off as per their own attempt count
 func (crp *CosmosRetryPolicy) Attempt(rq gocql.RetryableQuery) bool {
-	crp.numAttempts = rq.Attempts()
-	return rq.Attempts() <= crp.MaxRetryCount || crp.MaxRetryCount == -1
+	id := goroutineID()
+	crp.dropAbandoned(id)
+	ctx := queryContext(rq)
+	ex, scoped := scopedExecution(ctx)
+	if scoped {
+		ex.next()
+	} else {
+		ex = crp.sharedExecution(rq)
+	}
+	ex.consistency = rq.GetConsistency()
+	ex.healthCheck = IsHealthCheck(ctx)
+	ex.idempotent = isIdempotent(rq)
+	ex.requestID = RequestID(ctx)
+	ex.ctx = ctx
+	ex.query = rq
+	if ex.start.IsZero() {
+		ex.start = crp.currentTime()
+	}
+	// the previous retry, if any, has failed
+	crp.releaseSlot(ex)
+
+	allowed := crp.allowed(ex.attempts)
+	if !allowed {
+		crp.throttleExhausted(ex)
+	}
+	ex.path = ""
+	if !allowed || !crp.acquireSlot(ex) {
+		if allowed {
+			// the Limiter denied the retry
+			crp.publishPath(ex)
+		}
+		// gocql rethrows the error
+		ex.decision = gocql.Rethrow
+		crp.finish(ex, false)
+		crp.storeExecution(ex)
+		return false
+	}
+	crp.warnSoftRetryThreshold(ex)
+	crp.storeExecution(ex)
+	crp.handOff(id, ex)
+	return true
+}
+
+// decision returns the retry decision for err, the latest error of ex, along with its back-off (see backOff), which is left to the caller to sleep for. It is shared by GetRetryType and Decide
+func (crp *CosmosRetryPolicy) decision(ex *execution, err error) gocql.RetryType {
+	ex.kind = crp.ClassifyError(err)
+	ex.path = ""
+	ex.backOff = 0
+	ex.hasBackOff = false
+	ex.activityID = parseActivityID(err.Error())
+	if ex.kind == RateLimitedError {
+		ex.rateLimited = true
+	} else {
+		ex.notRateLimited = true
+	}
+	crp.countUnavailable(ex, err)
+	return crp.retryType(ex, err)
+}
+
+// isRetry reports whether retryType retries the query, on the same host or the next one
+func isRetry(retryType gocql.RetryType) bool {
+	return retryType == gocql.Retry || retryType == gocql.RetryNextHost
 }
 
-// GetRetryType determines the RetryType. In case of rate limiting (429), it parses the error message to get RetryAfterMs
+// allowed reports whether a query which has been attempted the given number of times may be retried
+func (crp *CosmosRetryPolicy) allowed(attempts int) bool {
+	if !crp.retryWindowOpen() {
+		return false
+	}
+	return attempts <= crp.MaxRetryCount || crp.MaxRetryCount == -1
+}
+
+// GetRetryType determines the RetryType. In case of rate limiting (429), it parses the error message to get RetryAfterMs. Errors are rethrown without backing off if Attempt would not allow another attempt. Back-offs are shortened so as not to outlast the deadline of the query context (the error is rethrown if it is about to be reached), and cut short, with the error rethrown, once the context is done. See Decide for the decision alone, without sleeping
 func (crp *CosmosRetryPolicy) GetRetryType(err error) gocql.RetryType {
+	return crp.decide(crp.claimExecution(), err)
+}
 
-	switch err.(type) {
-	default:
-		retryAfterMs := crp.getRetryAfterMs(err.Error())
-		if retryAfterMs == -1 {
-			return gocql.Rethrow
+// decide makes the retry decision for err, the latest error of ex, then sleeps for its back-off and accounts for it
+func (crp *CosmosRetryPolicy) decide(ex *execution, err error) gocql.RetryType {
+	retryType := crp.decision(ex, err)
+	crp.closeCircuit(ex, retryType)
+	activityID := ex.activityID
+	crp.recordActivityID(activityID)
+	waited := ex.totalWait
+	if ex.hasBackOff && isRetry(retryType) && !crp.sleep(ex) {
+		retryType = gocql.Rethrow
+	}
+	backOff := ex.totalWait - waited
+	ex.decision = retryType
+	crp.publishPath(ex)
+	crp.trace(ex, err, retryType, backOff)
+	if !isRetry(retryType) {
+		crp.finish(ex, false)
+	}
+	crp.counters.record(retryType, ex.kind == RateLimitedError)
+	crp.logDecision(ex, err, retryType, backOff)
+	if ex.shared {
+		// the hand-off is complete: unlike scoped executions, the state of queries which are not scoped does not keep Limiter slots, as their success goes unnoticed
+		crp.releaseSlot(ex)
+	}
+	crp.storeExecution(ex)
+	crp.emit(RetryEvent{Attempt: ex.attempts, RetryType: retryType, BackOff: backOff, RateLimited: ex.kind == RateLimitedError, DecisionPath: ex.path, Elapsed: crp.currentTime().Sub(ex.start), ActivityID: activityID})
+	return retryType
+}
+
+func (crp *CosmosRetryPolicy) retryType(ex *execution, err error) gocql.RetryType {
+	// do not back off for an attempt which would be denied anyway
+	if !crp.allowed(ex.attempts) {
+		crp.recordPath(ex, DecisionPathAttemptsExhausted)
+		return gocql.Rethrow
+	}
+	if !crp.circuitClosed(ex) {
+		return gocql.Rethrow
+	}
+	if crp.chaosThrottled() {
+		return crp.simulateThrottle(ex)
+	}
+
+	if crp.Classifier != nil {
+		if decision, backOff := crp.Classifier.Classify(err); decision != DecisionDefault {
+			return crp.applyDecision(ex, decision, backOff)
 		}
-		time.Sleep(retryAfterMs)
-		return gocql.Retry
-	case *gocql.RequestErrReadTimeout:
-		return gocql.Retry
-	case *gocql.RequestErrUnavailable:
-		return gocql.Retry
-	case *gocql.RequestErrWriteTimeout:
+	}
+
+	if crp.ignored(err) {
+		crp.recordThrottle(ex, false)
+		crp.recordPath(ex, DecisionPathIgnored)
+		return gocql.Ignore
+	}
+
+	// timeouts may be wrapped, e.g. by middleware
+	var (
+		readTimeout  *gocql.RequestErrReadTimeout
+		writeTimeout *gocql.RequestErrWriteTimeout
+		unavailable  *gocql.RequestErrUnavailable
+	)
+	switch {
+	case errors.As(err, &readTimeout):
+		crp.recordThrottle(ex, false)
+		return crp.retryReadTimeout(ex)
+	case errors.As(err, &writeTimeout):
+		crp.recordThrottle(ex, false)
+		return crp.retryWriteTimeout(ex, writeTimeout)
+	case errors.As(err, &unavailable):
+		crp.recordThrottle(ex, false)
+		crp.downgradeConsistency(ex)
+		if crp.RetryNextHostOnUnavailable {
+			return crp.nextHost(ex)
+		}
+		return crp.retryTimeout(ex)
+	}
+
+	if crp.RetryClientTimeouts && isClientTimeout(err) {
+		return crp.retryClientTimeout(ex)
+	}
+	if crp.RetryNextHostOnConnectionErrors && isConnectionError(err) {
+		return crp.retryConnectionError(ex, err)
+	}
+	if rule, ok := crp.matchRule(err.Error()); ok && !rule.builtIn() {
+		return crp.applyRule(ex, rule)
+	}
+	if isWriteForbidden(err.Error()) {
+		return crp.retryWriteForbidden(ex)
+	}
+	retryAfterMs, rateLimited := crp.throttleBackOff(ex, crp.ClassifyThrottle(err), err, err.Error())
+	crp.recordThrottle(ex, rateLimited)
+	if !rateLimited {
+		if crp.isNotReady(err.Error()) {
+			return crp.retryNotReady(ex)
+		}
+		if isAmbiguous(err.Error()) {
+			return crp.onParseFailure(ex, err.Error())
+		}
+		return crp.onUnknownError(ex, err)
+	}
+	if !crp.rateLimitedRetryAllowed(ex) {
+		return gocql.Rethrow
+	}
+	if !crp.backOff(ex, retryAfterMs) {
+		return gocql.Rethrow
+	}
+	return gocql.Retry
+}
+
+// retryTimeout retries a timeout (or unavailable) error immediately, unless the immediate retries are used up
+func (crp *CosmosRetryPolicy) retryTimeout(ex *execution) gocql.RetryType {
+	if crp.ImmediateRetries <= 0 || ex.attempts <= crp.ImmediateRetries {
+		crp.recordPath(ex, DecisionPathTimeoutImmediate)
 		return gocql.Retry
 	}
+	if !crp.backOff(ex, crp.fallbackBackOff(ex.consistency, ex.attempts, "")) {
+		return gocql.Rethrow
+	}
+	crp.recordPath(ex, DecisionPathTimeoutBackOff)
+	return gocql.Retry
 }
 
 const rateLimitingErrPart = "TooManyRequests (429)"
@@ -63,28 +419,204 @@ const growingBackOffSaltMillis = 2000
 	  ]
 	});
 */
-func (crp *CosmosRetryPolicy) getRetryAfterMs(errMsg string) time.Duration {
+func (crp *CosmosRetryPolicy) getRetryAfterMs(ex *execution, errMsg string) (time.Duration, bool) {
+	return crp.throttleBackOff(ex, crp.throttleMode(errMsg), nil, errMsg)
+}
+
+// throttleBackOff returns the back-off for an error classified as per mode, and false if it is not a retryable (rate limited) error. err, if not nil, is the error errMsg is the message of, whose structured fields take precedence over it (see RetryAfterError)
+func (crp *CosmosRetryPolicy) throttleBackOff(ex *execution, mode ThrottleMode, err error, errMsg string) (time.Duration, bool) {
+	switch mode {
 	// if rate limiting error
+	case GatewayThrottle:
+		ce := cosmosErrorOf(err, errMsg)
+		crp.recordRetryAfter(ex, ce)
+		if backOff, ok := crp.subStatusBackOff(ex, ce.subStatus); ok {
+			return backOff, true
+		}
+		if ce.hinted {
+			crp.recordPath(ex, DecisionPathServerHinted)
+			return crp.floorBackOff(ce.retryAfter), true
+		}
+		//if RetryAfterMs is not available
+		if backOff, ok := crp.secondaryRegionBackOff(ex, errMsg); ok {
+			return backOff, true
+		}
+		crp.recordPath(ex, crp.fallbackPath(ex.consistency))
+		return crp.fallbackBackOff(ex.consistency, ex.attempts, parseActivityID(errMsg)), true
+	case DirectThrottle:
+		ce := cosmosErrorOf(err, errMsg)
+		crp.recordRetryAfter(ex, ce)
+		if backOff, ok := crp.subStatusBackOff(ex, ce.subStatus); ok {
+			return backOff, true
+		}
+		if ce.hinted {
+			crp.recordPath(ex, DecisionPathServerHinted)
+			return crp.floorBackOff(ce.retryAfter), true
+		}
+		if backOff, ok := crp.secondaryRegionBackOff(ex, errMsg); ok {
+			return backOff, true
+		}
+		crp.recordPath(ex, crp.directPath(ex.consistency))
+		return crp.directBackOff(ex.consistency, ex.attempts, parseActivityID(errMsg)), true
+	}
+
+	if crp.HonorRetryAfterOnAnyError {
+		retryAfter, ok := structuredRetryAfter(err)
+		if !ok {
+			retryAfter, ok = findRetryAfter(errMsg)
+		}
+		if ok {
+			crp.recordPath(ex, DecisionPathServerHinted)
+			return crp.floorBackOff(retryAfter), true
+		}
+	}
+
+	crp.recordPath(ex, DecisionPathNotRetryable)
+	return 0, false
+}
+
+// fallbackBackOff returns the back-off to use for the given attempt of a query with consistency c when the server does not provide one. It is also used for timeouts once ImmediateRetries are used up. activityID is the ActivityID of the error, if available
+func (crp *CosmosRetryPolicy) fallbackBackOff(c gocql.Consistency, attempts int, activityID string) time.Duration {
+	// back-off configured for the consistency level of the query takes precedence
+	if backOff, ok := crp.ConsistencyBackOff[c]; ok {
+		return backOff
+	}
+
+	// finite max retry count - use fix backoff retry time
+	if crp.MaxRetryCount > -1 {
+		return time.Duration(crp.fixedJitter(crp.FixedBackOffTimeMs, activityID)) * time.Millisecond
+	}
+
+	// in case of infinite max retry count - use growing backoff retry time, as per BackOffStrategy
+	backOff := crp.growingBackOff(attempts)
+	switch {
+	case crp.JitterMode == NoJitter:
+	case crp.JitterMode == FullJitter:
+		// the cap applies to the range the back-off is picked from
+		return time.Duration(crp.fullJitter(crp.capBackOff(backOff))) * time.Millisecond
+	case crp.JitterFraction > 0:
+		backOff = crp.jitterFraction(backOff, activityID)
+	default:
+		salt, ok := crp.activityIDJitter(activityID)
+		if !ok {
+			salt = crp.randInt(growingBackOffSaltMillis)
+		}
+		backOff += salt
+	}
+	return time.Duration(crp.capBackOff(backOff)) * time.Millisecond
+}
+
+// rateLimitedHints identify rate limited (429) errors, whatever their casing or the way gocql wraps them
+var rateLimitedHints = []string{"(429)", "Request rate is large"}
+
+// isRateLimited reports whether errMsg is a rate limited (429) error. Besides the usual TooManyRequests (429), the status code and the Request rate is large phrase are matched case-insensitively. Cosmos DB errors (carrying an ActivityID) with RetryAfterMs are considered rate limited as well, whatever their wording
+func isRateLimited(errMsg string) bool {
 	if strings.Contains(errMsg, rateLimitingErrPart) {
-		parts := strings.Split(errMsg, ",")
-		retryPart := parts[1]
-		retryAfterMs := strings.Split(retryPart, "=")
+		return true
+	}
+	for _, hint := range rateLimitedHints {
+		if containsFold(errMsg, hint) {
+			return true
+		}
+	}
+	return strings.Contains(errMsg, retryAfterKey+"=") && parseActivityID(errMsg) != ""
+}
 
-		// should be RetryAfterMs
-		if strings.TrimSpace(retryAfterMs[0]) == retryAfterKey {
-			r, _ := strconv.Atoi(retryAfterMs[1])
-			return time.Duration(r) * time.Millisecond
+// containsFold is a case-insensitive strings.Contains which does not allocate, unlike lower casing both strings. Positions whose first byte does not match that of substr (case-insensitively, for ASCII) are skipped, as it runs on every error which is not rate limited
+func containsFold(s, substr string) bool {
+	if substr == "" {
+		return true
+	}
+	first := lowerASCII(substr[0])
+	ascii := substr[0] < utf8.RuneSelf
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if ascii && lowerASCII(s[i]) != first {
+			continue
 		}
-		//if RetryAfterMs is not available
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerASCII returns the lower case of c, if it is an upper case ASCII letter
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
 
-		// finite max retry count - use fix backoff retry time
-		if crp.MaxRetryCount > -1 {
-			return time.Duration(crp.FixedBackOffTimeMs) * time.Millisecond
+// findRetryAfter extracts RetryAfterMs from anywhere in errMsg, for errors whose format is not known. ok is false if it is not available or its value is not a valid number of milliseconds
+func findRetryAfter(errMsg string) (retryAfter time.Duration, ok bool) {
+	i := strings.Index(errMsg, retryAfterKey+"=")
+	if i == -1 {
+		return 0, false
+	}
+	value := strings.TrimLeft(errMsg[i+len(retryAfterKey)+1:], " ")
+	end := 0
+	for end < len(value) && (value[end] >= '0' && value[end] <= '9' || value[end] == '.') {
+		end++
+	}
+	return parseMillis(value[:end])
+}
+
+// parseRetryAfter extracts RetryAfterMs from a rate limited error message. ok is false if it is not available or its value (which may be surrounded by spaces, or be a decimal number) is not a valid number of milliseconds, in which case the configured back-off applies. The key is looked up wherever it is in the message (rather than at a given position), since the layout of the fields varies, e.g. when ActivityID is absent or the message leads with the JSON error body, which may carry it as a JSON field ("RetryAfterMs": 120). It is on the hot path of every rate limited error, hence it scans the message in place rather than splitting it
+func parseRetryAfter(errMsg string) (retryAfter time.Duration, ok bool) {
+	for rest := errMsg; ; {
+		i := strings.Index(rest, retryAfterKey)
+		if i == -1 {
+			return 0, false
+		}
+		// the key must start a field, so that e.g. XRetryAfterMs is not mistaken for it
+		atFieldStart := i == 0 || rest[i-1] == ' ' || rest[i-1] == ',' || rest[i-1] == '{'
+		// or be a JSON key, as in error bodies
+		quoted := i > 0 && rest[i-1] == '"'
+		rest = rest[i+len(retryAfterKey):]
+		if quoted {
+			if rest == "" || rest[0] != '"' {
+				continue
+			}
+			rest = rest[1:]
+		} else if !atFieldStart {
+			continue
 		}
 
-		// in case of infinite max retry count - use exponentially growing backoff retry time
-		return time.Duration((crp.GrowingBackOffTimeMs*crp.numAttempts + rand.Intn(growingBackOffSaltMillis))) * time.Millisecond
+		value := strings.TrimLeft(rest, " ")
+		if value == "" || (value[0] != '=' && !(quoted && value[0] == ':')) {
+			continue
+		}
+		value = value[1:]
+		if end := strings.IndexAny(value, ",;}"); end != -1 {
+			value = value[:end]
+		}
+		value = strings.TrimSpace(value)
+		if quoted {
+			value = strings.Trim(value, `"`)
+		}
+		return parseMillis(value)
 	}
+}
 
-	return -1
+// maxMillis is the largest number of milliseconds a time.Duration holds
+const maxMillis = math.MaxInt64 / int64(time.Millisecond)
+
+// parseMillis parses value as a non-negative number of milliseconds. Decimal values (e.g. 42.0) are rounded to the nearest millisecond. ok is false if value is not a valid number of milliseconds
+func parseMillis(value string) (d time.Duration, ok bool) {
+	// integers are the norm, and cheaper to parse
+	if r, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if r < 0 || r > maxMillis {
+			return 0, false
+		}
+		return time.Duration(r) * time.Millisecond, true
+	}
+	if strings.IndexByte(value, '.') == -1 {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || f < 0 || f > float64(maxMillis) {
+		return 0, false
+	}
+	return time.Duration(math.Round(f)) * time.Millisecond, true
 }
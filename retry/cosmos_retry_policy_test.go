@@ -3,6 +3,7 @@ package retry
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -49,32 +50,67 @@ func TestRetryDuration(t *testing.T) {
 		policy         *CosmosRetryPolicy
 		errMsg         string
 		expectedResult time.Duration
+		expectedOK     bool
 	}
 	p := NewCosmosRetryPolicy(5)
+	p.JitterMode = NoJitter
 	testCases := []testCase{
-		{"retry duration for rate limited error", p, rateLimitedErrMsg, time.Duration(42) * time.Millisecond},
-		{"retry duration for rate limited error when RetryAfterMs is not available", p, rateLimitedErrMsgWithoutRetryAfterMs, time.Duration(p.FixedBackOffTimeMs) * time.Millisecond},
-		{"retry duration for errors other than rate limiting", p, "error: today is not your day!", time.Duration(-1)},
+		{"retry duration for rate limited error", p, rateLimitedErrMsg, time.Duration(42) * time.Millisecond, true},
+		{"retry duration for rate limited error when RetryAfterMs is zero", p, strings.Replace(rateLimitedErrMsg, "RetryAfterMs=42", "RetryAfterMs=0", 1), 0, true},
+		{"retry duration for rate limited error when RetryAfterMs is not available", p, rateLimitedErrMsgWithoutRetryAfterMs, time.Duration(p.FixedBackOffTimeMs) * time.Millisecond, true},
+		{"retry duration for errors other than rate limiting", p, "error: today is not your day!", 0, false},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(te *testing.T) {
-			expectedRetryAfterMs := time.Duration(tc.expectedResult)
-			actualRetryAfterMs := tc.policy.getRetryAfterMs(tc.errMsg)
-			assert.Equal(te, expectedRetryAfterMs, actualRetryAfterMs)
+			actualRetryAfterMs, ok := tc.policy.getRetryAfterMs(&tc.policy.exec, tc.errMsg)
+			assert.Equal(te, tc.expectedOK, ok)
+			assert.Equal(te, tc.expectedResult, actualRetryAfterMs)
 		})
 	}
 }
 
+// retryAfter returns the back-off of p for errMsg, failing the test if it is not a rate limited error
+func retryAfter(t *testing.T, p *CosmosRetryPolicy, errMsg string) time.Duration {
+	t.Helper()
+	d, ok := p.getRetryAfterMs(&p.exec, errMsg)
+	assert.True(t, ok, "expected a rate limited error")
+	return d
+}
+
 func TestRetryDurationForRateLimitedErrorInfiniteRetryWhenRetryMsUnavailable(t *testing.T) {
 	p := NewCosmosRetryPolicy(-1) // infinite retry
-	p.numAttempts = 2             // assuming the query has been retried twice already
+	p.exec.attempts = 2           // assuming the query has been retried twice already
+	p.randFunc = func(int) int { return 150 }
+
+	actualRetryAfterMs := retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs)
+	// since numAttempts is 2, the retry duration will be 2s plus the salt
+	assert.Equal(t, 2150*time.Millisecond, actualRetryAfterMs)
+}
 
-	actualRetryAfterMs := p.getRetryAfterMs(rateLimitedErrMsgWithoutRetryAfterMs)
-	// since numAttempts is 2, the retry duration will be more than 2s
-	threshold := time.Duration(2) * time.Second
-	if actualRetryAfterMs < threshold {
-		t.Errorf("expected retry duration - %v. actual - %v", threshold, actualRetryAfterMs)
+func TestRetryDurationPerConsistency(t *testing.T) {
+	type testCase struct {
+		name           string
+		consistency    gocql.Consistency
+		expectedResult time.Duration
+	}
+
+	testCases := []testCase{
+		{"retry duration for query with overridden consistency QUORUM", gocql.Quorum, 3 * time.Second},
+		{"retry duration for query with overridden consistency ALL", gocql.All, 4 * time.Second},
+		{"retry duration for query with consistency that is not overridden", gocql.One, time.Duration(defaultFixedBackOffTimeMs) * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			p.JitterMode = NoJitter
+			p.ConsistencyBackOff = map[gocql.Consistency]time.Duration{gocql.Quorum: 3 * time.Second, gocql.All: 4 * time.Second}
+			p.Attempt(MockRetryableQuery{consistency: tc.consistency})
+			assert.Equal(te, tc.expectedResult, retryAfter(te, p, rateLimitedErrMsgWithoutRetryAfterMs))
+			// server provided RetryAfterMs is always honored
+			assert.Equal(te, time.Duration(42)*time.Millisecond, retryAfter(te, p, rateLimitedErrMsg))
+		})
 	}
 }
 
@@ -98,6 +134,9 @@ func TestGetRetryType(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(te *testing.T) {
 			p := NewCosmosRetryPolicy(2)
+			// no need to actually sleep through the fixed back-off
+			p.sleepFunc = func(time.Duration) {}
+			p.Attempt(attemptsQuery{attempts: 1})
 			actualRetryType := p.GetRetryType(tc.errorType)
 			expectedRetryType := tc.expectedRetryType
 			assert.Equal(te, expectedRetryType, actualRetryType)
@@ -105,7 +144,82 @@ func TestGetRetryType(t *testing.T) {
 	}
 }
 
+func TestImmediateRetries(t *testing.T) {
+	errs := map[string]error{
+		"RequestErrReadTimeout":  &gocql.RequestErrReadTimeout{},
+		"RequestErrUnavailable":  &gocql.RequestErrUnavailable{},
+		"RequestErrWriteTimeout": &gocql.RequestErrWriteTimeout{},
+	}
+
+	for name, err := range errs {
+		t.Run(name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			p.JitterMode = NoJitter
+			p.FixedBackOffTimeMs = 10
+			p.ImmediateRetries = 2
+
+			for attempt := 1; attempt <= 4; attempt++ {
+				before := p.BlockedTime()
+				p.Attempt(attemptsQuery{attempts: attempt})
+				assert.Equal(te, gocql.Retry, p.GetRetryType(err))
+
+				slept := p.BlockedTime() - before
+				if attempt <= p.ImmediateRetries {
+					assert.Equal(te, time.Duration(0), slept, "retry %d should be immediate", attempt)
+				} else {
+					assert.Equal(te, 10*time.Millisecond, slept, "retry %d should back off", attempt)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeoutsRetriedImmediatelyByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	for attempt := 1; attempt <= 5; attempt++ {
+		p.Attempt(attemptsQuery{attempts: attempt})
+		assert.Equal(t, gocql.Retry, p.GetRetryType(&gocql.RequestErrReadTimeout{}))
+	}
+	assert.Equal(t, time.Duration(0), p.BlockedTime())
+}
+
+func TestNoBackOffWhenAttemptsExhausted(t *testing.T) {
+	type testCase struct {
+		name         string
+		attempts     int
+		expectedType gocql.RetryType
+		expectedWait time.Duration
+	}
+
+	testCases := []testCase{
+		{"last allowed attempt backs off", 2, gocql.Retry, 42 * time.Millisecond},
+		{"attempt past the limit is rethrown without backing off", 3, gocql.Rethrow, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(2)
+			p.Attempt(attemptsQuery{attempts: tc.attempts})
+
+			assert.Equal(te, tc.expectedType, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+			assert.Equal(te, tc.expectedWait, p.BlockedTime())
+		})
+	}
+}
+
+func TestNoBackOffOutsideRetryWindow(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.Attempt(MockRetryableQuery{})
+	p.RetryWindow = func(time.Time) bool { return false }
+
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	assert.Equal(t, DecisionPathAttemptsExhausted, p.LastDecisionPath())
+	assert.Equal(t, time.Duration(0), p.BlockedTime())
+}
+
 type MockRetryableQuery struct {
+	consistency gocql.Consistency
+	ctx         context.Context
 }
 
 func (mrq MockRetryableQuery) Attempts() int {
@@ -114,8 +228,41 @@ func (mrq MockRetryableQuery) Attempts() int {
 func (mrq MockRetryableQuery) SetConsistency(c gocql.Consistency) {
 }
 func (mrq MockRetryableQuery) GetConsistency() gocql.Consistency {
-	return gocql.Any
+	return mrq.consistency
 }
 func (mrq MockRetryableQuery) Context() context.Context {
+	if mrq.ctx != nil {
+		return mrq.ctx
+	}
 	return context.Background()
 }
+
+func TestRetryAfterOnAnyError(t *testing.T) {
+	const errMsg = "Service is busy: RetryAfterMs=25; please retry"
+
+	type testCase struct {
+		name         string
+		errMsg       string
+		honor        bool
+		expectedType gocql.RetryType
+		expectedWait time.Duration
+	}
+
+	testCases := []testCase{
+		{"RetryAfterMs of non rate limited error honored when enabled", errMsg, true, gocql.Retry, 25 * time.Millisecond},
+		{"RetryAfterMs of non rate limited error ignored by default", errMsg, false, gocql.Rethrow, 0},
+		{"spaced RetryAfterMs", "Service is busy: RetryAfterMs= 25; please retry", true, gocql.Retry, 25 * time.Millisecond},
+		{"decimal RetryAfterMs", "Service is busy: RetryAfterMs=24.5; please retry", true, gocql.Retry, 25 * time.Millisecond},
+		{"out of range RetryAfterMs", "Service is busy: RetryAfterMs=99999999999999999999; please retry", true, gocql.Rethrow, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.HonorRetryAfterOnAnyError = tc.honor
+
+			assert.Equal(te, tc.expectedType, retryTypeOf(p, errors.New(tc.errMsg)))
+			assert.Equal(te, tc.expectedWait, p.BlockedTime())
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Decide returns the decision GetRetryType would make for a query which failed with err on its given attempt, idempotent or not (see gocql.Query.Idempotent), along with the back-off it would sleep for before retrying, without sleeping. It is meant for simulations, tests and custom retry loops, which are left to wait for the back-off themselves. Decide is not bound to a query: the back-off does not progress with, nor is it accounted for in, the state of the queries retried by the policy (e.g. MaxElapsedTimeMs applies to the back-off of this attempt alone, and MaxBlockedTimeMs, which bounds the back-offs being slept, does not apply), and the consistency level of the query is taken to be gocql.Any. LastDecisionPath reports how the decision was made
+func (crp *CosmosRetryPolicy) Decide(err error, attempts int, idempotent bool) (gocql.RetryType, time.Duration) {
+	ex := &execution{attempts: attempts, idempotent: idempotent, dryRun: true}
+	retryType := crp.decision(ex, err)
+	crp.publishPath(ex)
+	return retryType, ex.backOff
+}
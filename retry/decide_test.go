@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecide(t *testing.T) {
+	type testCase struct {
+		name              string
+		err               error
+		attempts          int
+		idempotent        bool
+		immediateRetries  int
+		expectedRetryType gocql.RetryType
+		expectedBackOff   time.Duration
+		expectedPath      string
+	}
+
+	testCases := []testCase{
+		{"rate limited with RetryAfterMs", errors.New(rateLimitedErrMsg), 1, true, 0, gocql.Retry, 42 * time.Millisecond, DecisionPathServerHinted},
+		{"rate limited without RetryAfterMs", errors.New(rateLimitedErrMsgWithoutRetryAfterMs), 2, true, 0, gocql.Retry, 5 * time.Second, DecisionPathFixedFallback},
+		{"rate limited query which is not idempotent", errors.New(rateLimitedErrMsg), 1, false, 0, gocql.Retry, 42 * time.Millisecond, DecisionPathServerHinted},
+		{"read timeout", &gocql.RequestErrReadTimeout{}, 1, true, 0, gocql.Retry, 0, DecisionPathTimeoutImmediate},
+		{"read timeout of a query which is not idempotent", &gocql.RequestErrReadTimeout{}, 1, false, 0, gocql.Rethrow, 0, DecisionPathNotIdempotent},
+		{"write timeout after immediate retries", &gocql.RequestErrWriteTimeout{}, 2, true, 1, gocql.Retry, 5 * time.Second, DecisionPathTimeoutBackOff},
+		{"fatal error", errors.New("error: today is not your day!"), 1, true, 0, gocql.Rethrow, 0, DecisionPathNotRetryable},
+		{"attempts exhausted", errors.New(rateLimitedErrMsg), 4, true, 0, gocql.Rethrow, 0, DecisionPathAttemptsExhausted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.JitterMode = NoJitter
+			p.ImmediateRetries = tc.immediateRetries
+			p.sleepFunc = func(time.Duration) {
+				te.Fatal("Decide must not sleep")
+			}
+
+			retryType, backOff := p.Decide(tc.err, tc.attempts, tc.idempotent)
+			assert.Equal(te, tc.expectedRetryType, retryType)
+			assert.Equal(te, tc.expectedBackOff, backOff)
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+		})
+	}
+}
+
+func TestDecideMatchesGetRetryType(t *testing.T) {
+	type testCase struct {
+		name       string
+		err        error
+		idempotent bool
+	}
+
+	testCases := []testCase{
+		{"rate limited", errors.New(rateLimitedErrMsg), true},
+		{"write timeout of an idempotent query", &gocql.RequestErrWriteTimeout{}, true},
+		{"write timeout of a query which is not idempotent", &gocql.RequestErrWriteTimeout{}, false},
+		{"read timeout of a query which is not idempotent", &gocql.RequestErrReadTimeout{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.JitterMode = NoJitter
+			var slept time.Duration
+			p.sleepFunc = func(d time.Duration) {
+				slept += d
+			}
+
+			decidedType, decidedBackOff := p.Decide(tc.err, 1, tc.idempotent)
+			decidedPath := p.LastDecisionPath()
+			assert.Equal(te, time.Duration(0), slept)
+
+			p.Attempt(idempotentQuery{MockRetryableQuery{}, tc.idempotent})
+			assert.Equal(te, decidedType, p.GetRetryType(tc.err))
+			assert.Equal(te, decidedBackOff, slept)
+			assert.Equal(te, decidedPath, p.LastDecisionPath())
+		})
+	}
+}
+
+func TestDecideIgnoresBlockedTimeLimit(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.MaxBlockedTimeMs = 1
+
+	retryType, backOff := p.Decide(errors.New(rateLimitedErrMsg), 1, true)
+	assert.Equal(t, gocql.Retry, retryType)
+	assert.Equal(t, 42*time.Millisecond, backOff)
+	assert.Equal(t, DecisionPathServerHinted, p.LastDecisionPath())
+}
+
+func TestDecideDoesNotMutateState(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.MaxRateLimitedRetries = 1
+	p.Attempt(attemptsQuery{attempts: 2})
+
+	for i := 0; i < 3; i++ {
+		retryType, _ := p.Decide(errors.New(rateLimitedErrMsg), 1, true)
+		assert.Equal(t, gocql.Retry, retryType)
+	}
+	assert.Equal(t, 2, p.exec.attempts)
+	assert.Equal(t, 0, p.exec.rateLimitedRetries)
+	assert.Equal(t, time.Duration(0), p.BlockedTime())
+	assert.Equal(t, int64(0), p.blockedNanos)
+	assert.Equal(t, int64(0), p.consecutiveThrottles)
+}
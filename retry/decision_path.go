@@ -0,0 +1,94 @@
+package retry
+
+import "github.com/gocql/gocql"
+
+// Decision paths reported by LastDecisionPath
+const (
+	// DecisionPathServerHinted - rate limited error backed off as per the RetryAfterMs returned by the server
+	DecisionPathServerHinted = "server-hinted"
+	// DecisionPathSubStatusOverride - rate limited error backed off as per SubStatusBackOff
+	DecisionPathSubStatusOverride = "substatus-override"
+	// DecisionPathConsistencyOverride - back-off as per ConsistencyBackOff
+	DecisionPathConsistencyOverride = "consistency-override"
+	// DecisionPathFixedFallback - back-off as per FixedBackOffTimeMs
+	DecisionPathFixedFallback = "fixed-fallback"
+	// DecisionPathGrowingFallback - back-off as per GrowingBackOffTimeMs
+	DecisionPathGrowingFallback = "growing-fallback"
+	// DecisionPathDirectFallback - direct mode rate limited error backed off as per DirectBackOffTimeMs
+	DecisionPathDirectFallback = "direct-fallback"
+	// DecisionPathSecondaryRegion - rate limited error served by a region other than PrimaryRegion backed off as per SecondaryRegionBackOffMs
+	DecisionPathSecondaryRegion = "secondary-region"
+	// DecisionPathTimeoutImmediate - timeout or unavailable error retried immediately
+	DecisionPathTimeoutImmediate = "timeout-immediate"
+	// DecisionPathTimeoutBackOff - timeout or unavailable error retried after a back-off since ImmediateRetries are used up
+	DecisionPathTimeoutBackOff = "timeout-backoff"
+	// DecisionPathParseFailureOpen - unclassifiable error retried as per ParseFailureAction
+	DecisionPathParseFailureOpen = "parse-failure-open"
+	// DecisionPathNextHost - error retried on the next host
+	DecisionPathNextHost = "next-host"
+	// DecisionPathHostHopsExhausted - error rethrown since the query has already been retried on MaxHostHops hosts
+	DecisionPathHostHopsExhausted = "host-hops-exhausted"
+	// DecisionPathWriteForbidden - write forbidden (403) by the region of the coordinator, retried on the next host
+	DecisionPathWriteForbidden = "write-forbidden"
+	// DecisionPathNotReady - error retried after NotReadyBackOffTimeMs since the keyspace or table is being created
+	DecisionPathNotReady = "not-ready"
+	// DecisionPathRule - error handled as per a ClassificationRule
+	DecisionPathRule = "rule"
+	// DecisionPathClassifier - error handled as per the decision of the Classifier
+	DecisionPathClassifier = "classifier"
+	// DecisionPathChaosThrottle - throttling simulated as per EnableChaos
+	DecisionPathChaosThrottle = "chaos-throttle"
+	// DecisionPathUnsafeWrite - write timeout rethrown since retrying it could apply the write twice, e.g. a COUNTER write of a query which is not idempotent
+	DecisionPathUnsafeWrite = "unsafe-write"
+	// DecisionPathNotIdempotent - read timeout (or coordinator connection error) rethrown since the query is not idempotent
+	DecisionPathNotIdempotent = "not-idempotent"
+	// DecisionPathIgnored - read error ignored as per IgnoreErrors
+	DecisionPathIgnored = "ignored"
+	// DecisionPathUnknownError - unclassified error retried as per UnknownErrorPolicy
+	DecisionPathUnknownError = "unknown-error"
+	// DecisionPathNotRetryable - error rethrown since it is not retryable
+	DecisionPathNotRetryable = "not-retryable"
+	// DecisionPathAttemptsExhausted - error rethrown since Attempt would not allow another attempt
+	DecisionPathAttemptsExhausted = "attempts-exhausted"
+	// DecisionPathRateLimitedRetriesExhausted - rate limited error rethrown since the query has already been retried MaxRateLimitedRetries times because of throttling
+	DecisionPathRateLimitedRetriesExhausted = "rate-limited-retries-exhausted"
+	// DecisionPathLimiterSaturated - retry denied since no Limiter slot freed up
+	DecisionPathLimiterSaturated = "limiter-saturated"
+	// DecisionPathCircuitOpen - error rethrown since the CircuitBreaker is open
+	DecisionPathCircuitOpen = "circuit-open"
+	// DecisionPathElapsedBudget - error rethrown since backing off would exceed MaxElapsedTimeMs for the query
+	DecisionPathElapsedBudget = "elapsed-budget"
+	// DecisionPathBlockedLimit - error rethrown since MaxBlockedTimeMs has been reached
+	DecisionPathBlockedLimit = "blocked-limit"
+	// DecisionPathDeadline - error rethrown since the deadline of the query context is about to be reached
+	DecisionPathDeadline = "deadline"
+	// DecisionPathContextDone - error rethrown since the context of the query was done during the back-off
+	DecisionPathContextDone = "context-done"
+)
+
+// LastDecisionPath returns the code path which handled the most recent GetRetryType decision (one of the DecisionPath constants), whichever query it was made for. It is a convenience for tests: concurrent decisions overwrite one another, use the DecisionPath of RetryEvent (or of the trace) to tell them apart
+func (crp *CosmosRetryPolicy) LastDecisionPath() string {
+	path, _ := crp.decisionPath.Load().(string)
+	return path
+}
+
+// recordPath records path as the code path handling the decision being made for ex
+func (crp *CosmosRetryPolicy) recordPath(ex *execution, path string) {
+	ex.path = path
+}
+
+// publishPath makes the decision path of ex the one returned by LastDecisionPath
+func (crp *CosmosRetryPolicy) publishPath(ex *execution) {
+	crp.decisionPath.Store(ex.path)
+}
+
+// fallbackPath returns the decision path matching the back-off chosen by fallbackBackOff
+func (crp *CosmosRetryPolicy) fallbackPath(c gocql.Consistency) string {
+	if _, ok := crp.ConsistencyBackOff[c]; ok {
+		return DecisionPathConsistencyOverride
+	}
+	if crp.MaxRetryCount > -1 {
+		return DecisionPathFixedFallback
+	}
+	return DecisionPathGrowingFallback
+}
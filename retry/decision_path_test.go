@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastDecisionPath(t *testing.T) {
+	type testCase struct {
+		name         string
+		configure    func(p *CosmosRetryPolicy)
+		attempts     int
+		err          error
+		expectedPath string
+	}
+
+	testCases := []testCase{
+		{"rate limited error with RetryAfterMs", nil, 1, errors.New(rateLimitedErrMsg), DecisionPathServerHinted},
+		{"rate limited error without RetryAfterMs and finite retries", nil, 1, errors.New(rateLimitedErrMsgWithoutRetryAfterMs), DecisionPathFixedFallback},
+		{"rate limited error without RetryAfterMs and infinite retries", func(p *CosmosRetryPolicy) { p.MaxRetryCount = -1; p.GrowingBackOffTimeMs = 0 }, 1, errors.New(rateLimitedErrMsgWithoutRetryAfterMs), DecisionPathGrowingFallback},
+		{"rate limited error with consistency override", func(p *CosmosRetryPolicy) {
+			p.ConsistencyBackOff = map[gocql.Consistency]time.Duration{gocql.Any: time.Millisecond}
+		}, 1, errors.New(rateLimitedErrMsgWithoutRetryAfterMs), DecisionPathConsistencyOverride},
+		{"rate limited error with substatus override", func(p *CosmosRetryPolicy) {
+			p.SubStatusBackOff = map[int]time.Duration{3200: time.Millisecond}
+		}, 1, errors.New(rateLimitedErrMsg), DecisionPathSubStatusOverride},
+		{"direct mode rate limited error", func(p *CosmosRetryPolicy) { p.DirectBackOffTimeMs = 1 }, 1, errors.New(directRateLimitedErrMsg), DecisionPathDirectFallback},
+		{"direct mode rate limited error with RetryAfterMs", nil, 1, errors.New(directRateLimitedErrMsgWithRetryAfterMs), DecisionPathServerHinted},
+		{"timeout retried immediately", nil, 1, &gocql.RequestErrReadTimeout{}, DecisionPathTimeoutImmediate},
+		{"timeout retried after back-off", func(p *CosmosRetryPolicy) { p.ImmediateRetries = 1 }, 2, &gocql.RequestErrWriteTimeout{}, DecisionPathTimeoutBackOff},
+		{"ambiguous error with fail open", func(p *CosmosRetryPolicy) { p.ParseFailureAction = FailOpen }, 1, errors.New(ambiguousErrMsg), DecisionPathParseFailureOpen},
+		{"error other than rate limiting", nil, 1, errors.New("error: today is not your day!"), DecisionPathNotRetryable},
+		{"blocked time limit reached", func(p *CosmosRetryPolicy) { p.MaxBlockedTimeMs = 1 }, 1, errors.New(rateLimitedErrMsg), DecisionPathBlockedLimit},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			p.FixedBackOffTimeMs = 1
+			if tc.configure != nil {
+				tc.configure(p)
+			}
+			p.Attempt(attemptsQuery{attempts: tc.attempts})
+			p.GetRetryType(tc.err)
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+		})
+	}
+}
+
+func TestLastDecisionPathBeforeAnyDecision(t *testing.T) {
+	assert.Equal(t, "", NewCosmosRetryPolicy(5).LastDecisionPath())
+}
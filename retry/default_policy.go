@@ -0,0 +1,39 @@
+package retry
+
+import (
+	"sync"
+
+	"github.com/gocql/gocql"
+)
+
+var defaultPolicy struct {
+	sync.RWMutex
+	policy *CosmosRetryPolicy
+}
+
+// SetDefaultPolicy sets the policy used by NewCosmosCluster when none is passed explicitly. Passing nil clears it. It is safe for concurrent use
+func SetDefaultPolicy(crp *CosmosRetryPolicy) {
+	defaultPolicy.Lock()
+	defer defaultPolicy.Unlock()
+	defaultPolicy.policy = crp
+}
+
+// DefaultPolicy returns the policy set using SetDefaultPolicy, or nil if there is none
+func DefaultPolicy() *CosmosRetryPolicy {
+	defaultPolicy.RLock()
+	defer defaultPolicy.RUnlock()
+	return defaultPolicy.policy
+}
+
+// NewCosmosCluster returns a gocql.ClusterConfig for the given hosts which uses crp as the retry policy. If crp is nil, DefaultPolicy is used (if set)
+func NewCosmosCluster(crp *CosmosRetryPolicy, hosts ...string) *gocql.ClusterConfig {
+	cluster := gocql.NewCluster(hosts...)
+	if crp == nil {
+		crp = DefaultPolicy()
+	}
+	// avoid setting a typed nil RetryPolicy, which gocql would not recognize as unset
+	if crp != nil {
+		cluster.RetryPolicy = crp
+	}
+	return cluster
+}
@@ -0,0 +1,30 @@
+package retry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCosmosClusterUsesDefaultPolicy(t *testing.T) {
+	defer SetDefaultPolicy(nil)
+
+	assert.Nil(t, DefaultPolicy())
+	assert.Nil(t, NewCosmosCluster(nil, "localhost").RetryPolicy, "no policy should be set without a default")
+
+	def := NewCosmosRetryPolicy(3)
+	SetDefaultPolicy(def)
+	assert.True(t, def == DefaultPolicy())
+
+	cluster := NewCosmosCluster(nil, "localhost")
+	assert.Equal(t, []string{"localhost"}, cluster.Hosts)
+	assert.True(t, cluster.RetryPolicy == def)
+}
+
+func TestNewCosmosClusterExplicitPolicyOverridesDefault(t *testing.T) {
+	defer SetDefaultPolicy(nil)
+	SetDefaultPolicy(NewCosmosRetryPolicy(3))
+
+	explicit := NewCosmosRetryPolicy(-1)
+	assert.True(t, NewCosmosCluster(explicit, "localhost").RetryPolicy == explicit)
+}
@@ -0,0 +1,15 @@
+package retry
+
+import "time"
+
+// withinElapsedBudget reports whether ex may back off for another d without exceeding MaxElapsedTimeMs, counted from the cumulative back-off of ex or, if greater, the time elapsed since its first failed attempt as per the policy clock (which includes the time spent executing the query)
+func (crp *CosmosRetryPolicy) withinElapsedBudget(ex *execution, d time.Duration) bool {
+	if crp.MaxElapsedTimeMs <= 0 {
+		return true
+	}
+	elapsed := ex.totalWait
+	if sinceStart := crp.currentTime().Sub(ex.start); !ex.start.IsZero() && sinceStart > elapsed {
+		elapsed = sinceStart
+	}
+	return elapsed+d <= time.Duration(crp.MaxElapsedTimeMs)*time.Millisecond
+}
@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxElapsedTimeGivesUp(t *testing.T) {
+	type testCase struct {
+		name          string
+		maxElapsedMs  int
+		expectedTypes []gocql.RetryType
+		expectedSlept []time.Duration
+	}
+
+	// each rate limited error backs off for 42ms
+	testCases := []testCase{
+		{"budget crossed by the third back-off", 100, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Rethrow}, []time.Duration{42 * time.Millisecond, 42 * time.Millisecond}},
+		{"budget exactly used up", 84, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Rethrow}, []time.Duration{42 * time.Millisecond, 42 * time.Millisecond}},
+		{"budget too small for any back-off", 10, []gocql.RetryType{gocql.Rethrow}, nil},
+		{"no budget by default", 0, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Retry, gocql.Retry, gocql.Retry}, []time.Duration{42 * time.Millisecond, 42 * time.Millisecond, 42 * time.Millisecond, 42 * time.Millisecond, 42 * time.Millisecond}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(-1)
+			p.MaxElapsedTimeMs = tc.maxElapsedMs
+			var slept []time.Duration
+			p.sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+
+			var decisions []gocql.RetryType
+			for attempt := 1; attempt <= 5; attempt++ {
+				assert.True(te, p.Attempt(attemptsQuery{attempts: attempt}))
+				decision := p.GetRetryType(errors.New(rateLimitedErrMsg))
+				decisions = append(decisions, decision)
+				if decision == gocql.Rethrow {
+					assert.Equal(te, DecisionPathElapsedBudget, p.LastDecisionPath())
+					break
+				}
+			}
+			assert.Equal(te, tc.expectedTypes, decisions)
+			assert.Equal(te, tc.expectedSlept, slept)
+		})
+	}
+}
+
+func TestMaxElapsedTimeIsPerQuery(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1, WithMaxElapsedTime(100*time.Millisecond))
+	p.sleepFunc = func(time.Duration) {}
+
+	first, second := WithAttemptScope(context.Background()), WithAttemptScope(context.Background())
+	for i := 0; i < 2; i++ {
+		for _, ctx := range []context.Context{first, second} {
+			assert.True(t, p.Attempt(MockRetryableQuery{ctx: ctx}))
+			assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+		}
+	}
+	// each query has backed off for 84ms, hence another back-off would exceed its budget. Once given up on, the next execution starts afresh
+	assert.True(t, p.Attempt(MockRetryableQuery{ctx: first}))
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	assert.True(t, p.Attempt(MockRetryableQuery{ctx: first}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+
+	// unscoped queries start afresh with their first attempt
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+}
+
+func TestMaxElapsedTimeFollowsThePolicyClock(t *testing.T) {
+	type testCase struct {
+		name          string
+		queryTime     time.Duration
+		expectedTypes []gocql.RetryType
+	}
+
+	// each rate limited error backs off for 42ms, slept by advancing the clock
+	testCases := []testCase{
+		{"queries executing in no time", 0, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Retry, gocql.Retry, gocql.Retry}},
+		{"budget crossed while executing the query", 500 * time.Millisecond, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Rethrow}},
+		{"budget used up by a single execution", time.Second, []gocql.RetryType{gocql.Retry, gocql.Rethrow}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+			p := NewCosmosRetryPolicy(-1, WithMaxElapsedTime(time.Second))
+			p.now = func() time.Time { return now }
+			p.sleepFunc = func(d time.Duration) { now = now.Add(d) }
+
+			var decisions []gocql.RetryType
+			for attempt := 1; attempt <= 5; attempt++ {
+				assert.True(te, p.Attempt(attemptsQuery{attempts: attempt}))
+				decision := p.GetRetryType(errors.New(rateLimitedErrMsg))
+				decisions = append(decisions, decision)
+				if decision == gocql.Rethrow {
+					assert.Equal(te, DecisionPathElapsedBudget, p.LastDecisionPath())
+					break
+				}
+				// the retry fails after executing for queryTime
+				now = now.Add(tc.queryTime)
+			}
+			assert.Equal(te, tc.expectedTypes, decisions)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package retry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ThrottleExhaustedError is returned by WrapThrottleExhausted when a query failed with a rate limited (429) error after the policy gave up retrying it. It carries enough context for the caller to implement its own higher-level back-off
+type ThrottleExhaustedError struct {
+	// Attempts is the number of times the query was attempted
+	Attempts int
+	// TotalWait is the total time spent backing off between attempts. It is 0 if not known, i.e. for queries which are not scoped (see WithAttemptScope) and cannot be told apart by their identity (unlike a *gocql.Query), or which have been evicted since (see TrackedQueries)
+	TotalWait time.Duration
+	// LastRetryAfter is the RetryAfterMs returned with the final error (0 if not available)
+	LastRetryAfter time.Duration
+	// Err is the final error returned by gocql
+	Err error
+}
+
+func (e *ThrottleExhaustedError) Error() string {
+	return fmt.Sprintf("rate limited after %d attempts (waited %v, last RetryAfterMs %v): %v", e.Attempts, e.TotalWait, e.LastRetryAfter, e.Err)
+}
+
+// Unwrap returns the underlying gocql error
+func (e *ThrottleExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// WrapThrottleExhausted converts err, the final error returned for query q, into a *ThrottleExhaustedError if it is a rate limited (429) error. Other errors (including nil) are returned as is
+//
+//	err := query.Exec()
+//	err = policy.WrapThrottleExhausted(query, err)
+func (crp *CosmosRetryPolicy) WrapThrottleExhausted(q gocql.RetryableQuery, err error) error {
+	if crp.ClassifyThrottle(err) == NotThrottled {
+		return err
+	}
+	attempts, totalWait := q.Attempts(), crp.totalWaitOf(q)
+	if scoped, ok := scopedExecution(queryContext(q)); ok {
+		attempts, totalWait = scoped.attempts, scoped.totalWait
+	}
+	lastRetryAfter, _ := retryAfterOf(err, err.Error())
+	return &ThrottleExhaustedError{
+		Attempts:       attempts,
+		TotalWait:      totalWait,
+		LastRetryAfter: lastRetryAfter,
+		Err:            err,
+	}
+}
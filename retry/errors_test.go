@@ -0,0 +1,116 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+type attemptsQuery struct {
+	MockRetryableQuery
+	attempts int
+}
+
+func (aq attemptsQuery) Attempts() int {
+	return aq.attempts
+}
+
+// IsIdempotent allows timeouts to be retried
+func (aq attemptsQuery) IsIdempotent() bool {
+	return true
+}
+
+// retryTypeOf makes the calls gocql makes for a failed first attempt of an idempotent query: Attempt, then GetRetryType
+func retryTypeOf(p *CosmosRetryPolicy, err error) gocql.RetryType {
+	p.Attempt(attemptsQuery{attempts: 1})
+	return p.GetRetryType(err)
+}
+
+func TestWrapThrottleExhausted(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	q := &pointerQuery{}
+	assert.Equal(t, 3, failUntilGivenUp(p, q, errors.New(rateLimitedErrMsg)))
+
+	finalErr := errors.New(rateLimitedErrMsg)
+	err := p.WrapThrottleExhausted(q, finalErr)
+
+	var exhausted *ThrottleExhaustedError
+	assert.True(t, errors.As(fmt.Errorf("insert failed: %w", err), &exhausted))
+	assert.Equal(t, 3, exhausted.Attempts)
+	assert.Equal(t, 2*42*time.Millisecond, exhausted.TotalWait)
+	assert.Equal(t, 42*time.Millisecond, exhausted.LastRetryAfter)
+	assert.True(t, errors.Is(err, finalErr))
+}
+
+func TestWrapThrottleExhaustedReportsTheWaitOfItsQuery(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	p.SleepDisabled = true
+	first, second := &pointerQuery{}, &pointerQuery{}
+	fail := func(q *pointerQuery, err error) {
+		q.attempts++
+		if p.Attempt(q) {
+			p.GetRetryType(err)
+		}
+	}
+
+	// both are given up on, first after 2 back-offs and second right away, the latter being the last one to fail
+	fail(first, errors.New(rateLimitedErrMsg))
+	fail(second, errors.New(rateLimitedErrMsg))
+	fail(first, errors.New(rateLimitedErrMsg))
+	fail(first, errors.New(rateLimitedErrMsg))
+	second.attempts = 3
+	fail(second, errors.New(rateLimitedErrMsg))
+	assert.Equal(t, 0, p.TrackedQueries())
+
+	var exhausted *ThrottleExhaustedError
+	assert.True(t, errors.As(p.WrapThrottleExhausted(first, errors.New(rateLimitedErrMsg)), &exhausted))
+	assert.Equal(t, 2*42*time.Millisecond, exhausted.TotalWait)
+	assert.True(t, errors.As(p.WrapThrottleExhausted(second, errors.New(rateLimitedErrMsg)), &exhausted))
+	assert.Equal(t, 42*time.Millisecond, exhausted.TotalWait)
+}
+
+func TestWrapThrottleExhaustedWithUnknownWait(t *testing.T) {
+	type testCase struct {
+		name  string
+		query gocql.RetryableQuery
+	}
+	for _, tc := range []testCase{
+		{"query which cannot be told apart", attemptsQuery{attempts: 3}},
+		{"query which was never retried", &pointerQuery{attempts: 1}},
+	} {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(2)
+			p.SleepDisabled = true
+			// another query backs off in the meantime
+			assert.Equal(te, 3, failUntilGivenUp(p, &pointerQuery{}, errors.New(rateLimitedErrMsg)))
+
+			var exhausted *ThrottleExhaustedError
+			assert.True(te, errors.As(p.WrapThrottleExhausted(tc.query, errors.New(rateLimitedErrMsg)), &exhausted))
+			assert.Equal(te, time.Duration(0), exhausted.TotalWait)
+		})
+	}
+}
+
+func TestWrapThrottleExhaustedWithoutRetryAfterMs(t *testing.T) {
+	p := NewCosmosRetryPolicy(0)
+	err := p.WrapThrottleExhausted(attemptsQuery{attempts: 1}, errors.New(rateLimitedErrMsgWithoutRetryAfterMs))
+
+	var exhausted *ThrottleExhaustedError
+	assert.True(t, errors.As(err, &exhausted))
+	assert.Equal(t, time.Duration(0), exhausted.LastRetryAfter)
+}
+
+func TestWrapThrottleExhaustedIgnoresOtherErrors(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	other := errors.New("error: today is not your day!")
+
+	assert.Equal(t, other, p.WrapThrottleExhausted(attemptsQuery{attempts: 1}, other))
+	assert.Nil(t, p.WrapThrottleExhausted(attemptsQuery{attempts: 1}, nil))
+
+	var exhausted *ThrottleExhaustedError
+	assert.False(t, errors.As(p.WrapThrottleExhausted(attemptsQuery{attempts: 1}, other), &exhausted))
+}
@@ -0,0 +1,42 @@
+package retry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// RetryEvent describes a retry decision made by GetRetryType. See OnRetry
+type RetryEvent struct {
+	// Attempt is the number of the failed attempt of the query
+	Attempt int
+	// RetryType is the decision
+	RetryType gocql.RetryType
+	// BackOff is the time slept before returning the decision or, with SleepDisabled, the time the caller is expected to wait before retrying
+	BackOff time.Duration
+	// RateLimited is set if the error was a rate limited (429) error
+	RateLimited bool
+	// DecisionPath is the code path which made the decision (one of the DecisionPath constants), e.g. why the error was rethrown
+	DecisionPath string
+	// Elapsed is the time elapsed since the first failed attempt of the query (as seen by Attempt), back-offs included, e.g. to alert on queries whose retries exceed a latency budget
+	Elapsed time.Duration
+	// ActivityID is the ActivityID of the error, if it is a Cosmos DB error, for Azure support to trace the request server-side
+	ActivityID string
+}
+
+// String renders the event for logs, e.g. attempt=1 decision=retry backoff=42ms rate_limited=true path=server-hinted elapsed=50ms activity_id=c268afb6-7367-4ff8-b06b-b7e2d1269f55
+func (e RetryEvent) String() string {
+	return fmt.Sprintf("attempt=%d decision=%s backoff=%v rate_limited=%t path=%s elapsed=%v activity_id=%s", e.Attempt, RetryTypeString(e.RetryType), e.BackOff, e.RateLimited, e.DecisionPath, e.Elapsed, e.ActivityID)
+}
+
+// emit passes event to OnRetry, if set. A panic in OnRetry is recovered from (and ignored), so that it cannot disrupt the query
+func (crp *CosmosRetryPolicy) emit(event RetryEvent) {
+	if crp.OnRetry == nil {
+		return
+	}
+	defer func() {
+		recover()
+	}()
+	crp.OnRetry(event)
+}
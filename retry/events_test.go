@@ -0,0 +1,134 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnRetryReceivesEvents(t *testing.T) {
+	type testCase struct {
+		name     string
+		err      error
+		expected RetryEvent
+	}
+
+	testCases := []testCase{
+		{"rate limited error", errors.New(rateLimitedErrMsg), RetryEvent{Attempt: 1, RetryType: gocql.Retry, BackOff: 42 * time.Millisecond, RateLimited: true, DecisionPath: DecisionPathServerHinted, ActivityID: "c268afb6-7367-4ff8-b06b-b7e2d1269f55"}},
+		{"read timeout", &gocql.RequestErrReadTimeout{}, RetryEvent{Attempt: 1, RetryType: gocql.Retry, DecisionPath: DecisionPathTimeoutImmediate}},
+		{"non retryable error", errors.New("syntax error"), RetryEvent{Attempt: 1, RetryType: gocql.Rethrow, DecisionPath: DecisionPathNotRetryable}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			now := time.Now()
+			p.now = func() time.Time { return now }
+			var events []RetryEvent
+			p.OnRetry = func(e RetryEvent) { events = append(events, e) }
+
+			assert.True(te, p.Attempt(attemptsQuery{attempts: 1}))
+			p.GetRetryType(tc.err)
+			assert.Equal(te, []RetryEvent{tc.expected}, events)
+		})
+	}
+}
+
+func TestRetryEventPathOfOverlappingDecisions(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	var (
+		mu     sync.Mutex
+		events []RetryEvent
+	)
+	p.OnRetry = func(e RetryEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	sleeping, resume := make(chan struct{}), make(chan struct{})
+	p.sleepFunc = func(time.Duration) {
+		sleeping <- struct{}{}
+		<-resume
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Attempt(attemptsQuery{attempts: 1})
+		p.GetRetryType(errors.New(rateLimitedErrMsg))
+	}()
+	// the read timeout is decided while the rate limited error is backing off
+	<-sleeping
+	p.Attempt(attemptsQuery{attempts: 1})
+	p.GetRetryType(&gocql.RequestErrReadTimeout{})
+	close(resume)
+	<-done
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, DecisionPathTimeoutImmediate, events[0].DecisionPath)
+	assert.Equal(t, DecisionPathServerHinted, events[1].DecisionPath)
+	assert.True(t, events[1].RateLimited)
+}
+
+func TestOnRetryPanicIsRecovered(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.OnRetry = func(RetryEvent) { panic("broken hook") }
+
+	assert.NotPanics(t, func() {
+		assert.Equal(t, gocql.Retry, retryTypeOf(p, errors.New(rateLimitedErrMsg)))
+	})
+}
+
+func TestOnRetryUnset(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(&gocql.RequestErrWriteTimeout{}))
+}
+
+func TestRetryEventElapsed(t *testing.T) {
+	type testCase struct {
+		name  string
+		query func(attempt int) gocql.RetryableQuery
+	}
+
+	testCases := []testCase{
+		{"query attempts", func(attempt int) gocql.RetryableQuery { return attemptsQuery{attempts: attempt} }},
+		{"attempt scope", func() func(int) gocql.RetryableQuery {
+			ctx := WithAttemptScope(context.Background())
+			return func(int) gocql.RetryableQuery { return MockRetryableQuery{ctx: ctx} }
+		}()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+			p := NewCosmosRetryPolicy(-1)
+			p.now = func() time.Time { return now }
+			// back-offs, and the attempts themselves, take time
+			p.sleepFunc = func(d time.Duration) { now = now.Add(d) }
+			var events []RetryEvent
+			p.OnRetry = func(e RetryEvent) { events = append(events, e) }
+
+			for attempt := 1; attempt <= 4; attempt++ {
+				p.Attempt(tc.query(attempt))
+				now = now.Add(10 * time.Millisecond)
+				p.GetRetryType(errors.New(rateLimitedErrMsg))
+			}
+
+			assert.Len(te, events, 4)
+			for i, e := range events {
+				assert.Equal(te, time.Duration(i+1)*52*time.Millisecond, e.Elapsed, "attempt %d", e.Attempt)
+			}
+		})
+	}
+}
+
+func TestRetryEventString(t *testing.T) {
+	e := RetryEvent{Attempt: 2, RetryType: gocql.RetryNextHost, BackOff: 42 * time.Millisecond, DecisionPath: DecisionPathNextHost, Elapsed: time.Second, ActivityID: "abc"}
+	assert.Equal(t, "attempt=2 decision=retry-next-host backoff=42ms rate_limited=false path=next-host elapsed=1s activity_id=abc", e.String())
+}
@@ -0,0 +1,19 @@
+package retry
+
+import "context"
+
+type healthCheckKey struct{}
+
+// WithHealthCheck returns a copy of ctx which tags queries executed with it as synthetic health checks. Health check queries are retried and backed off as usual, but are excluded from throttle accounting, and bypass CircuitBreaker and Limiter
+func WithHealthCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, healthCheckKey{}, true)
+}
+
+// IsHealthCheck reports whether ctx has been tagged using WithHealthCheck
+func IsHealthCheck(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	tagged, _ := ctx.Value(healthCheckKey{}).(bool)
+	return tagged
+}
@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsHealthCheck(t *testing.T) {
+	assert.True(t, IsHealthCheck(WithHealthCheck(context.Background())))
+	assert.False(t, IsHealthCheck(context.Background()))
+	assert.False(t, IsHealthCheck(nil))
+}
+
+func TestHealthCheckQueriesExcludedFromThrottleAccounting(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(-1)
+	p.SustainedThrottleThreshold = 2
+	p.Logger = logger
+
+	healthCheck := MockRetryableQuery{ctx: WithHealthCheck(context.Background())}
+	for i := 0; i < 4; i++ {
+		assert.True(t, p.Attempt(healthCheck))
+		// health checks are still retried
+		assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	}
+	assert.Equal(t, int64(0), p.consecutiveThrottles, "health check affected throttle accounting")
+	assert.Equal(t, 0, logger.count("sustained 429s detected"))
+
+	p.Attempt(MockRetryableQuery{})
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+	assert.Equal(t, int64(1), p.consecutiveThrottles)
+}
+
+func TestHealthCheckQueriesBypassCircuitBreaker(t *testing.T) {
+	p, now := breakerPolicy()
+	p.CircuitBreaker = NewCircuitBreaker(2, time.Minute, time.Minute)
+	p.CircuitBreaker.now = func() time.Time { return *now }
+	ctx := WithHealthCheck(context.Background())
+	healthCheck := attemptsQuery{MockRetryableQuery{ctx: ctx}, 1}
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, p.Attempt(healthCheck))
+		assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	}
+	assert.Equal(t, CircuitClosed, p.CircuitBreaker.State(), "health checks opened the breaker")
+
+	// an open breaker still lets health checks through
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	assert.Equal(t, CircuitOpen, p.CircuitBreaker.State())
+	assert.True(t, p.Attempt(healthCheck))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+
+	// nor does a successful health check close a half-open breaker
+	*now = now.Add(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, p.CircuitBreaker.State())
+	p.ObserveQuery(ctx, gocql.ObservedQuery{})
+	assert.Equal(t, CircuitHalfOpen, p.CircuitBreaker.State())
+	p.ObserveQuery(context.Background(), gocql.ObservedQuery{})
+	assert.Equal(t, CircuitClosed, p.CircuitBreaker.State())
+}
+
+func TestHealthCheckQueriesBypassLimiter(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.SleepDisabled = true
+	p.Limiter = NewLimiter(1, 0)
+	assert.True(t, p.Limiter.Acquire())
+
+	healthCheck := attemptsQuery{MockRetryableQuery{ctx: WithHealthCheck(context.Background())}, 1}
+	assert.True(t, p.Attempt(healthCheck), "health check denied by the saturated limiter")
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	assert.False(t, p.Limiter.Acquire(), "health check released a slot it did not acquire")
+}
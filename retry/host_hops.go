@@ -0,0 +1,14 @@
+package retry
+
+import "github.com/gocql/gocql"
+
+// nextHost returns gocql.RetryNextHost unless the query has already hopped across MaxHostHops hosts, in which case it is rethrown
+func (crp *CosmosRetryPolicy) nextHost(ex *execution) gocql.RetryType {
+	if crp.MaxHostHops > 0 && ex.hostHops >= crp.MaxHostHops {
+		crp.recordPath(ex, DecisionPathHostHopsExhausted)
+		return gocql.Rethrow
+	}
+	ex.hostHops++
+	crp.recordPath(ex, DecisionPathNextHost)
+	return gocql.RetryNextHost
+}
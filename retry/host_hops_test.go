@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxHostHops(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.RetryNextHostOnUnavailable = true
+	p.MaxHostHops = 2
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		p.Attempt(attemptsQuery{attempts: attempt})
+		assert.Equal(t, gocql.RetryNextHost, p.GetRetryType(&gocql.RequestErrUnavailable{}), "attempt %d", attempt)
+	}
+	p.Attempt(attemptsQuery{attempts: 3})
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(&gocql.RequestErrUnavailable{}), "hop cap should be enforced")
+	assert.Equal(t, DecisionPathHostHopsExhausted, p.LastDecisionPath())
+
+	// a new query starts with a fresh hop count
+	p.Attempt(attemptsQuery{attempts: 1})
+	assert.Equal(t, gocql.RetryNextHost, p.GetRetryType(&gocql.RequestErrUnavailable{}))
+}
+
+func TestHostHopsUnlimited(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.RetryNextHostOnUnavailable = true
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		p.Attempt(attemptsQuery{attempts: attempt})
+		assert.Equal(t, gocql.RetryNextHost, p.GetRetryType(&gocql.RequestErrUnavailable{}))
+	}
+}
+
+func TestUnavailableRetriedOnSameHostByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.MaxHostHops = 1
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		p.Attempt(attemptsQuery{attempts: attempt})
+		assert.Equal(t, gocql.Retry, p.GetRetryType(&gocql.RequestErrUnavailable{}))
+	}
+}
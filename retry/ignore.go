@@ -0,0 +1,37 @@
+package retry
+
+import (
+	"errors"
+
+	"github.com/gocql/gocql"
+)
+
+// IgnoredErrors is a set of read errors resolved to gocql.Ignore, i.e. the query returns whatever it got (if anything) without an error, rather than being retried or rethrown. Combine them using |, e.g. IgnoreReadTimeoutsWithData | IgnoreReadFailures. This changes the correctness semantics of reads, which may return partial (or no) data, hence it only suits read paths where incomplete results are acceptable
+type IgnoredErrors int
+
+const (
+	// IgnoreReadTimeoutsWithData ignores read timeouts where the replica asked for the data responded, but not enough replicas did to meet the consistency level
+	IgnoreReadTimeoutsWithData IgnoredErrors = 1 << iota
+	// IgnoreReadTimeouts ignores every read timeout
+	IgnoreReadTimeouts
+	// IgnoreReadFailures ignores read failures, e.g. when a replica hit too many tombstones
+	IgnoreReadFailures
+)
+
+// ignored reports whether err is to be resolved to gocql.Ignore as per IgnoreErrors
+func (crp *CosmosRetryPolicy) ignored(err error) bool {
+	if crp.IgnoreErrors == 0 {
+		return false
+	}
+	var (
+		readTimeout *gocql.RequestErrReadTimeout
+		readFailure *gocql.RequestErrReadFailure
+	)
+	switch {
+	case errors.As(err, &readTimeout):
+		return crp.IgnoreErrors&IgnoreReadTimeouts != 0 || crp.IgnoreErrors&IgnoreReadTimeoutsWithData != 0 && readTimeout.DataPresent != 0
+	case errors.As(err, &readFailure):
+		return crp.IgnoreErrors&IgnoreReadFailures != 0
+	}
+	return false
+}
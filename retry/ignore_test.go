@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreErrors(t *testing.T) {
+	type testCase struct {
+		name     string
+		ignore   IgnoredErrors
+		err      error
+		expected gocql.RetryType
+	}
+
+	withData := &gocql.RequestErrReadTimeout{Received: 1, BlockFor: 2, DataPresent: 1}
+	withoutData := &gocql.RequestErrReadTimeout{Received: 1, BlockFor: 2}
+	readFailure := &gocql.RequestErrReadFailure{NumFailures: 1}
+	testCases := []testCase{
+		{"read timeout with data not ignored by default", 0, withData, gocql.Retry},
+		{"read failure not ignored by default", 0, readFailure, gocql.Rethrow},
+		{"read timeout with data", IgnoreReadTimeoutsWithData, withData, gocql.Ignore},
+		{"wrapped read timeout with data", IgnoreReadTimeoutsWithData, fmt.Errorf("iter: %w", withData), gocql.Ignore},
+		{"read timeout without data", IgnoreReadTimeoutsWithData, withoutData, gocql.Retry},
+		{"any read timeout", IgnoreReadTimeouts, withoutData, gocql.Ignore},
+		{"read failure", IgnoreReadFailures, readFailure, gocql.Ignore},
+		{"read failure with read timeouts ignored", IgnoreReadTimeouts, readFailure, gocql.Rethrow},
+		{"read timeout with read failures ignored", IgnoreReadFailures, withData, gocql.Retry},
+		{"combined", IgnoreReadTimeoutsWithData | IgnoreReadFailures, readFailure, gocql.Ignore},
+		{"write timeout", IgnoreReadTimeouts | IgnoreReadFailures, &gocql.RequestErrWriteTimeout{}, gocql.Retry},
+		{"unavailable", IgnoreReadTimeouts | IgnoreReadFailures, &gocql.RequestErrUnavailable{}, gocql.Retry},
+		{"rate limited", IgnoreReadTimeouts | IgnoreReadFailures, errors.New(rateLimitedErrMsg), gocql.Retry},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.IgnoreErrors = tc.ignore
+			p.sleepFunc = func(time.Duration) {}
+
+			assert.True(te, p.Attempt(attemptsQuery{attempts: 1}))
+			assert.Equal(te, tc.expected, p.GetRetryType(tc.err))
+			if tc.expected == gocql.Ignore {
+				assert.Equal(te, DecisionPathIgnored, p.LastDecisionPath())
+			}
+		})
+	}
+}
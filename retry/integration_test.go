@@ -0,0 +1,134 @@
+//go:build integration
+// +build integration
+
+package retry
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// The integration tests run against the Cassandra API of a Cosmos DB account (or the Cosmos DB emulator) configured with the following environment variables, and are skipped if COSMOS_CASSANDRA_CONTACT_POINT is not set. Run them with go test -tags integration ./...
+const (
+	// envContactPoint is the host name of the account, e.g. localhost for the emulator
+	envContactPoint = "COSMOS_CASSANDRA_CONTACT_POINT"
+	envPort         = "COSMOS_CASSANDRA_PORT"
+	envUsername     = "COSMOS_CASSANDRA_USERNAME"
+	envPassword     = "COSMOS_CASSANDRA_PASSWORD"
+	// envInsecureSkipVerify disables the verification of the server certificate, e.g. the self-signed one of the emulator
+	envInsecureSkipVerify = "COSMOS_CASSANDRA_INSECURE_SKIP_VERIFY"
+	// envWrites is the number of writes driven to trigger rate limiting, which depends on the throughput of the account
+	envWrites = "COSMOS_CASSANDRA_WRITES"
+)
+
+const defaultIntegrationWrites = 2000
+const integrationWorkers = 50
+const integrationThroughput = 400
+
+func integrationSession(t *testing.T, crp *CosmosRetryPolicy) *gocql.Session {
+	contactPoint := os.Getenv(envContactPoint)
+	if contactPoint == "" {
+		t.Skipf("%s is not set", envContactPoint)
+	}
+	port, err := integrationInt(envPort, DefaultCosmosPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := NewCosmosClusterWithAuth(contactPoint, os.Getenv(envUsername), os.Getenv(envPassword), port, crp)
+	if skip, _ := strconv.ParseBool(os.Getenv(envInsecureSkipVerify)); skip {
+		cluster.SslOpts = &gocql.SslOptions{Config: &tls.Config{InsecureSkipVerify: true}}
+	}
+	cluster.Timeout = 30 * time.Second
+	cluster.ConnectTimeout = 30 * time.Second
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", contactPoint, err)
+	}
+	return session
+}
+
+// integrationInt returns the integer value of the environment variable key, or def if it is not set
+func integrationInt(key string, def int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return i, nil
+}
+
+func TestIntegrationRateLimitedWritesSucceed(t *testing.T) {
+	var rateLimitedRetries int64
+	crp := NewCosmosRetryPolicy(-1)
+	crp.OnRetry = func(e RetryEvent) {
+		if e.RateLimited && e.RetryType == gocql.Retry {
+			atomic.AddInt64(&rateLimitedRetries, 1)
+		}
+	}
+	crp.MaxElapsedTimeMs = int(2 * time.Minute / time.Millisecond)
+
+	session := integrationSession(t, crp)
+	defer session.Close()
+
+	writes, err := integrationInt(envWrites, defaultIntegrationWrites)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyspace := fmt.Sprintf("retry_it_%d", time.Now().UnixNano())
+	assert.NoError(t, session.Query(fmt.Sprintf("CREATE KEYSPACE %s WITH REPLICATION = {'class': 'SimpleStrategy', 'replication_factor': 1}", keyspace)).Exec())
+	defer func() {
+		assert.NoError(t, session.Query(fmt.Sprintf("DROP KEYSPACE IF EXISTS %s", keyspace)).Exec())
+	}()
+	// the lowest throughput, so that the writes get rate limited quickly
+	assert.NoError(t, session.Query(fmt.Sprintf("CREATE TABLE %s.writes (id int PRIMARY KEY, payload text) WITH cosmosdb_provisioned_throughput=%d", keyspace, integrationThroughput)).Exec())
+
+	insert := fmt.Sprintf("INSERT INTO %s.writes (id, payload) VALUES (?, ?)", keyspace)
+	payload := strings.Repeat("x", 4096)
+	ids := make(chan int)
+	var failed int64
+	var wg sync.WaitGroup
+	for w := 0; w < integrationWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				if err := session.Query(insert, id, payload).WithContext(WithAttemptScope(context.Background())).Idempotent(true).Exec(); err != nil {
+					if atomic.AddInt64(&failed, 1) == 1 {
+						t.Errorf("write %d failed: %v", id, err)
+					}
+				}
+			}
+		}()
+	}
+	for id := 0; id < writes; id++ {
+		ids <- id
+	}
+	close(ids)
+	wg.Wait()
+
+	assert.Equal(t, int64(0), atomic.LoadInt64(&failed))
+	if atomic.LoadInt64(&rateLimitedRetries) == 0 {
+		t.Skipf("no write was rate limited, consider increasing %s", envWrites)
+	}
+
+	var count int
+	assert.NoError(t, session.Query(fmt.Sprintf("SELECT COUNT(*) FROM %s.writes", keyspace)).Scan(&count))
+	assert.Equal(t, writes, count)
+}
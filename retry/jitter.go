@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"hash/fnv"
+)
+
+// JitterMode defines how the back-off is jittered, to de-correlate the retries of concurrent clients. It applies to both the fixed (used with finite retries) and the growing (used with infinite retries) back-off
+type JitterMode int
+
+const (
+	// EqualJitter adds a random salt to the back-off, of up to 2s for the growing back-off and up to a tenth of it (no more than 2s) for the fixed back-off, or jitters it as per JitterFraction if set (default). ActivityIDJitter derives the salt from the ActivityID of the error instead
+	EqualJitter JitterMode = iota
+	// NoJitter uses the back-off as is, regardless of JitterFraction and ActivityIDJitter
+	NoJitter
+	// FullJitter picks the back-off at random between 0 and its value (capped by MaxBackOffTimeMs), which de-correlates retries best. ActivityIDJitter and JitterFraction do not apply
+	FullJitter
+)
+
+func (jm JitterMode) String() string {
+	switch jm {
+	case NoJitter:
+		return "none"
+	case FullJitter:
+		return "full"
+	}
+	return "equal"
+}
+
+// activityIDJitter returns the jitter (in ms) derived from activityID if ActivityIDJitter is enabled. ok is false otherwise
+func (crp *CosmosRetryPolicy) activityIDJitter(activityID string) (offset int, ok bool) {
+	if !crp.ActivityIDJitter || activityID == "" {
+		return 0, false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(activityID))
+	return int(h.Sum32() % growingBackOffSaltMillis), true
+}
+
+// jitterFraction returns base (in ms) jittered by up to ±JitterFraction of it. The jitter is derived from activityID if ActivityIDJitter is enabled, and random otherwise
+func (crp *CosmosRetryPolicy) jitterFraction(base int, activityID string) int {
+	// r is in [-1, 1)
+	r := crp.randFloat64()*2 - 1
+	if offset, ok := crp.activityIDJitter(activityID); ok {
+		r = float64(offset)/growingBackOffSaltMillis*2 - 1
+	}
+	jittered := base + int(float64(base)*crp.JitterFraction*r)
+	if jittered < 0 {
+		return 0
+	}
+	return jittered
+}
+
+// fullJitter returns a random back-off (in ms) in [0, base]
+func (crp *CosmosRetryPolicy) fullJitter(base int) int {
+	if base <= 0 {
+		return 0
+	}
+	return crp.randInt(base + 1)
+}
+
+// fixedJitter returns the fixed back-off base (in ms) jittered as per JitterMode. The result is never negative
+func (crp *CosmosRetryPolicy) fixedJitter(base int, activityID string) int {
+	switch {
+	case crp.JitterMode == NoJitter:
+	case crp.JitterMode == FullJitter:
+		return crp.fullJitter(base)
+	case crp.JitterFraction > 0:
+		base = crp.jitterFraction(base, activityID)
+	case base <= 0:
+	default:
+		salt, ok := crp.activityIDJitter(activityID)
+		if !ok {
+			salt = crp.randInt(fixedBackOffSaltMillis(base) + 1)
+		}
+		base += salt
+	}
+	if base < 0 {
+		return 0
+	}
+	return base
+}
+
+// fixedBackOffSaltFraction bounds the random salt EqualJitter adds to the fixed back-off, relative to it
+const fixedBackOffSaltFraction = 0.1
+
+// fixedBackOffSaltMillis returns the upper bound (in ms) of the random salt EqualJitter adds to the fixed back-off base: a tenth of it, but no more than the salt of the growing back-off
+func fixedBackOffSaltMillis(base int) int {
+	salt := int(float64(base) * fixedBackOffSaltFraction)
+	if salt > growingBackOffSaltMillis {
+		return growingBackOffSaltMillis
+	}
+	return salt
+}
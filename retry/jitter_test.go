@@ -0,0 +1,250 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rateLimitedErrMsgWithActivityID(activityID string) string {
+	return strings.Replace(rateLimitedErrMsgWithoutRetryAfterMs, "c268afb6-7367-4ff8-b06b-b7e2d1269f55", activityID, -1)
+}
+
+func TestActivityIDJitter(t *testing.T) {
+	type testCase struct {
+		name   string
+		policy *CosmosRetryPolicy
+		base   time.Duration
+	}
+
+	finite := NewCosmosRetryPolicy(5)
+	finite.ActivityIDJitter = true
+	infinite := NewCosmosRetryPolicy(-1)
+	infinite.ActivityIDJitter = true
+	infinite.exec.attempts = 2
+
+	testCases := []testCase{
+		{"jitter for fixed back-off", finite, time.Duration(finite.FixedBackOffTimeMs) * time.Millisecond},
+		{"jitter for growing back-off", infinite, time.Duration(2*infinite.GrowingBackOffTimeMs) * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			first := retryAfter(te, tc.policy, rateLimitedErrMsgWithActivityID("2f9c4a10-1b3e-4c5d-8e7f-6a5b4c3d2e1f"))
+			second := retryAfter(te, tc.policy, rateLimitedErrMsgWithActivityID("9e8d7c6b-5a4f-4e3d-2c1b-0a9f8e7d6c5b"))
+
+			assert.NotEqual(te, first, second, "different activity ids should back off at different offsets")
+			for _, d := range []time.Duration{first, second} {
+				assert.True(te, d >= tc.base && d < tc.base+growingBackOffSaltMillis*time.Millisecond, "back-off %v out of range", d)
+			}
+			for i := 0; i < 10; i++ {
+				assert.Equal(te, first, retryAfter(te, tc.policy, rateLimitedErrMsgWithActivityID("2f9c4a10-1b3e-4c5d-8e7f-6a5b4c3d2e1f")), "same activity id should back off at a stable offset")
+			}
+		})
+	}
+}
+
+func TestActivityIDJitterDisabledByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	// the random salt of EqualJitter, rather than one derived from the activity id
+	p.randFunc = func(int) int { return 0 }
+	assert.Equal(t, time.Duration(p.FixedBackOffTimeMs)*time.Millisecond, retryAfter(t, p, rateLimitedErrMsgWithActivityID("2f9c4a10-1b3e-4c5d-8e7f-6a5b4c3d2e1f")))
+}
+
+func TestJitterFraction(t *testing.T) {
+	type testCase struct {
+		name   string
+		policy *CosmosRetryPolicy
+		base   time.Duration
+	}
+
+	finite := NewCosmosRetryPolicy(5)
+	finite.JitterFraction = 0.2
+	infinite := NewCosmosRetryPolicy(-1)
+	infinite.JitterFraction = 0.2
+	infinite.exec.attempts = 3
+
+	testCases := []testCase{
+		{"jitter fraction for fixed back-off", finite, time.Duration(finite.FixedBackOffTimeMs) * time.Millisecond},
+		{"jitter fraction for growing back-off", infinite, time.Duration(3*infinite.GrowingBackOffTimeMs) * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			low, high := tc.base*8/10, tc.base*12/10
+			seen := map[time.Duration]bool{}
+			for i := 0; i < 100; i++ {
+				d := retryAfter(te, tc.policy, rateLimitedErrMsgWithoutRetryAfterMs)
+				assert.True(te, d >= low && d <= high, "back-off %v not within 20%% of %v", d, tc.base)
+				seen[d] = true
+			}
+			assert.True(te, len(seen) > 1, "back-off was not jittered")
+		})
+	}
+}
+
+func TestJitterFractionWithActivityID(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.JitterFraction = 0.1
+	p.ActivityIDJitter = true
+	base := time.Duration(p.FixedBackOffTimeMs) * time.Millisecond
+
+	first := retryAfter(t, p, rateLimitedErrMsgWithActivityID("2f9c4a10-1b3e-4c5d-8e7f-6a5b4c3d2e1f"))
+	assert.True(t, first >= base*9/10 && first <= base*11/10, "back-off %v not within 10%% of %v", first, base)
+	assert.Equal(t, first, retryAfter(t, p, rateLimitedErrMsgWithActivityID("2f9c4a10-1b3e-4c5d-8e7f-6a5b4c3d2e1f")))
+}
+
+func TestJitterModeDistribution(t *testing.T) {
+	type testCase struct {
+		name     string
+		mode     JitterMode
+		min, max time.Duration
+		mean     time.Duration
+	}
+
+	// growing back-off of 4s for the 4th attempt
+	const base = 4 * time.Second
+	testCases := []testCase{
+		{"no jitter", NoJitter, base, base, base},
+		{"equal jitter", EqualJitter, base, base + growingBackOffSaltMillis*time.Millisecond, base + growingBackOffSaltMillis*time.Millisecond/2},
+		{"full jitter", FullJitter, 0, base, base / 2},
+	}
+
+	const samples = 5000
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(-1)
+			p.JitterMode = tc.mode
+			p.exec.attempts = 4
+			// seeded, so that the test is not flaky
+			p.randFunc = rand.New(rand.NewSource(1)).Intn
+
+			lowest, highest, sum := time.Duration(math.MaxInt64), time.Duration(0), time.Duration(0)
+			for i := 0; i < samples; i++ {
+				d := retryAfter(te, p, rateLimitedErrMsgWithoutRetryAfterMs)
+				assert.True(te, d >= tc.min && d <= tc.max, "back-off %v out of [%v, %v]", d, tc.min, tc.max)
+				if d < lowest {
+					lowest = d
+				}
+				if d > highest {
+					highest = d
+				}
+				sum += d
+			}
+
+			// samples should spread over the whole range, around its middle
+			spread := tc.max - tc.min
+			assert.True(te, lowest-tc.min <= spread/20, "lowest back-off %v", lowest)
+			assert.True(te, tc.max-highest <= spread/20, "highest back-off %v", highest)
+			mean := sum / samples
+			assert.True(te, mean >= tc.mean-spread/20 && mean <= tc.mean+spread/20, "mean back-off %v, expected about %v", mean, tc.mean)
+		})
+	}
+}
+
+func TestFullJitterCappedByMaxBackOff(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1, WithMaxBackOff(time.Second))
+	p.JitterMode = FullJitter
+	p.exec.attempts = 100
+	var bound int
+	p.randFunc = func(n int) int {
+		bound = n
+		return n - 1
+	}
+
+	assert.Equal(t, time.Second, retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs))
+	assert.Equal(t, 1001, bound)
+}
+
+func TestJitterModeString(t *testing.T) {
+	assert.Equal(t, "equal", EqualJitter.String())
+	assert.Equal(t, "none", NoJitter.String())
+	assert.Equal(t, "full", FullJitter.String())
+}
+
+func TestFixedBackOffJitterMode(t *testing.T) {
+	type testCase struct {
+		name     string
+		mode     JitterMode
+		fraction float64
+		min, max time.Duration
+	}
+
+	const base = 5 * time.Second
+	testCases := []testCase{
+		{"no jitter", NoJitter, 0, base, base},
+		{"no jitter ignores jitter fraction", NoJitter, 0.2, base, base},
+		{"equal jitter", EqualJitter, 0, base, base * 11 / 10},
+		{"equal jitter with jitter fraction", EqualJitter, 0.2, base * 8 / 10, base * 12 / 10},
+		{"full jitter", FullJitter, 0, 0, base},
+	}
+
+	const samples = 1000
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			p.JitterMode = tc.mode
+			p.JitterFraction = tc.fraction
+			p.randFunc = rand.New(rand.NewSource(1)).Intn
+
+			seen := map[time.Duration]bool{}
+			for i := 0; i < samples; i++ {
+				d := retryAfter(te, p, rateLimitedErrMsgWithoutRetryAfterMs)
+				assert.True(te, d >= tc.min && d <= tc.max, "back-off %v out of [%v, %v]", d, tc.min, tc.max)
+				seen[d] = true
+			}
+			assert.Equal(te, tc.min != tc.max, len(seen) > 1, "back-off jittered")
+		})
+	}
+}
+
+func TestFixedBackOffEqualJitterSaltIsBounded(t *testing.T) {
+	type testCase struct {
+		name     string
+		base     int
+		expected int
+	}
+
+	testCases := []testCase{
+		{"a tenth of the back-off", 5000, 500},
+		{"no salt for tiny back-offs", 9, 0},
+		{"no more than the salt of the growing back-off", 60000, growingBackOffSaltMillis},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			p.FixedBackOffTimeMs = tc.base
+			var bound int
+			p.randFunc = func(n int) int {
+				bound = n
+				return n - 1
+			}
+
+			assert.Equal(te, time.Duration(tc.base+tc.expected)*time.Millisecond, retryAfter(te, p, rateLimitedErrMsgWithoutRetryAfterMs))
+			assert.Equal(te, tc.expected+1, bound)
+		})
+	}
+}
+
+func TestFixedBackOffJitterNeverNegative(t *testing.T) {
+	for _, mode := range []JitterMode{EqualJitter, NoJitter, FullJitter} {
+		p := NewCosmosRetryPolicy(5)
+		p.JitterMode = mode
+		p.JitterFraction = 1
+		p.FixedBackOffTimeMs = -100
+		for i := 0; i < 100; i++ {
+			assert.Equal(t, time.Duration(0), retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs), "jitter mode %v", mode)
+		}
+
+		p.FixedBackOffTimeMs = 1
+		for i := 0; i < 100; i++ {
+			d := retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs)
+			assert.True(t, d >= 0 && d <= 2*time.Millisecond, "jitter mode %v: back-off %v", mode, d)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package retry
+
+import "time"
+
+// boundBackOff clamps d to the floor and ceiling configured for kind in BackOffBoundsByKind. A ceiling of 0 means no ceiling
+func (crp *CosmosRetryPolicy) boundBackOff(kind ErrorKind, d time.Duration) time.Duration {
+	bounds, ok := crp.BackOffBoundsByKind[kind]
+	if !ok {
+		return d
+	}
+	if d < bounds[0] {
+		d = bounds[0]
+	}
+	if bounds[1] > 0 && d > bounds[1] {
+		d = bounds[1]
+	}
+	return d
+}
+
+// parseErrorKind returns the ErrorKind whose String representation is s
+func parseErrorKind(s string) (ErrorKind, bool) {
+	for kind := UnclassifiedError; kind <= TransientError; kind++ {
+		if kind.String() == s {
+			return kind, true
+		}
+	}
+	return UnclassifiedError, false
+}
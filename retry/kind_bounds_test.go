@@ -0,0 +1,42 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackOffBoundsByKind(t *testing.T) {
+	type testCase struct {
+		name     string
+		bounds   map[ErrorKind][2]time.Duration
+		err      error
+		expected time.Duration
+	}
+
+	testCases := []testCase{
+		{"rate limited back-off raised to its floor", map[ErrorKind][2]time.Duration{RateLimitedError: {50 * time.Millisecond, time.Second}}, errors.New(rateLimitedErrMsg), 50 * time.Millisecond},
+		{"rate limited back-off within bounds", map[ErrorKind][2]time.Duration{RateLimitedError: {10 * time.Millisecond, time.Second}}, errors.New(rateLimitedErrMsg), 42 * time.Millisecond},
+		{"not ready back-off lowered to its ceiling", map[ErrorKind][2]time.Duration{NotReadyError: {0, 20 * time.Millisecond}}, errors.New(notReadyErrMsg), 20 * time.Millisecond},
+		{"timeout back-off lowered to its ceiling", map[ErrorKind][2]time.Duration{TransientError: {time.Millisecond, 5 * time.Millisecond}}, &gocql.RequestErrWriteTimeout{}, 5 * time.Millisecond},
+		{"unmapped kind uses the computed back-off", map[ErrorKind][2]time.Duration{NotReadyError: {time.Second, 0}}, errors.New(rateLimitedErrMsg), 42 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			p.FixedBackOffTimeMs = 30
+			p.ImmediateRetries = 1
+			p.NotReadyMarker = "unconfigured table"
+			p.NotReadyBackOffTimeMs = 1000
+			p.BackOffBoundsByKind = tc.bounds
+
+			p.Attempt(attemptsQuery{attempts: 2})
+			assert.Equal(te, gocql.Retry, p.GetRetryType(tc.err))
+			assert.Equal(te, tc.expected, p.BlockedTime())
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LastRetryAfterMs returns the RetryAfterMs of the most recent rate limited (429) error which carried it, as sent by the server (before MinBackOffTimeMs or any bound applies), to gauge how aggressively requests are being throttled. It is 0 until such an error is handled
+func (crp *CosmosRetryPolicy) LastRetryAfterMs() time.Duration {
+	return time.Duration(atomic.LoadInt64(&crp.lastRetryAfterNanos))
+}
+
+// recordRetryAfter keeps track of the RetryAfterMs of ce, if it carries it, see LastRetryAfterMs and RetryAfterHistogram. Decisions made by Decide are not accounted for
+func (crp *CosmosRetryPolicy) recordRetryAfter(ex *execution, ce cosmosError) {
+	if !ce.hinted || ex.dryRun {
+		return
+	}
+	atomic.StoreInt64(&crp.lastRetryAfterNanos, int64(ce.retryAfter))
+	crp.observeRetryAfter(ce.retryAfter)
+}
@@ -0,0 +1,35 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastRetryAfterMs(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.MinBackOffTimeMs = 100
+	p.sleepFunc = func(time.Duration) {}
+	assert.Equal(t, time.Duration(0), p.LastRetryAfterMs())
+
+	p.Attempt(attemptsQuery{attempts: 1})
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+	// as sent by the server, not as floored by MinBackOffTimeMs
+	assert.Equal(t, 42*time.Millisecond, p.LastRetryAfterMs())
+
+	p.Attempt(attemptsQuery{attempts: 2})
+	p.GetRetryType(errors.New(directRateLimitedErrMsgWithRetryAfterMs))
+	assert.Equal(t, 120*time.Millisecond, p.LastRetryAfterMs())
+
+	// errors without RetryAfterMs leave it as is
+	p.Attempt(attemptsQuery{attempts: 3})
+	p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs))
+	p.Attempt(attemptsQuery{attempts: 4})
+	p.GetRetryType(errors.New("error: today is not your day!"))
+	assert.Equal(t, 120*time.Millisecond, p.LastRetryAfterMs())
+
+	p.Decide(errors.New(rateLimitedErrMsg), 1, true)
+	assert.Equal(t, 120*time.Millisecond, p.LastRetryAfterMs())
+}
@@ -0,0 +1,94 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter bounds the number of in-flight query executions. To keep retries from amplifying load, share the same Limiter between the application (for initial executions) and the policy (for retries), using AcquireSlot. See CosmosRetryPolicy.Limiter
+type Limiter interface {
+	// Acquire reserves a slot for an execution. It may wait for capacity to free up, and returns false if none did
+	Acquire() bool
+	// Release frees a slot reserved using Acquire. It must be called once per successful Acquire, since a Release without a matching Acquire frees a slot reserved by another execution
+	Release()
+}
+
+// AcquireSlot reserves a slot of l and returns the function releasing it. Unlike Release, the function may be called more than once (e.g. by a deferred call as well as an early one), only the first call releases the slot, so that a slot reserved by another execution is never freed
+func AcquireSlot(l Limiter) (release func(), ok bool) {
+	if !l.Acquire() {
+		return nil, false
+	}
+	var once sync.Once
+	return func() { once.Do(l.Release) }, true
+}
+
+// semaphoreLimiter is a Limiter backed by a buffered channel
+type semaphoreLimiter struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewLimiter returns a Limiter allowing up to capacity in-flight executions. Acquire waits up to maxWait for a slot to free up. 0 means it does not wait
+func NewLimiter(capacity int, maxWait time.Duration) Limiter {
+	return &semaphoreLimiter{slots: make(chan struct{}, capacity), maxWait: maxWait}
+}
+
+func (sl *semaphoreLimiter) Acquire() bool {
+	select {
+	case sl.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if sl.maxWait <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(sl.maxWait)
+	defer timer.Stop()
+	select {
+	case sl.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (sl *semaphoreLimiter) Release() {
+	select {
+	case <-sl.slots:
+	default:
+	}
+}
+
+// acquireSlot reserves a Limiter slot (if configured) for the next retry of ex. Health check queries bypass the Limiter
+func (crp *CosmosRetryPolicy) acquireSlot(ex *execution) bool {
+	if crp.Limiter == nil || ex.healthCheck {
+		return true
+	}
+	release, ok := AcquireSlot(crp.Limiter)
+	if !ok {
+		crp.recordPath(ex, DecisionPathLimiterSaturated)
+		return false
+	}
+	ex.releaseSlot = release
+	return true
+}
+
+// releaseSlot frees the Limiter slot held by ex, if any. Copies of ex share the slot, which is only released once
+func (crp *CosmosRetryPolicy) releaseSlot(ex *execution) {
+	if ex.releaseSlot == nil {
+		return
+	}
+	release := ex.releaseSlot
+	ex.releaseSlot = nil
+	release()
+}
+
+// finish marks ex as done, releasing its Limiter slot and recording its stat (see EnableRetryStats)
+func (crp *CosmosRetryPolicy) finish(ex *execution, succeeded bool) {
+	crp.releaseSlot(ex)
+	if !ex.done {
+		crp.recordStat(ex, succeeded)
+	}
+	ex.done = true
+}
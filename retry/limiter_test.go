@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterCapacity(t *testing.T) {
+	l := NewLimiter(1, 0)
+	assert.True(t, l.Acquire())
+	assert.False(t, l.Acquire(), "limiter should be saturated")
+	l.Release()
+	assert.True(t, l.Acquire())
+}
+
+func TestAcquireSlotReleasesOnce(t *testing.T) {
+	l := NewLimiter(2, 0)
+	release, ok := AcquireSlot(l)
+	assert.True(t, ok)
+	// held by another execution
+	assert.True(t, l.Acquire())
+
+	_, ok = AcquireSlot(l)
+	assert.False(t, ok, "limiter should be saturated")
+	release()
+	release()
+	assert.True(t, l.Acquire())
+	assert.False(t, l.Acquire(), "the slot of the other execution should still be held")
+}
+
+func TestPolicyOnlyReleasesItsOwnSlots(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.SleepDisabled = true
+	p.Limiter = NewLimiter(2, 0)
+	// held by the application
+	_, ok := AcquireSlot(p.Limiter)
+	assert.True(t, ok)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		assert.True(t, p.Attempt(attemptsQuery{attempts: attempt}))
+		assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	}
+	// an Attempt without GetRetryType, whose slot is released by Reset
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 1}))
+	p.Reset()
+	p.Reset()
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+
+	assert.True(t, p.Limiter.Acquire())
+	assert.False(t, p.Limiter.Acquire(), "the slot of the application should still be held")
+}
+
+func TestRetriesRespectLimiterCapacity(t *testing.T) {
+	limiter := NewLimiter(2, 0)
+	p := NewCosmosRetryPolicy(3)
+	p.FixedBackOffTimeMs = 1
+	p.Limiter = limiter
+
+	// the application holds a slot for an initial execution
+	assert.True(t, limiter.Acquire())
+
+	first := MockRetryableQuery{ctx: WithAttemptScope(context.Background())}
+	assert.True(t, p.Attempt(first))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+
+	second := MockRetryableQuery{ctx: WithAttemptScope(context.Background())}
+	assert.False(t, p.Attempt(second), "retry should be denied while the limiter is saturated")
+	assert.Equal(t, DecisionPathLimiterSaturated, p.LastDecisionPath())
+
+	// the retry of the first query succeeds, freeing its slot
+	p.ObserveQuery(first.ctx, gocql.ObservedQuery{})
+	assert.True(t, p.Attempt(second))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+
+	// the retry of the second query fails again, and is given up on
+	assert.True(t, p.Attempt(second))
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New("syntax error")))
+	assert.True(t, limiter.Acquire(), "slot should have been released after giving up")
+}
+
+func TestRetryDeferredUntilLimiterSlotFreesUp(t *testing.T) {
+	limiter := NewLimiter(1, time.Second)
+	p := NewCosmosRetryPolicy(3)
+	p.Limiter = limiter
+
+	assert.True(t, limiter.Acquire())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		limiter.Release()
+	}()
+
+	begin := time.Now()
+	assert.True(t, p.Attempt(MockRetryableQuery{ctx: WithAttemptScope(context.Background())}))
+	assert.True(t, time.Since(begin) >= 20*time.Millisecond, "retry should have waited for a slot")
+}
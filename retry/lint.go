@@ -0,0 +1,12 @@
+package retry
+
+const uncappedInfiniteRetriesWarning = "infinite retries (MaxRetryCount -1) without MaxBackOffTimeMs: the growing back-off is unbounded"
+
+// Lint returns warnings about configurations which are valid, but likely to misbehave in production. It returns nil if there is nothing to report
+func (crp *CosmosRetryPolicy) Lint() []string {
+	var warnings []string
+	if crp.MaxRetryCount == -1 && crp.MaxBackOffTimeMs <= 0 {
+		warnings = append(warnings, uncappedInfiniteRetriesWarning)
+	}
+	return warnings
+}
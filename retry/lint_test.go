@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintWarnsOnUncappedInfiniteRetries(t *testing.T) {
+	type testCase struct {
+		name     string
+		policy   *CosmosRetryPolicy
+		expected []string
+	}
+
+	testCases := []testCase{
+		{"infinite retries without back-off cap", NewCosmosRetryPolicy(-1, WithMaxBackOff(0)), []string{uncappedInfiniteRetriesWarning}},
+		{"infinite retries with back-off cap", NewCosmosRetryPolicy(-1, WithMaxBackOff(30*time.Second)), nil},
+		{"infinite retries with default back-off cap", NewCosmosRetryPolicy(-1), nil},
+		{"finite retries", NewCosmosRetryPolicy(3), nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, tc.policy.Lint())
+		})
+	}
+}
+
+func TestMaxBackOffCapsGrowingBackOff(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1, WithMaxBackOff(1500*time.Millisecond))
+	p.exec.attempts = 5
+
+	assert.Equal(t, 1500*time.Millisecond, retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs))
+}
+
+func TestMaxBackOffCapsGrowingBackOffByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.exec.attempts = 1000
+
+	assert.Equal(t, 30*time.Second, retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs))
+}
+
+func TestMaxBackOffDisabled(t *testing.T) {
+	for _, maxBackOff := range []int{0, -1} {
+		p := NewCosmosRetryPolicy(-1)
+		p.MaxBackOffTimeMs = maxBackOff
+		p.exec.attempts = 1000
+		p.randFunc = func(int) int { return 500 }
+
+		// growing back-off of 1000s, plus the salt
+		assert.Equal(t, 1000*time.Second+500*time.Millisecond, retryAfter(t, p, rateLimitedErrMsgWithoutRetryAfterMs), "MaxBackOffTimeMs %d", maxBackOff)
+	}
+}
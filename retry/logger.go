@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Logger is the minimal logging interface used by CosmosRetryPolicy. *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+const retryDecisionMessage = "retry decision: attempt=%d decision=%s backoff_ms=%d retry_after_ms=%s rate_limited=%t path=%s"
+
+// loggedDecision is a retry decision as logged when LogDecisions is enabled
+type loggedDecision struct {
+	attempt     int
+	decision    string
+	backOff     time.Duration
+	retryAfter  time.Duration
+	hinted      bool
+	rateLimited bool
+	path        string
+}
+
+// decisionLogger is implemented by Loggers which log retry decisions as structured records rather than formatted messages, see NewSlogLogger
+type decisionLogger interface {
+	logDecision(ctx context.Context, d loggedDecision)
+}
+
+// logDecision logs a retry decision made by GetRetryType, if LogDecisions is enabled. retry_after_ms is the RetryAfterMs carried by the error, or n/a
+func (crp *CosmosRetryPolicy) logDecision(ex *execution, err error, retryType gocql.RetryType, backOff time.Duration) {
+	if crp.Logger == nil || !crp.LogDecisions {
+		return
+	}
+	d := loggedDecision{attempt: ex.attempts, decision: RetryTypeString(retryType), backOff: backOff, rateLimited: ex.kind == RateLimitedError, path: ex.path}
+	if err != nil {
+		ce := cosmosErrorOf(err, err.Error())
+		d.retryAfter, d.hinted = ce.retryAfter, ce.hinted
+	}
+	if dl, ok := crp.Logger.(decisionLogger); ok {
+		ctx := ex.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		dl.logDecision(ctx, d)
+		return
+	}
+
+	retryAfter := "n/a"
+	if d.hinted {
+		retryAfter = strconv.FormatInt(int64(d.retryAfter/time.Millisecond), 10)
+	}
+	crp.Logger.Printf(retryDecisionMessage, d.attempt, d.decision, int64(d.backOff/time.Millisecond), retryAfter, d.rateLimited, d.path)
+}
+
+// RetryTypeString returns a human-readable name for retryType, e.g. retry rather than 0, for logs
+func RetryTypeString(retryType gocql.RetryType) string {
+	switch retryType {
+	case gocql.Retry:
+		return "retry"
+	case gocql.RetryNextHost:
+		return "retry-next-host"
+	case gocql.Ignore:
+		return "ignore"
+	case gocql.Rethrow:
+		return "rethrow"
+	}
+	return "unknown(" + strconv.Itoa(int(retryType)) + ")"
+}
@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogDecisions(t *testing.T) {
+	type testCase struct {
+		name     string
+		err      error
+		expected string
+	}
+
+	testCases := []testCase{
+		{"rate limited error with RetryAfterMs", errors.New(rateLimitedErrMsg), "retry decision: attempt=2 decision=retry backoff_ms=42 retry_after_ms=42 rate_limited=true path=" + DecisionPathServerHinted},
+		{"rate limited error without RetryAfterMs", errors.New(rateLimitedErrMsgWithoutRetryAfterMs), "retry decision: attempt=2 decision=retry backoff_ms=5000 retry_after_ms=n/a rate_limited=true path=" + DecisionPathFixedFallback},
+		{"error other than rate limiting", errors.New("error: today is not your day!"), "retry decision: attempt=2 decision=rethrow backoff_ms=0 retry_after_ms=n/a rate_limited=false path=" + DecisionPathNotRetryable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			logger := &capturingLogger{}
+			p := NewCosmosRetryPolicy(5)
+			p.JitterMode = NoJitter
+			p.LogDecisions = true
+			p.Logger = logger
+			p.sleepFunc = func(time.Duration) {}
+
+			assert.True(te, p.Attempt(attemptsQuery{MockRetryableQuery{}, 2}))
+			p.GetRetryType(tc.err)
+			assert.Equal(te, []string{tc.expected}, logger.messages)
+		})
+	}
+}
+
+func TestLogDecisionsDisabledByDefault(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(5)
+	p.Logger = logger
+	p.sleepFunc = func(time.Duration) {}
+
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	assert.Empty(t, logger.messages)
+
+	// enabling it without a Logger is a no-op
+	p.Logger = nil
+	p.LogDecisions = true
+	assert.Equal(t, gocql.Retry, retryTypeOf(p, errors.New(rateLimitedErrMsg)))
+}
+
+func TestRetryTypeString(t *testing.T) {
+	type testCase struct {
+		retryType gocql.RetryType
+		expected  string
+	}
+
+	testCases := []testCase{
+		{gocql.Retry, "retry"},
+		{gocql.RetryNextHost, "retry-next-host"},
+		{gocql.Ignore, "ignore"},
+		{gocql.Rethrow, "rethrow"},
+		{gocql.RetryType(42), "unknown(42)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(te *testing.T) {
+			assert.Equal(te, tc.expected, RetryTypeString(tc.retryType))
+		})
+	}
+}
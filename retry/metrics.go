@@ -0,0 +1,50 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// retryCounters are the decision counters maintained by the policy. They are updated atomically
+type retryCounters struct {
+	retries            uint64
+	rateLimitedRetries uint64
+	rethrows           uint64
+}
+
+// record accounts for a retry decision
+func (rc *retryCounters) record(retryType gocql.RetryType, rateLimited bool) {
+	switch retryType {
+	case gocql.Retry, gocql.RetryNextHost:
+		atomic.AddUint64(&rc.retries, 1)
+		if rateLimited {
+			atomic.AddUint64(&rc.rateLimitedRetries, 1)
+		}
+	case gocql.Rethrow:
+		atomic.AddUint64(&rc.rethrows, 1)
+	}
+}
+
+// Metrics is a snapshot of the policy counters, e.g. for wiring them into Prometheus collectors. See CosmosRetryPolicy.Metrics
+type Metrics struct {
+	// Retries is the number of errors retried (on the same or the next host)
+	Retries uint64
+	// RateLimitedRetries is the number of rate limited (429) errors retried
+	RateLimitedRetries uint64
+	// Rethrows is the number of errors rethrown to the caller
+	Rethrows uint64
+	// BackOff is the cumulative time spent backing off
+	BackOff time.Duration
+}
+
+// Metrics returns a snapshot of the policy counters. It is safe to call concurrently with queries, e.g. from a metrics scrape
+func (crp *CosmosRetryPolicy) Metrics() Metrics {
+	return Metrics{
+		Retries:            atomic.LoadUint64(&crp.counters.retries),
+		RateLimitedRetries: atomic.LoadUint64(&crp.counters.rateLimitedRetries),
+		Rethrows:           atomic.LoadUint64(&crp.counters.rethrows),
+		BackOff:            crp.BlockedTime(),
+	}
+}
@@ -0,0 +1,51 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryCounters(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.FixedBackOffTimeMs = 1
+
+	errs := []error{
+		errors.New(rateLimitedErrMsg),
+		errors.New(rateLimitedErrMsgWithoutRetryAfterMs),
+		&gocql.RequestErrReadTimeout{},
+		errors.New("error: today is not your day!"),
+		errors.New("error: still not your day!"),
+	}
+	for _, err := range errs {
+		// an idempotent query, so that timeouts are retried
+		retryTypeOf(p, err)
+	}
+
+	assert.Equal(t, uint64(3), p.counters.retries)
+	assert.Equal(t, uint64(2), p.counters.rateLimitedRetries)
+	assert.Equal(t, uint64(2), p.counters.rethrows)
+}
+
+func TestMetricsSnapshot(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.FixedBackOffTimeMs = 3
+	assert.Equal(t, Metrics{}, p.Metrics())
+
+	errs := []error{
+		errors.New(rateLimitedErrMsg),
+		errors.New(rateLimitedErrMsgWithoutRetryAfterMs),
+		&gocql.RequestErrWriteTimeout{},
+		errors.New("error: today is not your day!"),
+	}
+	for _, err := range errs {
+		// an idempotent query, so that timeouts are retried
+		retryTypeOf(p, err)
+	}
+
+	expected := Metrics{Retries: 3, RateLimitedRetries: 2, Rethrows: 1, BackOff: 45 * time.Millisecond}
+	assert.Equal(t, expected, p.Metrics())
+}
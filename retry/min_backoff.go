@@ -0,0 +1,15 @@
+package retry
+
+import "time"
+
+// floorBackOff raises a back-off provided by the server (RetryAfterMs) below MinBackOffTimeMs to a random value between MinBackOffTimeMs and 1.5 times it, so that clients throttled together do not retry in lockstep. With NoJitter, it is raised to MinBackOffTimeMs exactly
+func (crp *CosmosRetryPolicy) floorBackOff(d time.Duration) time.Duration {
+	floor := time.Duration(crp.MinBackOffTimeMs) * time.Millisecond
+	if floor <= 0 || d >= floor {
+		return d
+	}
+	if crp.JitterMode == NoJitter {
+		return floor
+	}
+	return floor + time.Duration(crp.randInt(crp.MinBackOffTimeMs/2+1))*time.Millisecond
+}
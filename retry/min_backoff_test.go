@@ -0,0 +1,51 @@
+package retry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinBackOff(t *testing.T) {
+	type testCase struct {
+		name     string
+		min      int
+		mode     JitterMode
+		errMsg   string
+		expected time.Duration
+	}
+
+	tiny := strings.Replace(rateLimitedErrMsg, "RetryAfterMs=42", "RetryAfterMs=1", 1)
+	testCases := []testCase{
+		{"no floor by default", 0, EqualJitter, tiny, time.Millisecond},
+		{"RetryAfterMs raised to the floor, with jitter", 100, EqualJitter, tiny, 130 * time.Millisecond},
+		{"RetryAfterMs raised to the floor, without jitter", 100, NoJitter, tiny, 100 * time.Millisecond},
+		{"RetryAfterMs above the floor", 10, EqualJitter, rateLimitedErrMsg, 42 * time.Millisecond},
+		{"zero RetryAfterMs raised to the floor", 100, NoJitter, strings.Replace(rateLimitedErrMsg, "RetryAfterMs=42", "RetryAfterMs=0", 1), 100 * time.Millisecond},
+		{"fallback back-off not affected", 10000, NoJitter, rateLimitedErrMsgWithoutRetryAfterMs, 5 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5, WithMinBackOff(time.Duration(tc.min)*time.Millisecond))
+			p.JitterMode = tc.mode
+			p.randFunc = func(int) int { return 30 }
+			assert.Equal(te, tc.expected, retryAfter(te, p, tc.errMsg))
+		})
+	}
+}
+
+func TestMinBackOffJitterRange(t *testing.T) {
+	p := NewCosmosRetryPolicy(5, WithMinBackOff(100*time.Millisecond))
+	tiny := strings.Replace(rateLimitedErrMsg, "RetryAfterMs=42", "RetryAfterMs=1", 1)
+
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 200; i++ {
+		d := retryAfter(t, p, tiny)
+		assert.True(t, d >= 100*time.Millisecond && d <= 150*time.Millisecond, "back-off %v out of range", d)
+		seen[d] = true
+	}
+	assert.True(t, len(seen) > 1, "back-off was not jittered")
+}
@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// MockRetryPolicy is a RetryPolicy for tests of code which depends on one: every error is decided as per RetryType, with BackOff (which is reported by Decide but never slept), and recorded for the test to assert on. It is safe for concurrent use
+type MockRetryPolicy struct {
+	// Allow is returned by Attempt
+	Allow bool
+	// RetryType is the decision made for every error
+	RetryType gocql.RetryType
+	// BackOff is the back-off returned by Decide
+	BackOff time.Duration
+	// Kind is returned by ClassifyError
+	Kind ErrorKind
+
+	mu       sync.Mutex
+	attempts int
+	errs     []error
+	metrics  Metrics
+}
+
+var _ RetryPolicy = (*MockRetryPolicy)(nil)
+
+// NewMockRetryPolicy returns a MockRetryPolicy which allows every attempt and decides every error as per retryType
+func NewMockRetryPolicy(retryType gocql.RetryType) *MockRetryPolicy {
+	return &MockRetryPolicy{Allow: true, RetryType: retryType}
+}
+
+// Attempt records the attempt and returns Allow
+func (mp *MockRetryPolicy) Attempt(gocql.RetryableQuery) bool {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.attempts++
+	return mp.Allow
+}
+
+// GetRetryType records err and returns RetryType
+func (mp *MockRetryPolicy) GetRetryType(err error) gocql.RetryType {
+	retryType, _ := mp.Decide(err, 0, false)
+	return retryType
+}
+
+// Decide records err and returns RetryType and BackOff
+func (mp *MockRetryPolicy) Decide(err error, _ int, _ bool) (gocql.RetryType, time.Duration) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.errs = append(mp.errs, err)
+	switch mp.RetryType {
+	case gocql.Retry, gocql.RetryNextHost:
+		mp.metrics.Retries++
+	case gocql.Rethrow:
+		mp.metrics.Rethrows++
+	}
+	return mp.RetryType, mp.BackOff
+}
+
+// ClassifyError returns Kind
+func (mp *MockRetryPolicy) ClassifyError(error) ErrorKind {
+	return mp.Kind
+}
+
+// Metrics returns the number of decisions made so far, by RetryType
+func (mp *MockRetryPolicy) Metrics() Metrics {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return mp.metrics
+}
+
+// LastDecisionPath returns an empty string, as the mock has no decision paths
+func (mp *MockRetryPolicy) LastDecisionPath() string {
+	return ""
+}
+
+// LastRetryAfterMs returns 0, as the mock does not parse errors
+func (mp *MockRetryPolicy) LastRetryAfterMs() time.Duration {
+	return 0
+}
+
+// LastActivityID returns an empty string, as the mock does not parse errors
+func (mp *MockRetryPolicy) LastActivityID() string {
+	return ""
+}
+
+// Reset clears the recorded attempts, errors and metrics
+func (mp *MockRetryPolicy) Reset() {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.attempts = 0
+	mp.errs = nil
+	mp.metrics = Metrics{}
+}
+
+// Attempts returns the number of times Attempt was called
+func (mp *MockRetryPolicy) Attempts() int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return mp.attempts
+}
+
+// Errors returns the errors decided upon so far, in order
+func (mp *MockRetryPolicy) Errors() []error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return append([]error(nil), mp.errs...)
+}
@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// scheduler stands for application code depending on a RetryPolicy, which schedules retries itself
+type scheduler struct {
+	policy RetryPolicy
+}
+
+// next returns when to retry a query which failed with err, and false if it should not be retried
+func (s scheduler) next(err error, attempts int) (time.Duration, bool) {
+	retryType, backOff := s.policy.Decide(err, attempts, true)
+	return backOff, retryType == gocql.Retry || retryType == gocql.RetryNextHost
+}
+
+func TestMockRetryPolicyInjection(t *testing.T) {
+	mock := NewMockRetryPolicy(gocql.Retry)
+	mock.BackOff = time.Minute
+	s := scheduler{policy: mock}
+
+	err := errors.New("boom")
+	backOff, retry := s.next(err, 1)
+	assert.True(t, retry)
+	assert.Equal(t, time.Minute, backOff)
+	assert.Equal(t, []error{err}, mock.Errors())
+	assert.Equal(t, uint64(1), mock.Metrics().Retries)
+
+	mock.RetryType = gocql.Rethrow
+	_, retry = s.next(err, 2)
+	assert.False(t, retry)
+	assert.Equal(t, uint64(1), mock.Metrics().Rethrows)
+
+	mock.Reset()
+	assert.Empty(t, mock.Errors())
+	assert.Equal(t, Metrics{}, mock.Metrics())
+
+	// the real policy is injected the same way
+	s = scheduler{policy: NewCosmosRetryPolicy(3)}
+	backOff, retry = s.next(errors.New(rateLimitedErrMsg), 1)
+	assert.True(t, retry)
+	assert.Equal(t, 42*time.Millisecond, backOff)
+}
+
+func TestMockRetryPolicyAsGocqlPolicy(t *testing.T) {
+	mock := NewMockRetryPolicy(gocql.RetryNextHost)
+	cluster := gocql.NewCluster("localhost")
+	cluster.RetryPolicy = mock
+
+	assert.True(t, cluster.RetryPolicy.Attempt(MockRetryableQuery{}))
+	mock.Allow = false
+	assert.False(t, cluster.RetryPolicy.Attempt(MockRetryableQuery{}))
+	assert.Equal(t, gocql.RetryNextHost, cluster.RetryPolicy.GetRetryType(gocql.ErrTimeoutNoResponse))
+	assert.Equal(t, 2, mock.Attempts())
+	assert.Equal(t, []error{gocql.ErrTimeoutNoResponse}, mock.Errors())
+}
@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// nilContextQuery is a query whose Context returns nil, like some mocks and wrappers of gocql queries
+type nilContextQuery struct {
+	attempts int
+}
+
+func (q nilContextQuery) Attempts() int {
+	return q.attempts
+}
+
+func (q nilContextQuery) SetConsistency(gocql.Consistency) {}
+
+func (q nilContextQuery) GetConsistency() gocql.Consistency {
+	return gocql.Quorum
+}
+
+func (q nilContextQuery) Context() context.Context {
+	return nil
+}
+
+func (q nilContextQuery) IsIdempotent() bool {
+	return true
+}
+
+func TestNilQueryContext(t *testing.T) {
+	type testCase struct {
+		name      string
+		configure func(p *CosmosRetryPolicy)
+		err       error
+		expected  gocql.RetryType
+	}
+
+	testCases := []testCase{
+		{"rate limited error", func(p *CosmosRetryPolicy) {}, errors.New(rateLimitedErrMsg), gocql.Retry},
+		{"rate limited error without RetryAfterMs", func(p *CosmosRetryPolicy) {}, errors.New(rateLimitedErrMsgWithoutRetryAfterMs), gocql.Retry},
+		{"timeout", func(p *CosmosRetryPolicy) {}, &gocql.RequestErrWriteTimeout{}, gocql.Retry},
+		{"timeout backed off", func(p *CosmosRetryPolicy) { p.ImmediateRetries = 1 }, &gocql.RequestErrReadTimeout{}, gocql.Retry},
+		{"non retryable error", func(p *CosmosRetryPolicy) {}, errors.New("syntax error"), gocql.Rethrow},
+		{"absolute max sleep", func(p *CosmosRetryPolicy) { p.AbsoluteMaxSleepMs = 1 }, errors.New(rateLimitedErrMsg), gocql.Retry},
+		{"limiter", func(p *CosmosRetryPolicy) { p.Limiter = NewLimiter(1, 0) }, errors.New(rateLimitedErrMsg), gocql.Retry},
+		{"tracing and decision logging", func(p *CosmosRetryPolicy) {
+			p.EnableTracing(10)
+			p.LogDecisions = true
+			p.Logger = &capturingLogger{}
+		}, errors.New(rateLimitedErrMsg), gocql.Retry},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.FixedBackOffTimeMs = 1
+			tc.configure(p)
+
+			assert.NotPanics(te, func() {
+				for attempt := 1; attempt <= 3; attempt++ {
+					assert.True(te, p.Attempt(nilContextQuery{attempts: attempt}))
+					assert.Equal(te, tc.expected, p.GetRetryType(tc.err))
+				}
+				assert.False(te, p.Attempt(nilContextQuery{attempts: 4}))
+			})
+		})
+	}
+}
+
+func TestNilQueryContextBacksOff(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	var slept []time.Duration
+	p.sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+
+	assert.True(t, p.Attempt(nilContextQuery{attempts: 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	assert.Equal(t, []time.Duration{42 * time.Millisecond}, slept)
+}
+
+func TestNilQueryContextWrapThrottleExhausted(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	var exhausted *ThrottleExhaustedError
+	assert.NotPanics(t, func() {
+		assert.True(t, errors.As(p.WrapThrottleExhausted(nilContextQuery{attempts: 4}, errors.New(rateLimitedErrMsg)), &exhausted))
+	})
+	assert.Equal(t, 4, exhausted.Attempts)
+}
+
+func TestNilContextHelpers(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.AbsoluteMaxSleepMs = 1
+
+	assert.NotPanics(t, func() {
+		assert.True(t, wait(nil, time.Millisecond))
+		d, ok := untilDeadline(nil, time.Second)
+		assert.True(t, ok)
+		assert.Equal(t, time.Second, d)
+		assert.Equal(t, time.Millisecond, p.capWithoutDeadline(nil, time.Second))
+		_, scoped := scopedExecution(nil)
+		assert.False(t, scoped)
+		assert.False(t, IsHealthCheck(nil))
+		assert.Equal(t, "", RequestID(nil))
+		p.ObserveQuery(nil, gocql.ObservedQuery{})
+	})
+}
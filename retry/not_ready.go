@@ -0,0 +1,24 @@
+package retry
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const defaultNotReadyBackOffTimeMs = 10000
+
+// isNotReady reports whether errMsg indicates the keyspace or table is still being created, as per NotReadyMarker
+func (crp *CosmosRetryPolicy) isNotReady(errMsg string) bool {
+	return crp.NotReadyMarker != "" && strings.Contains(strings.ToLower(errMsg), strings.ToLower(crp.NotReadyMarker))
+}
+
+// retryNotReady backs off for NotReadyBackOffTimeMs before retrying a query against a keyspace or table which is not ready yet
+func (crp *CosmosRetryPolicy) retryNotReady(ex *execution) gocql.RetryType {
+	if !crp.backOff(ex, time.Duration(crp.NotReadyBackOffTimeMs)*time.Millisecond) {
+		return gocql.Rethrow
+	}
+	crp.recordPath(ex, DecisionPathNotReady)
+	return gocql.Retry
+}
@@ -0,0 +1,34 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+const notReadyErrMsg = "unconfigured table orders"
+
+func TestNotReadyRetriedWithLongerBackOff(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	p.FixedBackOffTimeMs = 1
+	p.NotReadyMarker = "Unconfigured Table"
+	p.NotReadyBackOffTimeMs = 20
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		if !p.Attempt(attemptsQuery{attempts: attempt}) {
+			assert.Equal(t, 3, attempt, "should give up once retries are exhausted")
+			break
+		}
+		assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(notReadyErrMsg)))
+		assert.Equal(t, DecisionPathNotReady, p.LastDecisionPath())
+	}
+	assert.Equal(t, 2*20*time.Millisecond, p.BlockedTime())
+}
+
+func TestNotReadyDetectionDisabledByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	assert.Equal(t, gocql.Rethrow, retryTypeOf(p, errors.New(notReadyErrMsg)))
+}
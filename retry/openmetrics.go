@@ -0,0 +1,66 @@
+package retry
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const metricsNamespace = "cosmos_retry"
+
+// WriteOpenMetrics writes the policy metrics to w in the OpenMetrics text exposition format (which Prometheus can scrape as well)
+func (crp *CosmosRetryPolicy) WriteOpenMetrics(w io.Writer) error {
+	ew := &errWriter{w: w}
+	m := crp.Metrics()
+
+	ew.metric("retries", "counter", "Number of retries", float64(m.Retries))
+	ew.metric("rate_limited_retries", "counter", "Number of retries of rate limited (429) errors", float64(m.RateLimitedRetries))
+	ew.metric("rethrows", "counter", "Number of errors rethrown to the caller", float64(m.Rethrows))
+	ew.metric("backoff_seconds", "counter", "Cumulative time spent backing off", m.BackOff.Seconds())
+	ew.metric("blocked_workers", "gauge", "Number of workers currently blocked in a back-off", float64(crp.BlockedWorkers()))
+	bounds, counts, sum := crp.retryAfterSnapshot()
+	ew.histogram("retry_after_seconds", "RetryAfterMs of rate limited (429) errors, as sent by the server", bounds, counts, sum)
+	ew.printf("# EOF\n")
+
+	return ew.err
+}
+
+// errWriter writes to w until the first error, which is retained
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
+
+func (ew *errWriter) metric(name, metricType, help string, value float64) {
+	family := metricsNamespace + "_" + name
+	ew.printf("# TYPE %s %s\n", family, metricType)
+	ew.printf("# HELP %s %s\n", family, help)
+	sample := family
+	if metricType == "counter" {
+		sample += "_total"
+	}
+	ew.printf("%s %v\n", sample, value)
+}
+
+// histogram writes the histogram family name, whose (non-cumulative) counts are per bucket of the given upper bounds, plus one for values exceeding all of them
+func (ew *errWriter) histogram(name, help string, bounds []time.Duration, counts []uint64, sum time.Duration) {
+	family := metricsNamespace + "_" + name
+	ew.printf("# TYPE %s histogram\n", family)
+	ew.printf("# HELP %s %s\n", family, help)
+	var cumulative uint64
+	for i, bound := range bounds {
+		cumulative += counts[i]
+		ew.printf("%s_bucket{le=\"%v\"} %d\n", family, bound.Seconds(), cumulative)
+	}
+	cumulative += counts[len(bounds)]
+	ew.printf("%s_bucket{le=\"+Inf\"} %d\n", family, cumulative)
+	ew.printf("%s_count %d\n", family, cumulative)
+	ew.printf("%s_sum %v\n", family, sum.Seconds())
+}
@@ -0,0 +1,108 @@
+package retry
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	typeLine   = regexp.MustCompile(`^# TYPE ([a-zA-Z_:][a-zA-Z0-9_:]*) (counter|gauge|histogram)$`)
+	helpLine   = regexp.MustCompile(`^# HELP ([a-zA-Z_:][a-zA-Z0-9_:]*) .+$`)
+	sampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{[a-zA-Z_][a-zA-Z0-9_]*="[^"]*"(,[a-zA-Z_][a-zA-Z0-9_]*="[^"]*")*\})? ([-+]?[0-9.eE+-]+|\+Inf|NaN)$`)
+)
+
+// parseExposition validates text in the exposition format and returns the sample values by name
+func parseExposition(t *testing.T, text string) map[string]string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	assert.Equal(t, "# EOF", lines[len(lines)-1], "exposition must end with # EOF")
+
+	samples := map[string]string{}
+	families := map[string]bool{}
+	for _, line := range lines[:len(lines)-1] {
+		switch {
+		case typeLine.MatchString(line):
+			families[typeLine.FindStringSubmatch(line)[1]] = true
+		case helpLine.MatchString(line):
+		case sampleLine.MatchString(line):
+			m := sampleLine.FindStringSubmatch(line)
+			name := m[1]
+			known := false
+			for family := range families {
+				if strings.HasPrefix(name, family) {
+					known = true
+				}
+			}
+			assert.True(t, known, "sample %q has no TYPE", name)
+			samples[name+m[2]] = m[4]
+		default:
+			t.Errorf("invalid exposition line %q", line)
+		}
+	}
+	return samples
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	// an idempotent query, so that timeouts are retried
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	retryTypeOf(p, &gocql.RequestErrReadTimeout{})
+	retryTypeOf(p, errors.New("error: today is not your day!"))
+	retryTypeOf(p, errors.New(rateLimitedErrMsgWithRetryAfter("3")))
+
+	var buf bytes.Buffer
+	assert.NoError(t, p.WriteOpenMetrics(&buf))
+
+	samples := parseExposition(t, buf.String())
+	assert.Equal(t, "3", samples["cosmos_retry_retries_total"])
+	assert.Equal(t, "2", samples["cosmos_retry_rate_limited_retries_total"])
+	assert.Equal(t, "1", samples["cosmos_retry_rethrows_total"])
+	assert.Equal(t, "0.045", samples["cosmos_retry_backoff_seconds_total"])
+	assert.Equal(t, "0", samples["cosmos_retry_blocked_workers"])
+
+	// buckets are cumulative, as per DefaultRetryAfterBuckets
+	assert.Equal(t, "1", samples[`cosmos_retry_retry_after_seconds_bucket{le="0.01"}`])
+	assert.Equal(t, "2", samples[`cosmos_retry_retry_after_seconds_bucket{le="0.05"}`])
+	assert.Equal(t, "2", samples[`cosmos_retry_retry_after_seconds_bucket{le="5"}`])
+	assert.Equal(t, "2", samples[`cosmos_retry_retry_after_seconds_bucket{le="+Inf"}`])
+	assert.Equal(t, "2", samples["cosmos_retry_retry_after_seconds_count"])
+	assert.Equal(t, "0.045", samples["cosmos_retry_retry_after_seconds_sum"])
+}
+
+func TestWriteOpenMetricsRetryAfterHistogram(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.SleepDisabled = true
+	p.RetryAfterBuckets = []time.Duration{5 * time.Millisecond, time.Second}
+	for _, v := range []string{"1", "5", "6", "1000", "1500"} {
+		retryTypeOf(p, errors.New(rateLimitedErrMsgWithRetryAfter(v)))
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, p.WriteOpenMetrics(&buf))
+
+	assert.Contains(t, buf.String(), `# TYPE cosmos_retry_retry_after_seconds histogram
+# HELP cosmos_retry_retry_after_seconds RetryAfterMs of rate limited (429) errors, as sent by the server
+cosmos_retry_retry_after_seconds_bucket{le="0.005"} 2
+cosmos_retry_retry_after_seconds_bucket{le="1"} 4
+cosmos_retry_retry_after_seconds_bucket{le="+Inf"} 5
+cosmos_retry_retry_after_seconds_count 5
+cosmos_retry_retry_after_seconds_sum 2.512
+`)
+	parseExposition(t, buf.String())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestWriteOpenMetricsWriteError(t *testing.T) {
+	assert.Error(t, NewCosmosRetryPolicy(5).WriteOpenMetrics(failingWriter{}))
+}
@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option configures a CosmosRetryPolicy, returning an error if its input is invalid. See NewCosmosRetryPolicy
+type Option func(crp *CosmosRetryPolicy) error
+
+// toMs converts d to whole milliseconds (rounded down)
+func toMs(d time.Duration) int {
+	return int(d / time.Millisecond)
+}
+
+// durationOption returns an Option which sets the field (in ms) returned by field from d, rejecting negative durations
+func durationOption(name string, d time.Duration, field func(crp *CosmosRetryPolicy) *int) Option {
+	return func(crp *CosmosRetryPolicy) error {
+		if d < 0 {
+			return fmt.Errorf("invalid %s %v: must not be negative", name, d)
+		}
+		*field(crp) = toMs(d)
+		return nil
+	}
+}
+
+// WithMaxRetryCount sets MaxRetryCount, which must be -1 (infinite retries) or more
+func WithMaxRetryCount(n int) Option {
+	return func(crp *CosmosRetryPolicy) error {
+		if n < -1 {
+			return fmt.Errorf("invalid max retry count %d: must be -1 (infinite) or more", n)
+		}
+		crp.MaxRetryCount = n
+		return nil
+	}
+}
+
+// WithFixedBackOff sets FixedBackOffTimeMs from a duration
+func WithFixedBackOff(d time.Duration) Option {
+	return durationOption("fixed back-off", d, func(crp *CosmosRetryPolicy) *int { return &crp.FixedBackOffTimeMs })
+}
+
+// WithGrowingBackOff sets GrowingBackOffTimeMs from a duration
+func WithGrowingBackOff(d time.Duration) Option {
+	return durationOption("growing back-off", d, func(crp *CosmosRetryPolicy) *int { return &crp.GrowingBackOffTimeMs })
+}
+
+// WithMaxBackOff sets MaxBackOffTimeMs from a duration. 0 means no cap
+func WithMaxBackOff(d time.Duration) Option {
+	return durationOption("max back-off", d, func(crp *CosmosRetryPolicy) *int { return &crp.MaxBackOffTimeMs })
+}
+
+// WithMinBackOff sets MinBackOffTimeMs from a duration
+func WithMinBackOff(d time.Duration) Option {
+	return durationOption("min back-off", d, func(crp *CosmosRetryPolicy) *int { return &crp.MinBackOffTimeMs })
+}
+
+// WithDirectBackOff sets DirectBackOffTimeMs from a duration
+func WithDirectBackOff(d time.Duration) Option {
+	return durationOption("direct back-off", d, func(crp *CosmosRetryPolicy) *int { return &crp.DirectBackOffTimeMs })
+}
+
+// WithSecondaryRegionBackOff sets SecondaryRegionBackOffMs from a duration
+func WithSecondaryRegionBackOff(d time.Duration) Option {
+	return durationOption("secondary region back-off", d, func(crp *CosmosRetryPolicy) *int { return &crp.SecondaryRegionBackOffMs })
+}
+
+// WithMaxElapsedTime sets MaxElapsedTimeMs from a duration
+func WithMaxElapsedTime(d time.Duration) Option {
+	return durationOption("max elapsed time", d, func(crp *CosmosRetryPolicy) *int { return &crp.MaxElapsedTimeMs })
+}
+
+// WithMaxBlockedTime sets MaxBlockedTimeMs from a duration
+func WithMaxBlockedTime(d time.Duration) Option {
+	return durationOption("max blocked time", d, func(crp *CosmosRetryPolicy) *int { return &crp.MaxBlockedTimeMs })
+}
@@ -0,0 +1,152 @@
+package retry
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationOptionsMatchIntFields(t *testing.T) {
+	fromDurations := NewCosmosRetryPolicy(5,
+		WithFixedBackOff(2*time.Second),
+		WithGrowingBackOff(1500*time.Millisecond),
+		WithMaxBackOff(30*time.Second),
+		WithDirectBackOff(750*time.Millisecond),
+		WithMaxBlockedTime(time.Minute))
+
+	fromInts := NewCosmosRetryPolicy(5)
+	fromInts.FixedBackOffTimeMs = 2000
+	fromInts.GrowingBackOffTimeMs = 1500
+	fromInts.MaxBackOffTimeMs = 30000
+	fromInts.DirectBackOffTimeMs = 750
+	fromInts.MaxBlockedTimeMs = 60000
+
+	assert.Equal(t, fromInts.FixedBackOffTimeMs, fromDurations.FixedBackOffTimeMs)
+	assert.Equal(t, fromInts.GrowingBackOffTimeMs, fromDurations.GrowingBackOffTimeMs)
+	assert.Equal(t, fromInts.MaxBackOffTimeMs, fromDurations.MaxBackOffTimeMs)
+	assert.Equal(t, fromInts.DirectBackOffTimeMs, fromDurations.DirectBackOffTimeMs)
+	assert.Equal(t, fromInts.MaxBlockedTimeMs, fromDurations.MaxBlockedTimeMs)
+	fromInts.JitterMode, fromDurations.JitterMode = NoJitter, NoJitter
+	assert.Equal(t, retryAfter(t, fromInts, rateLimitedErrMsgWithoutRetryAfterMs), retryAfter(t, fromDurations, rateLimitedErrMsgWithoutRetryAfterMs))
+}
+
+func TestDurationOptionsTruncateToMilliseconds(t *testing.T) {
+	p := NewCosmosRetryPolicy(5, WithFixedBackOff(1999*time.Microsecond))
+	assert.Equal(t, 1, p.FixedBackOffTimeMs)
+}
+
+func TestOptionsAppliedInOrder(t *testing.T) {
+	p := NewCosmosRetryPolicy(5, WithFixedBackOff(time.Second), WithFixedBackOff(3*time.Second))
+	assert.Equal(t, 3000, p.FixedBackOffTimeMs)
+}
+
+func TestOptionsKeepDefaults(t *testing.T) {
+	p := NewCosmosRetryPolicy(5, WithFixedBackOff(time.Second))
+	assert.Equal(t, defaultGrowingBackOffTimeMs, p.GrowingBackOffTimeMs)
+}
+
+// silenceLog discards the warnings NewCosmosRetryPolicy logs using the standard logger until the test ends
+func silenceLog(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	t.Cleanup(func() { log.SetOutput(out) })
+}
+
+func TestBuildRejectsInvalidOptions(t *testing.T) {
+	silenceLog(t)
+	type testCase struct {
+		name     string
+		opt      Option
+		expected string
+	}
+
+	testCases := []testCase{
+		{"negative fixed back-off", WithFixedBackOff(-time.Second), "invalid fixed back-off -1s: must not be negative"},
+		{"negative growing back-off", WithGrowingBackOff(-time.Millisecond), "invalid growing back-off -1ms: must not be negative"},
+		{"negative max back-off", WithMaxBackOff(-time.Second), "invalid max back-off -1s: must not be negative"},
+		{"negative direct back-off", WithDirectBackOff(-time.Second), "invalid direct back-off -1s: must not be negative"},
+		{"negative secondary region back-off", WithSecondaryRegionBackOff(-time.Second), "invalid secondary region back-off -1s: must not be negative"},
+		{"negative max blocked time", WithMaxBlockedTime(-time.Second), "invalid max blocked time -1s: must not be negative"},
+		{"max retry count below -1", WithMaxRetryCount(-2), "invalid max retry count -2: must be -1 (infinite) or more"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p, err := BuildCosmosRetryPolicy(5, WithFixedBackOff(time.Second), tc.opt)
+			assert.Nil(te, p)
+			if assert.Error(te, err) {
+				assert.Equal(te, tc.expected, err.Error())
+			}
+
+			// the single-argument constructor ignores invalid options instead
+			var tolerant *CosmosRetryPolicy
+			assert.NotPanics(te, func() { tolerant = NewCosmosRetryPolicy(5, WithFixedBackOff(time.Second), tc.opt) })
+			assert.Equal(te, newCosmosRetryPolicy(5).WithOverrides(WithFixedBackOff(time.Second)).MarshalConfig(), tolerant.MarshalConfig())
+		})
+	}
+}
+
+func TestBuildAppliesOptionsInOrder(t *testing.T) {
+	p, err := BuildCosmosRetryPolicy(5, WithGrowingBackOff(time.Second), WithMaxBackOff(0), WithGrowingBackOff(2*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 2000, p.GrowingBackOffTimeMs)
+	assert.Equal(t, 0, p.MaxBackOffTimeMs)
+	assert.Equal(t, defaultFixedBackOffTimeMs, p.FixedBackOffTimeMs)
+}
+
+func TestSingleArgumentConstructorUsesDefaults(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	assert.Equal(t, 5, p.MaxRetryCount)
+	assert.Equal(t, defaultFixedBackOffTimeMs, p.FixedBackOffTimeMs)
+	assert.Equal(t, defaultGrowingBackOffTimeMs, p.GrowingBackOffTimeMs)
+	assert.Equal(t, defaultMaxBackOffTimeMs, p.MaxBackOffTimeMs)
+}
+
+func TestMaxRetryCountOption(t *testing.T) {
+	type testCase struct {
+		name     string
+		policy   *CosmosRetryPolicy
+		expected int
+	}
+
+	testCases := []testCase{
+		{"positional argument", NewCosmosRetryPolicy(5), 5},
+		{"option overriding the positional argument", NewCosmosRetryPolicy(5, WithMaxRetryCount(2)), 2},
+		{"options alone", NewCosmosRetryPolicyWithOptions(WithMaxRetryCount(5)), 5},
+		{"infinite retries", NewCosmosRetryPolicyWithOptions(WithMaxRetryCount(-1)), -1},
+		{"no retries", NewCosmosRetryPolicyWithOptions(WithMaxRetryCount(0)), 0},
+		{"default", NewCosmosRetryPolicyWithOptions(), defaultMaxRetryCount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, tc.policy.MaxRetryCount)
+		})
+	}
+}
+
+func TestOptionsOnlyConstructorUsesDefaults(t *testing.T) {
+	silenceLog(t)
+	p := NewCosmosRetryPolicyWithOptions(WithFixedBackOff(time.Second))
+	assert.Equal(t, 3, p.MaxRetryCount)
+	assert.Equal(t, 1000, p.FixedBackOffTimeMs)
+	assert.Equal(t, defaultGrowingBackOffTimeMs, p.GrowingBackOffTimeMs)
+	assert.Equal(t, defaultMaxBackOffTimeMs, p.MaxBackOffTimeMs)
+	assert.Equal(t, defaultMaxRetryCount, NewCosmosRetryPolicyWithOptions(WithMaxRetryCount(-2)).MaxRetryCount)
+}
+
+func TestNewRecommendedCosmosRetryPolicy(t *testing.T) {
+	p := NewRecommendedCosmosRetryPolicy()
+	assert.Equal(t, 3, p.MaxRetryCount)
+	assert.Equal(t, 5000, p.FixedBackOffTimeMs)
+	assert.Equal(t, 1000, p.GrowingBackOffTimeMs)
+	assert.Equal(t, 30000, p.MaxBackOffTimeMs)
+	assert.Empty(t, p.Lint())
+
+	customized := NewRecommendedCosmosRetryPolicy(WithFixedBackOff(time.Second))
+	assert.Equal(t, 3, customized.MaxRetryCount)
+	assert.Equal(t, 1000, customized.FixedBackOffTimeMs)
+}
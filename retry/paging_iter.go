@@ -0,0 +1,87 @@
+package retry
+
+import "github.com/gocql/gocql"
+
+// pageIter is the subset of *gocql.Iter used by RetryingIter
+type pageIter interface {
+	Scan(dest ...interface{}) bool
+	Close() error
+	PageState() []byte
+}
+
+// RetryingIter iterates over the rows of a query one page at a time. If fetching a page fails (e.g. due to rate limiting), it backs off and retries as per the policy, resuming from the paging state of that page. Rows which have already been returned are never returned again
+//
+//	iter := retry.NewRetryingIter(session.Query(stmt).PageSize(100), policy)
+//	for iter.Scan(&id, &amount) {
+//		...
+//	}
+//	err := iter.Close()
+type RetryingIter struct {
+	crp       *CosmosRetryPolicy
+	query     gocql.RetryableQuery
+	fetch     func(pageState []byte) pageIter
+	iter      pageIter
+	pageState []byte
+	ex        execution
+	err       error
+	done      bool
+}
+
+// NewRetryingIter returns a RetryingIter over the rows of q, retried as per crp. The query is paged manually, see gocql.Query.PageState. Pages are retried as per the idempotency of q, and no longer once its context is done
+func NewRetryingIter(q *gocql.Query, crp *CosmosRetryPolicy) *RetryingIter {
+	return newRetryingIter(crp, q, func(pageState []byte) pageIter {
+		return q.PageState(pageState).Iter()
+	})
+}
+
+func newRetryingIter(crp *CosmosRetryPolicy, q gocql.RetryableQuery, fetch func(pageState []byte) pageIter) *RetryingIter {
+	ri := &RetryingIter{crp: crp, query: q, fetch: fetch}
+	ri.ex = ri.pageExecution()
+	return ri
+}
+
+// pageExecution returns the execution of fetching a page of the query, which is idempotent as the query is, and whose back-offs are cut short once the context of the query is done
+func (ri *RetryingIter) pageExecution() execution {
+	return execution{idempotent: isIdempotent(ri.query), ctx: queryContext(ri.query)}
+}
+
+// Scan copies the columns of the next row into dest, fetching (and retrying) pages as needed. It returns false once all rows have been read or the policy gave up on a page. See Close for the error, if any
+func (ri *RetryingIter) Scan(dest ...interface{}) bool {
+	for !ri.done {
+		if ri.iter == nil {
+			ri.iter = ri.fetch(ri.pageState)
+		}
+		if ri.iter.Scan(dest...) {
+			return true
+		}
+
+		err := ri.iter.Close()
+		if err == nil {
+			// the page has been read completely, move on to the next one (if any)
+			ri.pageState = ri.iter.PageState()
+			ri.iter = nil
+			ri.ex = ri.pageExecution()
+			ri.done = len(ri.pageState) == 0
+			continue
+		}
+
+		// the page is fetched again from the same paging state
+		ri.iter = nil
+		ri.ex.attempts++
+		if retryType := ri.crp.decide(&ri.ex, err); retryType != gocql.Retry && retryType != gocql.RetryNextHost {
+			ri.err = err
+			ri.done = true
+		}
+	}
+	return false
+}
+
+// Close closes the iterator and returns the error of the page the policy gave up on, if any
+func (ri *RetryingIter) Close() error {
+	if ri.iter != nil {
+		ri.iter.Close()
+		ri.iter = nil
+	}
+	ri.done = true
+	return ri.err
+}
@@ -0,0 +1,146 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePage is a pageIter over a single page of int rows
+type fakePage struct {
+	rows []int
+	next []byte
+	err  error
+}
+
+func (fp *fakePage) Scan(dest ...interface{}) bool {
+	if fp.err != nil || len(fp.rows) == 0 {
+		return false
+	}
+	*dest[0].(*int) = fp.rows[0]
+	fp.rows = fp.rows[1:]
+	return true
+}
+
+func (fp *fakePage) Close() error      { return fp.err }
+func (fp *fakePage) PageState() []byte { return fp.next }
+
+// fakePager serves three pages, failing to fetch the pages listed in failures the given number of times, with err (or a rate limited error if nil)
+type fakePager struct {
+	failures map[string]int
+	err      error
+	fetches  []string
+}
+
+func (fp *fakePager) fetch(pageState []byte) pageIter {
+	state := string(pageState)
+	fp.fetches = append(fp.fetches, state)
+	if fp.failures[state] > 0 {
+		fp.failures[state]--
+		if fp.err != nil {
+			return &fakePage{err: fp.err}
+		}
+		return &fakePage{err: errors.New(rateLimitedErrMsg)}
+	}
+	switch state {
+	case "":
+		return &fakePage{rows: []int{1, 2}, next: []byte("p2")}
+	case "p2":
+		return &fakePage{rows: []int{3, 4}, next: []byte("p3")}
+	default:
+		return &fakePage{rows: []int{5}}
+	}
+}
+
+func TestRetryingIterResumesAfterThrottledPage(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	pager := &fakePager{failures: map[string]int{"p2": 2}}
+	iter := newRetryingIter(p, attemptsQuery{}, pager.fetch)
+
+	var rows []int
+	var row int
+	for iter.Scan(&row) {
+		rows = append(rows, row)
+	}
+
+	assert.NoError(t, iter.Close())
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, rows, "rows should neither be skipped nor duplicated")
+	assert.Equal(t, []string{"", "p2", "p2", "p2", "p3"}, pager.fetches, "throttled page should be fetched again from its paging state")
+	assert.Equal(t, 2*42*time.Millisecond, p.BlockedTime())
+}
+
+func TestRetryingIterGivesUp(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	pager := &fakePager{failures: map[string]int{"p2": 10}}
+	iter := newRetryingIter(p, attemptsQuery{}, pager.fetch)
+
+	var rows []int
+	var row int
+	for iter.Scan(&row) {
+		rows = append(rows, row)
+	}
+
+	assert.Equal(t, []int{1, 2}, rows)
+	assert.EqualError(t, iter.Close(), rateLimitedErrMsg)
+	assert.Equal(t, []string{"", "p2", "p2", "p2"}, pager.fetches)
+}
+
+func TestRetryingIterPageReadTimeout(t *testing.T) {
+	type testCase struct {
+		name            string
+		idempotent      bool
+		expectedRows    []int
+		expectedErr     error
+		expectedFetches []string
+	}
+
+	readTimeout := &gocql.RequestErrReadTimeout{}
+	testCases := []testCase{
+		{"idempotent query is retried", true, []int{1, 2, 3, 4, 5}, nil, []string{"", "p2", "p2", "p3"}},
+		{"query which is not idempotent is not", false, []int{1, 2}, readTimeout, []string{"", "p2"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			pager := &fakePager{failures: map[string]int{"p2": 1}, err: readTimeout}
+			iter := newRetryingIter(p, idempotentQuery{MockRetryableQuery{}, tc.idempotent}, pager.fetch)
+
+			var rows []int
+			var row int
+			for iter.Scan(&row) {
+				rows = append(rows, row)
+			}
+
+			assert.Equal(te, tc.expectedRows, rows)
+			assert.Equal(te, tc.expectedErr, iter.Close())
+			assert.Equal(te, tc.expectedFetches, pager.fetches)
+		})
+	}
+}
+
+func TestRetryingIterCancelledContext(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pager := &fakePager{failures: map[string]int{"p2": 1}}
+	iter := newRetryingIter(p, attemptsQuery{MockRetryableQuery{ctx: ctx}, 0}, pager.fetch)
+
+	var rows []int
+	var row int
+	for iter.Scan(&row) {
+		rows = append(rows, row)
+		// the caller gives up on the query while reading the first page
+		cancel()
+	}
+
+	assert.Equal(t, []int{1, 2}, rows)
+	assert.EqualError(t, iter.Close(), rateLimitedErrMsg)
+	assert.Equal(t, []string{"", "p2"}, pager.fetches, "the throttled page should not be fetched again")
+	assert.Equal(t, DecisionPathContextDone, p.LastDecisionPath())
+	assert.True(t, p.BlockedTime() < 42*time.Millisecond, "the back-off should have been cut short")
+}
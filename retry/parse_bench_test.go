@@ -0,0 +1,208 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Benchmark results (amd64) of the rate limited error parse path, before and after scanning the message in place instead of splitting it:
+//
+//	BenchmarkGetRetryAfterMs                     before: 150 ns/op  96 B/op  2 allocs/op   after: 51 ns/op  0 B/op  0 allocs/op
+//	BenchmarkGetRetryAfterMsWithoutRetryAfterMs  before: 210 ns/op  96 B/op  3 allocs/op   after: 104 ns/op  16 B/op  1 allocs/op (recording the decision path)
+//
+// The default classification rules, before and after compiling their pattern once rather than on every DefaultClassificationRules call:
+//
+//	BenchmarkDefaultClassificationRules          before: 4549 ns/op  3808 B/op  25 allocs/op  after: 28 ns/op  32 B/op  1 allocs/op (the returned slice)
+//
+// and of errors other than rate limiting, before and after skipping the positions which cannot match in the case-insensitive lookup of the rate limited hints:
+//
+//	BenchmarkGetRetryAfterMsNotRateLimited       before: 1231 ns/op  0 B/op  0 allocs/op   after: 547 ns/op  0 B/op  0 allocs/op
+
+// notRateLimitedErrMsg is a typical error other than rate limiting, which is parsed as well before being rethrown
+const notRateLimitedErrMsg = "Error: line 1:7 no viable alternative at input 'FORM' (SELECT * [FORM]...); Additional details='Response status code does not indicate success: BadRequest (400)'"
+
+func BenchmarkGetRetryAfterMs(b *testing.B) {
+	p := NewCosmosRetryPolicy(3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.getRetryAfterMs(&p.exec, rateLimitedErrMsg)
+	}
+}
+
+func BenchmarkGetRetryAfterMsWithoutRetryAfterMs(b *testing.B) {
+	p := NewCosmosRetryPolicy(3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.getRetryAfterMs(&p.exec, rateLimitedErrMsgWithoutRetryAfterMs)
+	}
+}
+
+func BenchmarkGetRetryAfterMsDirectMode(b *testing.B) {
+	p := NewCosmosRetryPolicy(3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.getRetryAfterMs(&p.exec, directRateLimitedErrMsgWithRetryAfterMs)
+	}
+}
+
+func BenchmarkGetRetryAfterMsNotRateLimited(b *testing.B) {
+	p := NewCosmosRetryPolicy(3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.getRetryAfterMs(&p.exec, notRateLimitedErrMsg)
+	}
+}
+
+func BenchmarkClassifyError(b *testing.B) {
+	p := NewCosmosRetryPolicy(3)
+	err := errors.New(rateLimitedErrMsg)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.ClassifyError(err)
+	}
+}
+
+func TestParsePathAllocations(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { parseRetryAfter(rateLimitedErrMsg) }))
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { parseRetryAfter(rateLimitedErrMsgWithoutRetryAfterMs) }))
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { parseRetryAfter(directRateLimitedErrMsgWithRetryAfterMs) }))
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { parseRetryAfter(notRateLimitedErrMsg) }))
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { p.getRetryAfterMs(&p.exec, directRateLimitedErrMsgWithRetryAfterMs) }))
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { p.getRetryAfterMs(&p.exec, rateLimitedErrMsg) }))
+	assert.True(t, testing.AllocsPerRun(100, func() { p.getRetryAfterMs(&p.exec, rateLimitedErrMsgWithoutRetryAfterMs) }) <= 1)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	type testCase struct {
+		name     string
+		errMsg   string
+		expected time.Duration
+		ok       bool
+	}
+
+	testCases := []testCase{
+		{"gateway error with RetryAfterMs", rateLimitedErrMsg, 42 * time.Millisecond, true},
+		{"gateway error without RetryAfterMs", rateLimitedErrMsgWithoutRetryAfterMs, 0, false},
+		{"RetryAfterMs is the last field", "Request rate is large: ActivityID=abc, RetryAfterMs=7", 7 * time.Millisecond, true},
+		{"invalid RetryAfterMs", "Request rate is large: ActivityID=abc, RetryAfterMs=soon, x", 0, false},
+		{"negative RetryAfterMs", "Request rate is large: ActivityID=abc, RetryAfterMs=-5, x", 0, false},
+		{"no fields", "TooManyRequests (429)", 0, false},
+		{"field without value", "a, RetryAfterMs, b", 0, false},
+		{"truncated after key", "Request rate is large: ActivityID=abc, RetryAfterMs=", 0, false},
+		{"truncated within key", "Request rate is large: ActivityID=abc, RetryAft", 0, false},
+		{"truncated value", "Request rate is large: ActivityID=abc, RetryAfterMs=30", 30 * time.Millisecond, true},
+		{"reordered fields", "Request rate is large: RetryAfterMs=15, ActivityID=abc, Additional details='TooManyRequests (429)'", 15 * time.Millisecond, true},
+		{"missing ActivityID", "Request rate is large: RetryAfterMs=25, Additional details='TooManyRequests (429)'", 25 * time.Millisecond, true},
+		{"missing ActivityID and RetryAfterMs", "Request rate is large, Additional details='TooManyRequests (429)'", 0, false},
+		{"trailing comma", "Request rate is large: ActivityID=abc, RetryAfterMs=9,", 9 * time.Millisecond, true},
+		{"trailing commas only", "TooManyRequests (429),,", 0, false},
+		{"spaces around value", "Request rate is large: ActivityID=abc, RetryAfterMs = 11 , x", 11 * time.Millisecond, true},
+		{"key as part of another key", "Request rate is large: ActivityID=abc, XRetryAfterMs=5, RetryAfterMs=6", 6 * time.Millisecond, true},
+		{"leading JSON body", `{"Errors": ["Request rate is large"]}, ActivityID=abc, RetryAfterMs=120, TooManyRequests (429)`, 120 * time.Millisecond, true},
+		{"JSON field", `{"code": "TooManyRequests", "RetryAfterMs": "250"}`, 250 * time.Millisecond, true},
+		{"unquoted JSON field value", `{"code": "TooManyRequests", "RetryAfterMs":17}`, 17 * time.Millisecond, true},
+		{"JSON field with equals sign", `{"RetryAfterMs"=17}`, 17 * time.Millisecond, true},
+		{"JSON field without closing quote", `{"RetryAfterMs: 17}`, 0, false},
+		{"JSON field as part of another key", `{"XRetryAfterMs": 5, "RetryAfterMs": 6}`, 6 * time.Millisecond, true},
+		{"colon outside of JSON", "Request rate is large: RetryAfterMs: 5, x", 0, false},
+		{"invalid JSON field", `{"RetryAfterMs": "soon"}`, 0, false},
+		{"space after equals sign", "Request rate is large: ActivityID=abc, RetryAfterMs= 42, x", 42 * time.Millisecond, true},
+		{"decimal RetryAfterMs", "Request rate is large: ActivityID=abc, RetryAfterMs=42.0, x", 42 * time.Millisecond, true},
+		{"decimal RetryAfterMs rounded", "Request rate is large: ActivityID=abc, RetryAfterMs= 42.6 , x", 43 * time.Millisecond, true},
+		{"decimal RetryAfterMs without integer part", "Request rate is large: ActivityID=abc, RetryAfterMs=.4, x", 0, true},
+		{"RetryAfterMs with unit", "Request rate is large: ActivityID=abc, RetryAfterMs=42ms, x", 0, false},
+		{"RetryAfterMs with inner space", "Request rate is large: ActivityID=abc, RetryAfterMs=4 2, x", 0, false},
+		{"RetryAfterMs with several decimal points", "Request rate is large: ActivityID=abc, RetryAfterMs=4.2.1, x", 0, false},
+		{"negative decimal RetryAfterMs", "Request rate is large: ActivityID=abc, RetryAfterMs=-4.2, x", 0, false},
+		{"RetryAfterMs out of range", "Request rate is large: ActivityID=abc, RetryAfterMs=9223372036855, x", 0, false},
+		{"decimal RetryAfterMs out of range", "Request rate is large: ActivityID=abc, RetryAfterMs=99999999999999999999.5, x", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			actual, ok := parseRetryAfter(tc.errMsg)
+			assert.Equal(te, tc.ok, ok)
+			assert.Equal(te, tc.expected, actual)
+		})
+	}
+}
+
+func TestGetRetryAfterMsFallsBackForMalformedMessages(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.JitterMode = NoJitter
+	p.FixedBackOffTimeMs = 123
+	malformed := []string{
+		"TooManyRequests (429)",
+		"TooManyRequests (429),",
+		", TooManyRequests (429), RetryAfterMs",
+		"TooManyRequests (429), RetryAfterMs=",
+		"TooManyRequests (429), RetryAfterMs==",
+		"TooManyRequests (429), RetryAfterMs=later",
+		"TooManyRequests (429), RetryAfterMs=99999999999999999999",
+		"TooManyRequests (429), RetryAfterMs=NaN.",
+		"TooManyRequests (429), RetryAfterMs=4.2.1",
+		"TooManyRequests (429), RetryAfterMs=.",
+	}
+
+	for _, errMsg := range malformed {
+		assert.NotPanics(t, func() {
+			assert.Equal(t, 123*time.Millisecond, retryAfter(t, p, errMsg), errMsg)
+		})
+	}
+}
+
+// rulesSink keeps the result of DefaultClassificationRules alive, so that the compiler does not optimize the benchmarked call away
+var rulesSink []ClassificationRule
+
+func BenchmarkDefaultClassificationRules(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rulesSink = DefaultClassificationRules()
+	}
+}
+
+// splitParseRetryAfter is the former implementation of parseRetryAfter, which split the message and only handled RetryAfterMs as its second field
+func splitParseRetryAfter(errMsg string) (retryAfter time.Duration, ok bool) {
+	parts := strings.Split(errMsg, ",")
+	if len(parts) < 2 {
+		return 0, false
+	}
+	retryAfterMs := strings.Split(parts[1], "=")
+	if strings.TrimSpace(retryAfterMs[0]) != retryAfterKey {
+		return 0, false
+	}
+	r, _ := strconv.Atoi(retryAfterMs[1])
+	return time.Duration(r) * time.Millisecond, true
+}
+
+func TestParserMatchesSplitParser(t *testing.T) {
+	for i := 0; i < 5000; i++ {
+		activityID := fmt.Sprintf("%08x-7367-4ff8-b06b-b7e2d1269f55", i*7919)
+		errMsgs := []string{
+			strings.Replace(strings.Replace(rateLimitedErrMsg, "RetryAfterMs=42", "RetryAfterMs="+strconv.Itoa(i), 1), "c268afb6-7367-4ff8-b06b-b7e2d1269f55", activityID, -1),
+			strings.Replace(rateLimitedErrMsgWithoutRetryAfterMs, "c268afb6-7367-4ff8-b06b-b7e2d1269f55", activityID, -1),
+		}
+		for _, errMsg := range errMsgs {
+			expected, expectedOK := splitParseRetryAfter(errMsg)
+			actual, ok := parseRetryAfter(errMsg)
+			if !assert.Equal(t, expectedOK, ok, errMsg) || !assert.Equal(t, expected, actual, errMsg) {
+				return
+			}
+			if !assert.Equal(t, activityID, parseActivityID(errMsg), errMsg) {
+				return
+			}
+			_, subStatus, _ := ParseCosmosError(errMsg)
+			if !assert.Equal(t, 3200, subStatus, errMsg) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package retry
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ParseFailureAction defines how errors which look like throttling, but cannot be classified by the parser, are handled
+type ParseFailureAction int
+
+const (
+	// FailClosed rethrows errors which cannot be classified (default)
+	FailClosed ParseFailureAction = iota
+	// FailOpen retries errors which cannot be classified after FixedBackOffTimeMs
+	FailOpen
+)
+
+func (pfa ParseFailureAction) String() string {
+	if pfa == FailOpen {
+		return "fail-open"
+	}
+	return "fail-closed"
+}
+
+// ambiguousThrottleHints are (lower case) fragments suggesting an error might be a rate limited error the parser did not recognize. The status code is only matched the way the server reports it, as a bare 429 may well be part of an ActivityID or of any other number
+var ambiguousThrottleHints = []string{"(429)", "statuscode: 429", "request rate is large", "toomanyrequests", "retryafterms"}
+
+// isAmbiguous reports whether errMsg hints at throttling even though it was not classified as a rate limited error
+func isAmbiguous(errMsg string) bool {
+	lower := strings.ToLower(errMsg)
+	for _, hint := range ambiguousThrottleHints {
+		if strings.Contains(lower, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// onParseFailure applies ParseFailureAction to an error that could not be classified
+func (crp *CosmosRetryPolicy) onParseFailure(ex *execution, errMsg string) gocql.RetryType {
+	if crp.ParseFailureAction != FailOpen || !isAmbiguous(errMsg) {
+		return gocql.Rethrow
+	}
+	if !crp.backOff(ex, time.Duration(crp.FixedBackOffTimeMs)*time.Millisecond) {
+		return gocql.Rethrow
+	}
+	crp.recordPath(ex, DecisionPathParseFailureOpen)
+	return gocql.Retry
+}
@@ -0,0 +1,67 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+const ambiguousErrMsg = "server error: TooManyRequests, please retry later"
+
+func TestParseFailureAction(t *testing.T) {
+	type testCase struct {
+		name              string
+		action            ParseFailureAction
+		errMsg            string
+		expectedRetryType gocql.RetryType
+		expectedSleep     time.Duration
+	}
+
+	testCases := []testCase{
+		{"fail closed rethrows ambiguous error", FailClosed, ambiguousErrMsg, gocql.Rethrow, 0},
+		{"fail open retries ambiguous error with fixed back-off", FailOpen, ambiguousErrMsg, gocql.Retry, 10 * time.Millisecond},
+		{"fail open rethrows unrelated error", FailOpen, "error: today is not your day!", gocql.Rethrow, 0},
+		{"fail open rethrows error with 429 in its ActivityID", FailOpen, "error: today is not your day! ActivityID=4291c0de-7367-4ff8-b06b-b7e2d1269f55", gocql.Rethrow, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			p.FixedBackOffTimeMs = 10
+			p.ParseFailureAction = tc.action
+
+			assert.Equal(te, tc.expectedRetryType, retryTypeOf(p, errors.New(tc.errMsg)))
+			assert.Equal(te, tc.expectedSleep, p.BlockedTime())
+		})
+	}
+}
+
+func TestParseFailureActionDefaultsToFailClosed(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	assert.Equal(t, FailClosed, p.ParseFailureAction)
+	assert.Equal(t, gocql.Rethrow, retryTypeOf(p, errors.New(ambiguousErrMsg)))
+}
+
+func TestAmbiguousStatusCode(t *testing.T) {
+	type testCase struct {
+		name     string
+		errMsg   string
+		expected bool
+	}
+
+	testCases := []testCase{
+		{"status code in parentheses", "server error: status (429), please retry later", true},
+		{"status code as per direct mode", "server error: StatusCode: 429, please retry later", true},
+		{"429 in an ActivityID", "server error: ActivityID=4291c0de-7367-4ff8-b06b-b7e2d1269f55", false},
+		{"429 in a number", "server error: 3 of 14290 rows written", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, isAmbiguous(tc.errMsg))
+		})
+	}
+}
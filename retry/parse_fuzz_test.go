@@ -0,0 +1,74 @@
+//go:build go1.18
+// +build go1.18
+
+package retry
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzGetRetryAfterMs feeds arbitrary error messages to the parser, seeded with the samples of TestParserGolden and known malformed messages, and checks that it neither panics nor returns a negative back-off. Run it with go test -run '^$' -fuzz FuzzGetRetryAfterMs
+func FuzzGetRetryAfterMs(f *testing.F) {
+	samples, err := filepath.Glob(filepath.Join(parserSamplesDir, "*.txt"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, sample := range samples {
+		raw, err := ioutil.ReadFile(sample)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(raw))
+	}
+	for _, errMsg := range []string{
+		rateLimitedErrMsg,
+		rateLimitedErrMsgWithoutRetryAfterMs,
+		directRateLimitedErrMsg,
+		directRateLimitedErrMsgWithRetryAfterMs,
+		notRateLimitedErrMsg,
+		ambiguousErrMsg,
+		"",
+		"RetryAfterMs",
+		"RetryAfterMs=",
+		"RetryAfterMs=-5",
+		"RetryAfterMs=1e3",
+		"RetryAfterMs=.",
+		"RetryAfterMs=99999999999999999999",
+		"RetryAfterMs=9223372036854775807",
+		"RetryAfterMs=9223372036854775.807",
+		`{"RetryAfterMs"`,
+		`{"RetryAfterMs": "`,
+		"Request rate is large: RetryAfterMs",
+		"Request rate is large: ActivityID=",
+		"TooManyRequests (429); Substatus: ",
+		"StatusCode: 429, SubStatusCode: 3200, ActivityId:",
+	} {
+		f.Add(errMsg)
+	}
+
+	f.Fuzz(func(t *testing.T, errMsg string) {
+		p := NewCosmosRetryPolicy(3)
+		p.SleepDisabled = true
+
+		if d, ok := p.getRetryAfterMs(&p.exec, errMsg); d < 0 || !ok && d != 0 {
+			t.Errorf("getRetryAfterMs(%q) = %v, %v", errMsg, d, ok)
+		}
+		if d, ok := parseRetryAfter(errMsg); d < 0 || !ok && d != 0 {
+			t.Errorf("parseRetryAfter(%q) = %v, %v", errMsg, d, ok)
+		}
+		if d, ok := findRetryAfter(errMsg); d < 0 || !ok && d != 0 {
+			t.Errorf("findRetryAfter(%q) = %v, %v", errMsg, d, ok)
+		}
+		if d, _, _ := ParseCosmosError(errMsg); d < 0 {
+			t.Errorf("ParseCosmosError(%q) = %v", errMsg, d)
+		}
+		parseSubStatus(errMsg)
+		parseActivityID(errMsg)
+		if _, d := p.Decide(errors.New(errMsg), 1, true); d < 0 {
+			t.Errorf("Decide(%q) = %v", errMsg, d)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the parser tests")
+
+const parserSamplesDir = "testdata/parser"
+
+// parseSummary renders what the parser extracts from errMsg, one key=value per line
+func parseSummary(errMsg string) string {
+	var b strings.Builder
+	p := NewCosmosRetryPolicy(3)
+	fmt.Fprintf(&b, "throttle_mode=%v\n", p.throttleMode(errMsg))
+	retryAfter, ok := parseRetryAfter(errMsg)
+	fmt.Fprintf(&b, "retry_after_ms=%d (present=%v)\n", retryAfter.Milliseconds(), ok)
+	subStatus, ok := parseSubStatus(errMsg)
+	fmt.Fprintf(&b, "substatus=%d (present=%v)\n", subStatus, ok)
+	fmt.Fprintf(&b, "activity_id=%s\n", parseActivityID(errMsg))
+	return b.String()
+}
+
+// TestParserGolden parses every testdata/parser/*.txt sample and compares the result with the matching .golden file. To add a sample, drop the raw error message in a new .txt file and run go test -run TestParserGolden -update, then review the generated .golden file
+func TestParserGolden(t *testing.T) {
+	samples, err := filepath.Glob(filepath.Join(parserSamplesDir, "*.txt"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, samples)
+
+	for _, sample := range samples {
+		name := strings.TrimSuffix(filepath.Base(sample), ".txt")
+		t.Run(name, func(te *testing.T) {
+			raw, err := ioutil.ReadFile(sample)
+			assert.NoError(te, err)
+			actual := parseSummary(strings.TrimSuffix(string(raw), "\n"))
+
+			golden := strings.TrimSuffix(sample, ".txt") + ".golden"
+			if *updateGolden {
+				assert.NoError(te, ioutil.WriteFile(golden, []byte(actual), 0644))
+			}
+			expected, err := ioutil.ReadFile(golden)
+			assert.NoError(te, err)
+			assert.Equal(te, string(expected), actual)
+		})
+	}
+}
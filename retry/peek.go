@@ -0,0 +1,23 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// PeekNextAction returns the decision (and back-off) the policy would make if the current query failed once more with a rate limited error that does not carry RetryAfterMs. It is meant for diagnostics and does not modify the policy state
+func (crp *CosmosRetryPolicy) PeekNextAction() (gocql.RetryType, time.Duration) {
+	last := crp.lastExecution()
+	next := last.attempts + 1
+	if !crp.allowed(next) {
+		return gocql.Rethrow, 0
+	}
+
+	backOff := crp.fallbackBackOff(last.consistency, next, "")
+	if crp.MaxBlockedTimeMs > 0 && atomic.LoadInt64(&crp.blockedNanos)+int64(backOff) > int64(time.Duration(crp.MaxBlockedTimeMs)*time.Millisecond) {
+		return gocql.Rethrow, 0
+	}
+	return gocql.Retry, backOff
+}
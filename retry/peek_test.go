@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeekNextActionMatchesDecision(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.JitterMode = NoJitter
+	p.FixedBackOffTimeMs = 10
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		peekedType, peekedBackOff := p.PeekNextAction()
+
+		before := p.BlockedTime()
+		actualType := gocql.Rethrow
+		if p.Attempt(attemptsQuery{attempts: attempt}) {
+			actualType = p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs))
+		}
+
+		assert.Equal(t, actualType, peekedType, "attempt %d", attempt)
+		assert.Equal(t, p.BlockedTime()-before, peekedBackOff, "attempt %d", attempt)
+	}
+
+	peekedType, peekedBackOff := p.PeekNextAction()
+	assert.Equal(t, gocql.Rethrow, peekedType)
+	assert.Equal(t, time.Duration(0), peekedBackOff)
+	assert.False(t, p.Attempt(attemptsQuery{attempts: 4}))
+}
+
+func TestPeekNextActionDoesNotMutateState(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.FixedBackOffTimeMs = 10
+	p.Attempt(attemptsQuery{attempts: 2})
+
+	for i := 0; i < 3; i++ {
+		p.PeekNextAction()
+	}
+	assert.Equal(t, 2, p.exec.attempts)
+	assert.Equal(t, time.Duration(0), p.BlockedTime())
+	assert.Equal(t, int64(0), p.consecutiveThrottles)
+}
+
+func TestPeekNextActionInfiniteRetries(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.Attempt(attemptsQuery{attempts: 2})
+
+	retryType, backOff := p.PeekNextAction()
+	assert.Equal(t, gocql.Retry, retryType)
+	// growing back-off for the third attempt
+	assert.True(t, backOff >= 3*time.Duration(p.GrowingBackOffTimeMs)*time.Millisecond)
+}
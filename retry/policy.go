@@ -0,0 +1,28 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// RetryPolicy is a gocql.RetryPolicy along with the extras of CosmosRetryPolicy, which satisfies it. Depend on it rather than on *CosmosRetryPolicy to inject another implementation, e.g. a MockRetryPolicy in tests
+type RetryPolicy interface {
+	gocql.RetryPolicy
+	// Decide returns the retry decision for err on the given attempt of an idempotent query or not, along with the back-off, without sleeping
+	Decide(err error, attempts int, idempotent bool) (gocql.RetryType, time.Duration)
+	// ClassifyError returns the ErrorKind of err
+	ClassifyError(err error) ErrorKind
+	// Metrics returns a snapshot of the decision counters
+	Metrics() Metrics
+	// LastDecisionPath returns the code path which handled the most recent decision
+	LastDecisionPath() string
+	// LastRetryAfterMs returns the RetryAfterMs of the most recent rate limited error which carried it
+	LastRetryAfterMs() time.Duration
+	// LastActivityID returns the ActivityID of the most recent error which carried it
+	LastActivityID() string
+	// Reset clears the retry state of queries which are not scoped
+	Reset()
+}
+
+var _ RetryPolicy = (*CosmosRetryPolicy)(nil)
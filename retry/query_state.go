@@ -0,0 +1,98 @@
+package retry
+
+import (
+	"container/list"
+	"reflect"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// queryStateTTL is how long the execution of a query which is not scoped is tracked after its latest attempt. Successful queries are not reported to the policy (unless scoped, see WithAttemptScope), hence their executions are evicted once idle for that long
+const queryStateTTL = 10 * time.Minute
+
+// maxTrackedQueries bounds the number of executions tracked at any given moment. Once reached, the least recently attempted one is evicted
+const maxTrackedQueries = 10000
+
+// trackedExecution is the execution of a query which is not scoped, along with the time of its latest attempt and its element of the list of tracked queries, ordered from the most to the least recently attempted. Executions which are done are kept until evicted, so that WrapThrottleExhausted can report how long they backed off for
+type trackedExecution struct {
+	execution
+	lastSeen time.Time
+	query    gocql.RetryableQuery
+	element  *list.Element
+}
+
+// isTrackable reports whether the executions of rq can be told apart by its identity, i.e. whether it is a pointer such as a *gocql.Query or *gocql.Batch
+func isTrackable(rq gocql.RetryableQuery) bool {
+	return rq != nil && reflect.ValueOf(rq).Kind() == reflect.Ptr
+}
+
+// trackedQuery returns the execution tracked for rq, tracking a new one if there is none. crp.execMu must be held
+func (crp *CosmosRetryPolicy) trackedQuery(rq gocql.RetryableQuery, now time.Time) *execution {
+	if tracked, ok := crp.queries[rq]; ok {
+		tracked.lastSeen = now
+		crp.queryOrder.MoveToFront(tracked.element)
+		return &tracked.execution
+	}
+	if crp.queries == nil {
+		crp.queries = make(map[gocql.RetryableQuery]*trackedExecution)
+	}
+	crp.evictIdleQueries(now)
+	tracked := &trackedExecution{lastSeen: now, query: rq}
+	tracked.element = crp.queryOrder.PushFront(tracked)
+	crp.queries[rq] = tracked
+	return &tracked.execution
+}
+
+// evictIdleQueries stops tracking the least recently attempted executions which have not been attempted for queryStateTTL and, if there are still too many of them, the least recently attempted one. Only the executions evicted are visited. crp.execMu must be held
+func (crp *CosmosRetryPolicy) evictIdleQueries(now time.Time) {
+	for back := crp.queryOrder.Back(); back != nil; back = crp.queryOrder.Back() {
+		tracked := back.Value.(*trackedExecution)
+		if len(crp.queries) < maxTrackedQueries && now.Sub(tracked.lastSeen) < queryStateTTL {
+			return
+		}
+		crp.untrackQuery(tracked.query)
+	}
+}
+
+// recordQuery records ex as the latest state of the execution of rq, accounting for it being done (or attempted again) in finishedQueries. crp.execMu must be held
+func (crp *CosmosRetryPolicy) recordQuery(rq gocql.RetryableQuery, ex execution, now time.Time) {
+	tracked := crp.trackedQuery(rq, now)
+	if ex.done && !tracked.done {
+		crp.finishedQueries++
+	} else if !ex.done && tracked.done {
+		crp.finishedQueries--
+	}
+	*tracked = ex
+}
+
+// untrackQuery stops tracking the execution of rq. crp.execMu must be held
+func (crp *CosmosRetryPolicy) untrackQuery(rq gocql.RetryableQuery) {
+	if tracked, ok := crp.queries[rq]; ok {
+		if tracked.done {
+			crp.finishedQueries--
+		}
+		crp.queryOrder.Remove(tracked.element)
+		delete(crp.queries, rq)
+	}
+}
+
+// totalWaitOf returns the total back-off of the latest execution of rq, a query which is not scoped, or 0 if it is not known, i.e. if rq cannot be told apart from other queries (see isTrackable) or its execution is no longer tracked
+func (crp *CosmosRetryPolicy) totalWaitOf(rq gocql.RetryableQuery) time.Duration {
+	if !isTrackable(rq) {
+		return 0
+	}
+	crp.execMu.Lock()
+	defer crp.execMu.Unlock()
+	if tracked, ok := crp.queries[rq]; ok {
+		return tracked.totalWait
+	}
+	return 0
+}
+
+// TrackedQueries returns the number of queries which are not scoped (see WithAttemptScope) whose executions are currently tracked by the policy, i.e. which have failed and have neither been given up on nor been idle for long
+func (crp *CosmosRetryPolicy) TrackedQueries() int {
+	crp.execMu.Lock()
+	defer crp.execMu.Unlock()
+	return len(crp.queries) - crp.finishedQueries
+}
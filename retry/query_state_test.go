@@ -0,0 +1,198 @@
+package retry
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// pointerQuery is told apart by its identity, like a *gocql.Query
+type pointerQuery struct {
+	MockRetryableQuery
+	attempts int
+}
+
+func (pq *pointerQuery) Attempts() int {
+	return pq.attempts
+}
+
+// failUntilGivenUp fails q with err until the policy gives up on it, and returns the number of the attempt it was given up on
+func failUntilGivenUp(p *CosmosRetryPolicy, q *pointerQuery, err error) int {
+	for q.attempts = 1; ; q.attempts++ {
+		if !p.Attempt(q) || p.GetRetryType(err) != gocql.Retry {
+			return q.attempts
+		}
+	}
+}
+
+func TestConcurrentQueriesHaveIndependentState(t *testing.T) {
+	const queries = 50
+	p := NewCosmosRetryPolicy(-1)
+	p.SleepDisabled = true
+	// 5 back-offs of 42ms each
+	p.MaxElapsedTimeMs = 5*42 + 1
+
+	givenUpOn := make([]int, queries)
+	var wg sync.WaitGroup
+	for i := 0; i < queries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			givenUpOn[i] = failUntilGivenUp(p, &pointerQuery{}, errors.New(rateLimitedErrMsg))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < queries; i++ {
+		assert.Equal(t, 6, givenUpOn[i], "query %d", i)
+	}
+	assert.Equal(t, 0, p.TrackedQueries())
+}
+
+func TestInterleavedQueriesHaveIndependentState(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := NewCosmosRetryPolicy(-1)
+	p.SleepDisabled = true
+	p.now = func() time.Time { return now }
+	var events []RetryEvent
+	p.OnRetry = func(e RetryEvent) { events = append(events, e) }
+
+	first, second := &pointerQuery{}, &pointerQuery{}
+	fail := func(q *pointerQuery) RetryEvent {
+		q.attempts++
+		assert.True(t, p.Attempt(q))
+		p.GetRetryType(errors.New(rateLimitedErrMsg))
+		now = now.Add(time.Second)
+		return events[len(events)-1]
+	}
+
+	assert.Equal(t, time.Duration(0), fail(first).Elapsed)
+	assert.Equal(t, time.Duration(0), fail(second).Elapsed)
+	assert.Equal(t, 2*time.Second, fail(first).Elapsed)
+	assert.Equal(t, 2*time.Second, fail(second).Elapsed)
+	assert.Equal(t, 2, p.TrackedQueries())
+}
+
+func TestGivenUpQueriesAreNotTracked(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	p.SleepDisabled = true
+	q := &pointerQuery{}
+
+	q.attempts = 1
+	assert.True(t, p.Attempt(q))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	assert.Equal(t, 1, p.TrackedQueries())
+
+	// rethrown right away
+	assert.True(t, p.Attempt(&pointerQuery{attempts: 1}))
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New("syntax error")))
+	assert.Equal(t, 1, p.TrackedQueries())
+
+	// denied by Attempt
+	assert.Equal(t, 3, failUntilGivenUp(p, q, errors.New(rateLimitedErrMsg)))
+	assert.Equal(t, 0, p.TrackedQueries())
+}
+
+func TestIdleQueriesAreEvicted(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := NewCosmosRetryPolicy(3)
+	p.SleepDisabled = true
+	p.now = func() time.Time { return now }
+	fail := func(q *pointerQuery) {
+		q.attempts++
+		assert.True(t, p.Attempt(q))
+		assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	}
+
+	// the retry of idle succeeded, which the policy does not know about
+	idle, active := &pointerQuery{}, &pointerQuery{}
+	fail(idle)
+	fail(active)
+	now = now.Add(queryStateTTL / 2)
+	fail(active)
+	assert.Equal(t, 2, p.TrackedQueries())
+
+	now = now.Add(queryStateTTL / 2)
+	fail(&pointerQuery{})
+	assert.Equal(t, 2, p.TrackedQueries())
+	p.execMu.Lock()
+	_, idleTracked := p.queries[idle]
+	_, activeTracked := p.queries[active]
+	p.execMu.Unlock()
+	assert.False(t, idleTracked)
+	assert.True(t, activeTracked)
+}
+
+func TestTrackedQueriesAreBounded(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := NewCosmosRetryPolicy(3)
+
+	oldest := &pointerQuery{}
+	p.execMu.Lock()
+	p.trackedQuery(oldest, now)
+	for i := 1; i <= maxTrackedQueries; i++ {
+		p.trackedQuery(&pointerQuery{}, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	_, oldestTracked := p.queries[oldest]
+	p.execMu.Unlock()
+
+	assert.Equal(t, maxTrackedQueries, p.TrackedQueries())
+	assert.False(t, oldestTracked)
+}
+
+func TestLeastRecentlyAttemptedQueryIsEvicted(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := NewCosmosRetryPolicy(3)
+
+	oldest, second := &pointerQuery{}, &pointerQuery{}
+	p.execMu.Lock()
+	defer p.execMu.Unlock()
+	p.trackedQuery(oldest, now)
+	p.trackedQuery(second, now.Add(time.Millisecond))
+	for i := 3; i <= maxTrackedQueries; i++ {
+		p.trackedQuery(&pointerQuery{}, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	// oldest is attempted again, hence second is now the least recently attempted
+	p.trackedQuery(oldest, now.Add(time.Second))
+	p.trackedQuery(&pointerQuery{}, now.Add(2*time.Second))
+
+	_, oldestTracked := p.queries[oldest]
+	_, secondTracked := p.queries[second]
+	assert.Equal(t, maxTrackedQueries, len(p.queries))
+	assert.Equal(t, maxTrackedQueries, p.queryOrder.Len())
+	assert.True(t, oldestTracked)
+	assert.False(t, secondTracked)
+}
+
+func TestTrackedQueriesDoNotKeepLimiterSlots(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.SleepDisabled = true
+	p.Limiter = NewLimiter(2, 0)
+
+	// both queries retry once and succeed, which the policy does not know about
+	for _, q := range []*pointerQuery{{attempts: 1}, {attempts: 1}} {
+		assert.True(t, p.Attempt(q))
+		assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	}
+	assert.Equal(t, 2, p.TrackedQueries())
+
+	assert.True(t, p.Attempt(&pointerQuery{attempts: 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+}
+
+func TestResetStopsTrackingQueries(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.SleepDisabled = true
+
+	assert.True(t, p.Attempt(&pointerQuery{attempts: 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	assert.Equal(t, 1, p.TrackedQueries())
+
+	p.Reset()
+	assert.Equal(t, 0, p.TrackedQueries())
+	assert.Equal(t, 0, p.queryOrder.Len())
+}
@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// BenchmarkConcurrentGetRetryType makes GetRetryType decisions on rate limited errors without RetryAfterMs, whose growing back-off is jittered at random, from concurrent goroutines
+func BenchmarkConcurrentGetRetryType(b *testing.B) {
+	p := NewCosmosRetryPolicy(-1)
+	p.sleepFunc = func(time.Duration) {}
+	err := errors.New(rateLimitedErrMsgWithoutRetryAfterMs)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		q := attemptsQuery{attempts: 1}
+		for pb.Next() {
+			p.Attempt(q)
+			p.GetRetryType(err)
+		}
+	})
+}
+
+// BenchmarkConcurrentJitter computes jittered back-offs from concurrent goroutines, drawing from the global math/rand source (which is locked) as the policy used to, and from the generators of the policy. Compare them with go test -run - -bench ConcurrentJitter -cpu 1,8
+func BenchmarkConcurrentJitter(b *testing.B) {
+	b.Run("global", func(b *testing.B) {
+		p := NewCosmosRetryPolicy(-1)
+		p.randFunc = rand.Intn
+		benchmarkConcurrentJitter(b, p)
+	})
+	b.Run("policy", func(b *testing.B) {
+		benchmarkConcurrentJitter(b, NewCosmosRetryPolicy(-1))
+	})
+}
+
+func benchmarkConcurrentJitter(b *testing.B, p *CosmosRetryPolicy) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p.fallbackBackOff(gocql.One, 3, "")
+		}
+	})
+}
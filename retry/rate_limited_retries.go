@@ -0,0 +1,11 @@
+package retry
+
+// rateLimitedRetryAllowed reports whether a rate limited error of ex may be retried as per MaxRateLimitedRetries, accounting for the retry if so
+func (crp *CosmosRetryPolicy) rateLimitedRetryAllowed(ex *execution) bool {
+	if crp.MaxRateLimitedRetries > 0 && ex.rateLimitedRetries >= crp.MaxRateLimitedRetries {
+		crp.recordPath(ex, DecisionPathRateLimitedRetriesExhausted)
+		return false
+	}
+	ex.rateLimitedRetries++
+	return true
+}
@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxRateLimitedRetries(t *testing.T) {
+	type testCase struct {
+		name     string
+		max      int
+		errs     []error
+		expected []gocql.RetryType
+	}
+
+	throttled := errors.New(rateLimitedErrMsg)
+	timeout := &gocql.RequestErrWriteTimeout{}
+	testCases := []testCase{
+		{"no limit by default", 0, []error{throttled, throttled, throttled, throttled}, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Retry, gocql.Retry}},
+		{"rate limited retries capped", 2, []error{throttled, throttled, throttled}, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Rethrow}},
+		{"timeouts not accounted for", 2, []error{throttled, timeout, throttled, timeout, timeout, throttled}, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Retry, gocql.Retry, gocql.Retry, gocql.Rethrow}},
+		{"timeouts retried once the cap is reached", 1, []error{throttled, timeout, timeout}, []gocql.RetryType{gocql.Retry, gocql.Retry, gocql.Retry}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(-1)
+			p.MaxRateLimitedRetries = tc.max
+			p.sleepFunc = func(time.Duration) {}
+			q := idempotentQuery{MockRetryableQuery{ctx: WithAttemptScope(context.Background())}, true}
+
+			var actual []gocql.RetryType
+			for _, err := range tc.errs {
+				assert.True(te, p.Attempt(q))
+				actual = append(actual, p.GetRetryType(err))
+			}
+			assert.Equal(te, tc.expected, actual)
+		})
+	}
+}
+
+func TestMaxRateLimitedRetriesPerQuery(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.MaxRateLimitedRetries = 1
+	p.sleepFunc = func(time.Duration) {}
+	throttled := errors.New(rateLimitedErrMsg)
+
+	assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{}, 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(throttled))
+	assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{}, 2}))
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(throttled))
+	assert.Equal(t, DecisionPathRateLimitedRetriesExhausted, p.LastDecisionPath())
+
+	// the next query may be retried again
+	assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{}, 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(throttled))
+}
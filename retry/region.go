@@ -0,0 +1,37 @@
+package retry
+
+import (
+	"strings"
+	"time"
+)
+
+var regionKeys = []string{"RegionName=", "RegionName: "}
+
+// parseRegion extracts the name of the region which served the request from a Cosmos DB error message. It returns an empty string if not available
+func parseRegion(errMsg string) string {
+	for _, key := range regionKeys {
+		i := strings.Index(errMsg, key)
+		if i == -1 {
+			continue
+		}
+		region := errMsg[i+len(key):]
+		if end := strings.IndexAny(region, ",;'"); end != -1 {
+			region = region[:end]
+		}
+		return strings.TrimSpace(region)
+	}
+	return ""
+}
+
+// secondaryRegionBackOff returns SecondaryRegionBackOffMs if errMsg was served by a region other than PrimaryRegion. ok is false if it does not apply, including errors without region information
+func (crp *CosmosRetryPolicy) secondaryRegionBackOff(ex *execution, errMsg string) (backOff time.Duration, ok bool) {
+	if crp.PrimaryRegion == "" || crp.SecondaryRegionBackOffMs <= 0 {
+		return 0, false
+	}
+	region := parseRegion(errMsg)
+	if region == "" || strings.EqualFold(region, crp.PrimaryRegion) {
+		return 0, false
+	}
+	crp.recordPath(ex, DecisionPathSecondaryRegion)
+	return time.Duration(crp.SecondaryRegionBackOffMs) * time.Millisecond, true
+}
@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rateLimitedErrMsgFromRegion(region string) string {
+	return strings.Replace(rateLimitedErrMsgWithoutRetryAfterMs, "Substatus: 3200;", "Substatus: 3200; RegionName: "+region+";", 1)
+}
+
+func TestParseRegion(t *testing.T) {
+	assert.Equal(t, "East US 2", parseRegion(rateLimitedErrMsgFromRegion("East US 2")))
+	assert.Equal(t, "West US", parseRegion("Request rate is large: ActivityID=abc, RegionName=West US, Additional details"))
+	assert.Equal(t, "", parseRegion(rateLimitedErrMsgWithoutRetryAfterMs))
+}
+
+func TestSecondaryRegionBackOff(t *testing.T) {
+	type testCase struct {
+		name         string
+		errMsg       string
+		expected     time.Duration
+		expectedPath string
+	}
+
+	p := NewCosmosRetryPolicy(5, WithSecondaryRegionBackOff(12*time.Second))
+	p.JitterMode = NoJitter
+	p.PrimaryRegion = "West US"
+	fixed := time.Duration(p.FixedBackOffTimeMs) * time.Millisecond
+
+	testCases := []testCase{
+		{"error served by a secondary region", rateLimitedErrMsgFromRegion("East US"), 12 * time.Second, DecisionPathSecondaryRegion},
+		{"error served by the primary region", rateLimitedErrMsgFromRegion("west us"), fixed, DecisionPathFixedFallback},
+		{"error without region", rateLimitedErrMsgWithoutRetryAfterMs, fixed, DecisionPathFixedFallback},
+		{"server provided RetryAfterMs is honored", strings.Replace(rateLimitedErrMsg, "Substatus: 3200;", "Substatus: 3200; RegionName: East US;", 1), 42 * time.Millisecond, DecisionPathServerHinted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, retryAfter(te, p, tc.errMsg))
+			assert.Equal(te, tc.expectedPath, p.exec.path)
+		})
+	}
+}
+
+func TestSecondaryRegionBackOffDisabledByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.JitterMode = NoJitter
+	assert.Equal(t, time.Duration(p.FixedBackOffTimeMs)*time.Millisecond, retryAfter(t, p, rateLimitedErrMsgFromRegion("East US")))
+}
@@ -0,0 +1,36 @@
+package retry
+
+import (
+	"errors"
+	"time"
+)
+
+// RetryAfterError is implemented by errors which carry the back-off requested by the server (RetryAfterMs) as a structured field, e.g. a gocql.RequestError decoded from the payload of the error rather than only rendered in its message. The hint of such an error (or of any error it wraps) takes precedence over the message, which is only parsed if the error does not implement RetryAfterError or ok is false. It does not make the error rate limited by itself, see ThrottleErrorCode and HonorRetryAfterOnAnyError
+type RetryAfterError interface {
+	error
+	// RetryAfter returns the back-off requested by the server. ok is false if the error does not carry it
+	RetryAfter() (retryAfter time.Duration, ok bool)
+}
+
+// structuredRetryAfter returns the back-off carried by err as per RetryAfterError. ok is false if err is nil, does not carry it, or carries a negative one
+func structuredRetryAfter(err error) (retryAfter time.Duration, ok bool) {
+	if err == nil {
+		return 0, false
+	}
+	var rae RetryAfterError
+	if !errors.As(err, &rae) {
+		return 0, false
+	}
+	if retryAfter, ok = rae.RetryAfter(); !ok || retryAfter < 0 {
+		return 0, false
+	}
+	return retryAfter, true
+}
+
+// retryAfterOf returns the back-off carried by err, as per RetryAfterError or, if not available, as per its message
+func retryAfterOf(err error, errMsg string) (retryAfter time.Duration, ok bool) {
+	if retryAfter, ok = structuredRetryAfter(err); ok {
+		return retryAfter, true
+	}
+	return parseRetryAfter(errMsg)
+}
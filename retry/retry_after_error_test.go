@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// structuredRequestError is a gocql.RequestError which may carry RetryAfterMs as a structured field
+type structuredRequestError struct {
+	code       int
+	message    string
+	retryAfter time.Duration
+	hinted     bool
+}
+
+func (e structuredRequestError) Code() int {
+	return e.code
+}
+
+func (e structuredRequestError) Message() string {
+	return e.message
+}
+
+func (e structuredRequestError) Error() string {
+	return e.message
+}
+
+func (e structuredRequestError) RetryAfter() (time.Duration, bool) {
+	return e.retryAfter, e.hinted
+}
+
+var _ gocql.RequestError = structuredRequestError{}
+var _ RetryAfterError = structuredRequestError{}
+
+func TestStructuredRetryAfter(t *testing.T) {
+	type testCase struct {
+		name              string
+		err               error
+		honorOnAnyError   bool
+		expectedType      gocql.RetryType
+		expectedBackOff   time.Duration
+		expectedPath      string
+		expectedLastAfter time.Duration
+	}
+
+	overloaded := 0x1001
+	readTimeout := 0x1200
+	testCases := []testCase{
+		{"structured hint takes precedence over the message", structuredRequestError{overloaded, rateLimitedErrMsg, 7 * time.Millisecond, true}, false, gocql.Retry, 7 * time.Millisecond, DecisionPathServerHinted, 7 * time.Millisecond},
+		{"structured hint without Cosmos DB message", structuredRequestError{overloaded, "overloaded", 7 * time.Millisecond, true}, false, gocql.Retry, 7 * time.Millisecond, DecisionPathServerHinted, 7 * time.Millisecond},
+		{"zero structured hint", structuredRequestError{overloaded, rateLimitedErrMsg, 0, true}, false, gocql.Retry, 0, DecisionPathServerHinted, 0},
+		{"wrapped structured hint", fmt.Errorf("query failed: %w", structuredRequestError{overloaded, rateLimitedErrMsg, 7 * time.Millisecond, true}), false, gocql.Retry, 7 * time.Millisecond, DecisionPathServerHinted, 7 * time.Millisecond},
+		{"no structured hint falls back to the message", structuredRequestError{overloaded, rateLimitedErrMsg, 0, false}, false, gocql.Retry, 42 * time.Millisecond, DecisionPathServerHinted, 42 * time.Millisecond},
+		{"negative structured hint falls back to the message", structuredRequestError{overloaded, rateLimitedErrMsg, -time.Millisecond, true}, false, gocql.Retry, 42 * time.Millisecond, DecisionPathServerHinted, 42 * time.Millisecond},
+		{"no hint at all", structuredRequestError{overloaded, "overloaded", 0, false}, false, gocql.Retry, 5 * time.Second, DecisionPathFixedFallback, 0},
+		{"structured hint of another error", structuredRequestError{readTimeout, "schema mismatch", 7 * time.Millisecond, true}, false, gocql.Rethrow, 0, DecisionPathNotRetryable, 0},
+		{"structured hint of another error honored", structuredRequestError{readTimeout, "schema mismatch", 7 * time.Millisecond, true}, true, gocql.Retry, 7 * time.Millisecond, DecisionPathServerHinted, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.JitterMode = NoJitter
+			p.SleepDisabled = true
+			p.HonorRetryAfterOnAnyError = tc.honorOnAnyError
+			var events []RetryEvent
+			p.OnRetry = func(e RetryEvent) { events = append(events, e) }
+
+			assert.True(te, p.Attempt(attemptsQuery{attempts: 1}))
+			assert.Equal(te, tc.expectedType, p.GetRetryType(tc.err))
+			assert.Equal(te, tc.expectedBackOff, events[0].BackOff)
+			assert.Equal(te, tc.expectedPath, events[0].DecisionPath)
+			assert.Equal(te, tc.expectedLastAfter, p.LastRetryAfterMs())
+		})
+	}
+}
+
+func TestWrapThrottleExhaustedStructuredRetryAfter(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	err := p.WrapThrottleExhausted(attemptsQuery{attempts: 4}, structuredRequestError{0x1001, rateLimitedErrMsg, 7 * time.Millisecond, true})
+
+	var exhausted *ThrottleExhaustedError
+	assert.True(t, errors.As(err, &exhausted))
+	assert.Equal(t, 7*time.Millisecond, exhausted.LastRetryAfter)
+}
+
+func TestStructuredRetryAfterOfPlainErrors(t *testing.T) {
+	_, ok := structuredRetryAfter(nil)
+	assert.False(t, ok)
+	_, ok = structuredRetryAfter(errors.New(rateLimitedErrMsg))
+	assert.False(t, ok)
+	_, ok = structuredRetryAfter(&gocql.RequestErrReadTimeout{})
+	assert.False(t, ok)
+}
@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// retryAfterHistogramOverflow is the key of the histogram bucket of RetryAfterMs values exceeding the upper bound of every other bucket
+const retryAfterHistogramOverflow = "+Inf"
+
+// defaultRetryAfterBuckets are the bucket bounds returned by DefaultRetryAfterBuckets. They are shared, so that no slice is allocated for every rate limited error, and must not be modified
+var defaultRetryAfterBuckets = []time.Duration{10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second}
+
+// DefaultRetryAfterBuckets returns the upper bounds of the buckets of RetryAfterHistogram used if RetryAfterBuckets is not set
+func DefaultRetryAfterBuckets() []time.Duration {
+	return append([]time.Duration(nil), defaultRetryAfterBuckets...)
+}
+
+// retryAfterHistogram counts RetryAfterMs values per bucket. counts has one more entry than bounds, for values exceeding all of them. sum is the total of the values counted
+type retryAfterHistogram struct {
+	sync.Mutex
+	bounds []time.Duration
+	counts []uint64
+	sum    time.Duration
+}
+
+// retryAfterBuckets returns RetryAfterBuckets, or DefaultRetryAfterBuckets if it is not set
+func (crp *CosmosRetryPolicy) retryAfterBuckets() []time.Duration {
+	if len(crp.RetryAfterBuckets) == 0 {
+		return defaultRetryAfterBuckets
+	}
+	return crp.RetryAfterBuckets
+}
+
+// reset restarts the counts if bounds differ from the bucket bounds they have been counted for. h must be locked
+func (h *retryAfterHistogram) reset(bounds []time.Duration) {
+	if h.counts != nil && len(h.bounds) == len(bounds) {
+		same := true
+		for i := range bounds {
+			same = same && h.bounds[i] == bounds[i]
+		}
+		if same {
+			return
+		}
+	}
+	h.bounds = append([]time.Duration(nil), bounds...)
+	h.counts = make([]uint64, len(bounds)+1)
+	h.sum = 0
+}
+
+// observeRetryAfter counts retryAfter in the first bucket whose upper bound it does not exceed
+func (crp *CosmosRetryPolicy) observeRetryAfter(retryAfter time.Duration) {
+	h := &crp.retryAfterHistogram
+	h.Lock()
+	defer h.Unlock()
+	h.reset(crp.retryAfterBuckets())
+	h.counts[sort.Search(len(h.bounds), func(i int) bool { return retryAfter <= h.bounds[i] })]++
+	h.sum += retryAfter
+}
+
+// retryAfterSnapshot returns a copy of the bucket bounds and (non-cumulative) counts of RetryAfterHistogram, along with the sum of the values counted
+func (crp *CosmosRetryPolicy) retryAfterSnapshot() (bounds []time.Duration, counts []uint64, sum time.Duration) {
+	h := &crp.retryAfterHistogram
+	h.Lock()
+	defer h.Unlock()
+	h.reset(crp.retryAfterBuckets())
+	return append([]time.Duration(nil), h.bounds...), append([]uint64(nil), h.counts...), h.sum
+}
+
+// RetryAfterHistogram returns the number of rate limited (429) errors handled per RetryAfterMs range, as sent by the server, e.g. to tell frequent short throttles from rare long ones when planning capacity. Buckets are keyed by their upper bound (e.g. "100ms"), as per RetryAfterBuckets, and "+Inf" for values exceeding all of them. Counts are not cumulative: each value is only counted in the first bucket whose upper bound it does not exceed. Errors without RetryAfterMs, and decisions made by Decide, are not accounted for. The counts restart if RetryAfterBuckets is changed
+func (crp *CosmosRetryPolicy) RetryAfterHistogram() map[string]uint64 {
+	bounds, counts, _ := crp.retryAfterSnapshot()
+	histogram := make(map[string]uint64, len(counts))
+	for i, bound := range bounds {
+		histogram[bound.String()] = counts[i]
+	}
+	histogram[retryAfterHistogramOverflow] = counts[len(bounds)]
+	return histogram
+}
@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rateLimitedErrMsgWithRetryAfter returns a rate limited error message carrying the given RetryAfterMs
+func rateLimitedErrMsgWithRetryAfter(retryAfterMs string) string {
+	return fmt.Sprintf("Request rate is large: ActivityID=c268afb6-7367-4ff8-b06b-b7e2d1269f55, RetryAfterMs=%s, Additional details='Response status code does not indicate success: TooManyRequests (429); Substatus: 3200'", retryAfterMs)
+}
+
+func TestRetryAfterHistogram(t *testing.T) {
+	type testCase struct {
+		name     string
+		buckets  []time.Duration
+		values   []string
+		expected map[string]uint64
+	}
+
+	testCases := []testCase{
+		{"no values", nil, nil, map[string]uint64{"10ms": 0, "50ms": 0, "100ms": 0, "250ms": 0, "500ms": 0, "1s": 0, "5s": 0, "+Inf": 0}},
+		{"default buckets", nil, []string{"0", "3", "10", "11", "42", "42", "100", "300", "999", "1000", "4000", "30000"}, map[string]uint64{"10ms": 3, "50ms": 3, "100ms": 1, "250ms": 0, "500ms": 1, "1s": 2, "5s": 1, "+Inf": 1}},
+		{"custom buckets", []time.Duration{5 * time.Millisecond, time.Second}, []string{"1", "5", "6", "1000", "1001"}, map[string]uint64{"5ms": 2, "1s": 2, "+Inf": 1}},
+		{"decimal values", []time.Duration{5 * time.Millisecond}, []string{"4.6", "5.4", "5.5"}, map[string]uint64{"5ms": 2, "+Inf": 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(-1)
+			p.SleepDisabled = true
+			p.RetryAfterBuckets = tc.buckets
+			for _, v := range tc.values {
+				retryTypeOf(p, errors.New(rateLimitedErrMsgWithRetryAfter(v)))
+			}
+			assert.Equal(te, tc.expected, p.RetryAfterHistogram())
+		})
+	}
+}
+
+func TestRetryAfterHistogramIgnoresErrorsWithoutRetryAfter(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.SleepDisabled = true
+	p.RetryAfterBuckets = []time.Duration{time.Second}
+
+	retryTypeOf(p, errors.New(rateLimitedErrMsgWithoutRetryAfterMs))
+	retryTypeOf(p, errors.New("syntax error"))
+	p.Decide(errors.New(rateLimitedErrMsg), 1, true)
+	assert.Equal(t, map[string]uint64{"1s": 0, "+Inf": 0}, p.RetryAfterHistogram())
+}
+
+func TestRetryAfterHistogramRestartsWithNewBuckets(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.SleepDisabled = true
+	p.RetryAfterBuckets = []time.Duration{time.Second}
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	assert.Equal(t, map[string]uint64{"1s": 1, "+Inf": 0}, p.RetryAfterHistogram())
+
+	p.RetryAfterBuckets = []time.Duration{10 * time.Millisecond, time.Second}
+	assert.Equal(t, map[string]uint64{"10ms": 0, "1s": 0, "+Inf": 0}, p.RetryAfterHistogram())
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	assert.Equal(t, map[string]uint64{"10ms": 0, "1s": 1, "+Inf": 0}, p.RetryAfterHistogram())
+}
+
+func TestRetryAfterHistogramConcurrentUse(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.SleepDisabled = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				retryTypeOf(p, errors.New(rateLimitedErrMsg))
+				p.RetryAfterHistogram()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(1000), p.RetryAfterHistogram()["50ms"])
+}
+
+func TestDefaultRetryAfterBucketsReturnsACopy(t *testing.T) {
+	buckets := DefaultRetryAfterBuckets()
+	buckets[0] = time.Hour
+	assert.Equal(t, 10*time.Millisecond, DefaultRetryAfterBuckets()[0])
+}
+
+func TestObserveRetryAfterDoesNotAllocate(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.observeRetryAfter(42 * time.Millisecond)
+	allocs := testing.AllocsPerRun(100, func() {
+		p.observeRetryAfter(42 * time.Millisecond)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// RetryStat describes a completed query execution which has failed at least once. See RecentRetries
+type RetryStat struct {
+	// RequestID is the request id the query has been tagged with using WithRequestID, if any
+	RequestID string
+	// Attempts is the number of failed attempts of the query
+	Attempts int
+	// TotalBackOff is the cumulative time spent backing off between attempts
+	TotalBackOff time.Duration
+	// FinalDecision is the last retry decision made for the query, e.g. gocql.Rethrow if it was given up on, or gocql.Retry if the retry succeeded
+	FinalDecision gocql.RetryType
+	// Succeeded is set if the query eventually succeeded
+	Succeeded bool
+}
+
+// retryStats keeps the stats of up to max completed executions in a ring buffer. Once full, the oldest stat is overwritten
+type retryStats struct {
+	sync.Mutex
+	stats []RetryStat
+	next  int
+	full  bool
+}
+
+// EnableRetryStats records the stats of up to maxQueries completed query executions which have failed at least once (the oldest ones are overwritten first). Use RecentRetries to retrieve them. Queries which are given up on are always accounted for, whereas successful ones only are if they are executed with WithAttemptScope and the policy is registered as the gocql query (and batch) observer. A maxQueries of 0 disables it and discards the recorded stats
+func (crp *CosmosRetryPolicy) EnableRetryStats(maxQueries int) {
+	rs := &crp.retryStats
+	rs.Lock()
+	defer rs.Unlock()
+	rs.stats = nil
+	rs.next = 0
+	rs.full = false
+	if maxQueries > 0 {
+		rs.stats = make([]RetryStat, maxQueries)
+	}
+}
+
+// RecentRetries returns the stats recorded since EnableRetryStats, oldest first
+func (crp *CosmosRetryPolicy) RecentRetries() []RetryStat {
+	rs := &crp.retryStats
+	rs.Lock()
+	defer rs.Unlock()
+	if !rs.full {
+		return append([]RetryStat(nil), rs.stats[:rs.next]...)
+	}
+	return append(append([]RetryStat(nil), rs.stats[rs.next:]...), rs.stats[:rs.next]...)
+}
+
+// recordStat records the stat of ex, which has just completed
+func (crp *CosmosRetryPolicy) recordStat(ex *execution, succeeded bool) {
+	if ex.attempts == 0 {
+		return
+	}
+	rs := &crp.retryStats
+	rs.Lock()
+	defer rs.Unlock()
+	if len(rs.stats) == 0 {
+		return
+	}
+	rs.stats[rs.next] = RetryStat{RequestID: ex.requestID, Attempts: ex.attempts, TotalBackOff: ex.totalWait, FinalDecision: ex.decision, Succeeded: succeeded}
+	rs.next++
+	if rs.next == len(rs.stats) {
+		rs.next = 0
+		rs.full = true
+	}
+}
@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// runScopedQuery simulates a query executed with its own attempt scope, which fails failures times before succeeding (or being given up on)
+func runScopedQuery(p *CosmosRetryPolicy, requestID string, failures int) {
+	ctx := WithAttemptScope(WithRequestID(context.Background(), requestID))
+	q := MockRetryableQuery{ctx: ctx}
+	for i := 0; i < failures; i++ {
+		if !p.Attempt(q) {
+			return
+		}
+		p.GetRetryType(errors.New(rateLimitedErrMsg))
+	}
+	p.ObserveQuery(ctx, gocql.ObservedQuery{})
+}
+
+func TestRecentRetries(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.sleepFunc = func(time.Duration) {}
+	p.EnableRetryStats(16)
+
+	// query i fails i times, those failing more than 3 times are given up on
+	const queries = 6
+	var wg sync.WaitGroup
+	for i := 0; i < queries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			runScopedQuery(p, fmt.Sprint(i), i)
+		}(i)
+	}
+	wg.Wait()
+
+	stats := p.RecentRetries()
+	sort.Slice(stats, func(i, j int) bool { return stats[i].RequestID < stats[j].RequestID })
+	expected := []RetryStat{
+		// query 0 did not fail, hence is not accounted for
+		{RequestID: "1", Attempts: 1, TotalBackOff: 42 * time.Millisecond, FinalDecision: gocql.Retry, Succeeded: true},
+		{RequestID: "2", Attempts: 2, TotalBackOff: 84 * time.Millisecond, FinalDecision: gocql.Retry, Succeeded: true},
+		{RequestID: "3", Attempts: 3, TotalBackOff: 126 * time.Millisecond, FinalDecision: gocql.Retry, Succeeded: true},
+		{RequestID: "4", Attempts: 4, TotalBackOff: 126 * time.Millisecond, FinalDecision: gocql.Rethrow},
+		{RequestID: "5", Attempts: 4, TotalBackOff: 126 * time.Millisecond, FinalDecision: gocql.Rethrow},
+	}
+	assert.Equal(t, expected, stats)
+}
+
+func TestRecentRetriesOfUnscopedQueries(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.sleepFunc = func(time.Duration) {}
+	p.EnableRetryStats(4)
+
+	assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{}, 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{}, 2}))
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New("error: today is not your day!")))
+
+	assert.Equal(t, []RetryStat{{Attempts: 2, TotalBackOff: 42 * time.Millisecond, FinalDecision: gocql.Rethrow}}, p.RecentRetries())
+}
+
+func TestRecentRetriesEvictsOldest(t *testing.T) {
+	p := NewCosmosRetryPolicy(0)
+	p.EnableRetryStats(2)
+
+	for i := 0; i < 3; i++ {
+		runScopedQuery(p, fmt.Sprint(i), 1)
+	}
+	stats := p.RecentRetries()
+	if assert.Len(t, stats, 2) {
+		assert.Equal(t, "1", stats[0].RequestID)
+		assert.Equal(t, "2", stats[1].RequestID)
+	}
+}
+
+func TestRecentRetriesDisabled(t *testing.T) {
+	p := NewCosmosRetryPolicy(0)
+	runScopedQuery(p, "1", 1)
+	assert.Empty(t, p.RecentRetries(), "stats should not be recorded by default")
+
+	p.EnableRetryStats(2)
+	runScopedQuery(p, "2", 1)
+	assert.Len(t, p.RecentRetries(), 1)
+
+	p.EnableRetryStats(0)
+	assert.Empty(t, p.RecentRetries())
+}
@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ErrorKind is the category an error is classified into by ClassifyError
+type ErrorKind int
+
+const (
+	// UnclassifiedError is an error no rule applies to
+	UnclassifiedError ErrorKind = iota
+	// RateLimitedError is a rate limited (429) error. It is accounted for as throttling
+	RateLimitedError
+	// NotReadyError is returned while a keyspace or table is being created
+	NotReadyError
+	// TransientError is any other error which is expected to resolve by itself, e.g. timeouts
+	TransientError
+)
+
+func (ek ErrorKind) String() string {
+	switch ek {
+	case RateLimitedError:
+		return "rate-limited"
+	case NotReadyError:
+		return "not-ready"
+	case TransientError:
+		return "transient"
+	default:
+		return "unclassified"
+	}
+}
+
+// ClassificationRule maps error messages matching Pattern to an ErrorKind and the retry decision for it
+type ClassificationRule struct {
+	Pattern   *regexp.Regexp
+	Kind      ErrorKind
+	RetryType gocql.RetryType
+	// BackOff is the time to wait before retrying. For RateLimitedError rules, 0 means the built-in back-off is used (RetryAfterMs, if available, or the fallback back-off)
+	BackOff time.Duration
+}
+
+// rateLimitingPattern matches rate limited (429) errors. It is compiled once, and shared by the rules returned by DefaultClassificationRules since a regexp.Regexp is safe for concurrent use
+var rateLimitingPattern = regexp.MustCompile(regexp.QuoteMeta(rateLimitingErrPart))
+
+// DefaultClassificationRules returns the built-in rules, which are evaluated after ClassificationRules. Rate limited (429) errors are retried using the built-in back-off
+func DefaultClassificationRules() []ClassificationRule {
+	return []ClassificationRule{
+		{Pattern: rateLimitingPattern, Kind: RateLimitedError, RetryType: gocql.Retry},
+	}
+}
+
+var defaultClassificationRules = DefaultClassificationRules()
+
+// matchRule returns the first of ClassificationRules, followed by the default rules, matching errMsg
+func (crp *CosmosRetryPolicy) matchRule(errMsg string) (ClassificationRule, bool) {
+	for _, rules := range [][]ClassificationRule{crp.ClassificationRules, defaultClassificationRules} {
+		for _, rule := range rules {
+			if rule.Pattern != nil && rule.Pattern.MatchString(errMsg) {
+				return rule, true
+			}
+		}
+	}
+	return ClassificationRule{}, false
+}
+
+// ClassifyError returns the ErrorKind of err as per ClassificationRules and the default rules. Timeouts and coordinator connection errors are classified as TransientError. Direct mode rate limited errors (see DirectThrottleMarker) and errors matching NotReadyMarker are classified as well
+func (crp *CosmosRetryPolicy) ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return UnclassifiedError
+	}
+	if isTimeout(err) || isClientTimeout(err) || isConnectionError(err) {
+		return TransientError
+	}
+	if rule, ok := crp.matchRule(err.Error()); ok {
+		return rule.Kind
+	}
+	if crp.ClassifyThrottle(err) != NotThrottled {
+		return RateLimitedError
+	}
+	if crp.isNotReady(err.Error()) {
+		return NotReadyError
+	}
+	return UnclassifiedError
+}
+
+// builtIn reports whether the rule defers to the built-in rate limited error handling
+func (rule ClassificationRule) builtIn() bool {
+	return rule.Kind == RateLimitedError && rule.RetryType == gocql.Retry && rule.BackOff == 0
+}
+
+// applyRule makes the retry decision defined by rule
+func (crp *CosmosRetryPolicy) applyRule(ex *execution, rule ClassificationRule) gocql.RetryType {
+	crp.recordThrottle(ex, rule.Kind == RateLimitedError)
+	crp.recordPath(ex, DecisionPathRule)
+	if rule.RetryType != gocql.Retry && rule.RetryType != gocql.RetryNextHost {
+		return rule.RetryType
+	}
+	if !crp.backOff(ex, rule.BackOff) {
+		return gocql.Rethrow
+	}
+	return rule.RetryType
+}
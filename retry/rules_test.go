@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassificationRules(t *testing.T) {
+	type testCase struct {
+		name          string
+		errMsg        string
+		expectedType  gocql.RetryType
+		expectedPath  string
+		expectedSleep time.Duration
+	}
+
+	rules := []ClassificationRule{
+		{Pattern: regexp.MustCompile(`(?i)server is busy`), Kind: TransientError, RetryType: gocql.Retry, BackOff: 5 * time.Millisecond},
+		{Pattern: regexp.MustCompile(`(?s)Substatus: 3200.*do not retry`), Kind: RateLimitedError, RetryType: gocql.Rethrow},
+		{Pattern: regexp.MustCompile(`throughput exceeded`), Kind: RateLimitedError, RetryType: gocql.Retry},
+	}
+
+	testCases := []testCase{
+		{"custom rule with fixed back-off", "Server is busy, try again", gocql.Retry, DecisionPathRule, 5 * time.Millisecond},
+		{"custom rule overriding the default 429 rule", rateLimitedErrMsg + " do not retry", gocql.Rethrow, DecisionPathRule, 0},
+		{"custom rule deferring to the built-in back-off", "throughput exceeded", gocql.Retry, DecisionPathFixedFallback, 10 * time.Millisecond},
+		{"falls through to the default 429 rule", rateLimitedErrMsg, gocql.Retry, DecisionPathServerHinted, 42 * time.Millisecond},
+		{"falls through to the built-in logic", "error: today is not your day!", gocql.Rethrow, DecisionPathNotRetryable, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.JitterMode = NoJitter
+			p.FixedBackOffTimeMs = 10
+			p.ClassificationRules = rules
+
+			assert.Equal(te, tc.expectedType, retryTypeOf(p, errors.New(tc.errMsg)))
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+			assert.Equal(te, tc.expectedSleep, p.BlockedTime())
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.NotReadyMarker = "unconfigured table"
+	p.ClassificationRules = []ClassificationRule{
+		{Pattern: regexp.MustCompile(`overloaded`), Kind: TransientError, RetryType: gocql.Retry},
+	}
+
+	assert.Equal(t, RateLimitedError, p.ClassifyError(errors.New(rateLimitedErrMsg)))
+	assert.Equal(t, RateLimitedError, p.ClassifyError(errors.New(directRateLimitedErrMsg)))
+	assert.Equal(t, NotReadyError, p.ClassifyError(errors.New(notReadyErrMsg)))
+	assert.Equal(t, TransientError, p.ClassifyError(errors.New("coordinator overloaded")))
+	assert.Equal(t, UnclassifiedError, p.ClassifyError(errors.New("syntax error")))
+	assert.Equal(t, TransientError, p.ClassifyError(&gocql.RequestErrWriteTimeout{}))
+	assert.Equal(t, TransientError, p.ClassifyError(gocql.ErrTimeoutNoResponse))
+	assert.Equal(t, UnclassifiedError, p.ClassifyError(nil))
+	assert.Equal(t, "transient", TransientError.String())
+}
+
+func TestDefaultClassificationRulesCompiledOnce(t *testing.T) {
+	assert.True(t, DefaultClassificationRules()[0].Pattern == DefaultClassificationRules()[0].Pattern)
+	assert.True(t, rateLimitingPattern == defaultClassificationRules[0].Pattern)
+}
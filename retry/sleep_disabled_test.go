@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSleepDisabled(t *testing.T) {
+	type testCase struct {
+		name            string
+		err             error
+		expectedType    gocql.RetryType
+		expectedBackOff time.Duration
+	}
+
+	testCases := []testCase{
+		{"rate limited with RetryAfterMs", errors.New(rateLimitedErrMsg), gocql.Retry, 42 * time.Millisecond},
+		{"rate limited without RetryAfterMs", errors.New(rateLimitedErrMsgWithoutRetryAfterMs), gocql.Retry, 5 * time.Second},
+		{"timeout", &gocql.RequestErrWriteTimeout{}, gocql.Retry, 0},
+		{"non retryable error", errors.New("syntax error"), gocql.Rethrow, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.JitterMode = NoJitter
+			p.SleepDisabled = true
+			var events []RetryEvent
+			p.OnRetry = func(e RetryEvent) { events = append(events, e) }
+
+			start := time.Now()
+			p.Attempt(attemptsQuery{attempts: 1})
+			assert.Equal(te, tc.expectedType, p.GetRetryType(tc.err))
+			assert.True(te, time.Since(start) < time.Second)
+			assert.Equal(te, time.Duration(0), p.BlockedTime())
+
+			assert.Len(te, events, 1)
+			assert.Equal(te, tc.expectedBackOff, events[0].BackOff)
+		})
+	}
+}
+
+func TestSleepDisabledHonorsElapsedBudget(t *testing.T) {
+	p := NewCosmosRetryPolicy(-1)
+	p.SleepDisabled = true
+	p.FixedBackOffTimeMs = 1000
+	p.MaxElapsedTimeMs = 2500
+	p.JitterMode = NoJitter
+	p.MaxBackOffTimeMs = 1000
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		p.Attempt(attemptsQuery{attempts: attempt})
+		assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs)))
+	}
+	p.Attempt(attemptsQuery{attempts: 3})
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs)))
+	assert.Equal(t, DecisionPathElapsedBudget, p.LastDecisionPath())
+}
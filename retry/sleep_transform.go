@@ -0,0 +1,14 @@
+package retry
+
+import "time"
+
+// sleepDuration applies SleepTransform (if configured) to a computed back-off. Negative results are treated as no back-off
+func (crp *CosmosRetryPolicy) sleepDuration(d time.Duration) time.Duration {
+	if crp.SleepTransform == nil {
+		return d
+	}
+	if d = crp.SleepTransform(d); d < 0 {
+		return 0
+	}
+	return d
+}
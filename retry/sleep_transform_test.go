@@ -0,0 +1,50 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSleepTransformHalvesWaits(t *testing.T) {
+	type testCase struct {
+		name     string
+		err      error
+		attempts int
+		expected time.Duration
+	}
+
+	testCases := []testCase{
+		{"rate limited error with RetryAfterMs", errors.New(rateLimitedErrMsg), 1, 21 * time.Millisecond},
+		{"rate limited error without RetryAfterMs", errors.New(rateLimitedErrMsgWithoutRetryAfterMs), 1, 10 * time.Millisecond},
+		{"timeout after immediate retries", &gocql.RequestErrWriteTimeout{}, 2, 10 * time.Millisecond},
+		{"keyspace or table not ready", errors.New(notReadyErrMsg), 1, 15 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			p.JitterMode = NoJitter
+			p.FixedBackOffTimeMs = 20
+			p.ImmediateRetries = 1
+			p.NotReadyMarker = "unconfigured table"
+			p.NotReadyBackOffTimeMs = 30
+			p.SleepTransform = func(d time.Duration) time.Duration { return d / 2 }
+
+			assert.True(te, p.Attempt(attemptsQuery{attempts: tc.attempts}))
+			assert.Equal(te, gocql.Retry, p.GetRetryType(tc.err))
+			assert.Equal(te, tc.expected, p.BlockedTime())
+		})
+	}
+}
+
+func TestSleepTransformNegativeMeansNoWait(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.SleepTransform = func(d time.Duration) time.Duration { return -d }
+
+	assert.Equal(t, gocql.Retry, retryTypeOf(p, errors.New(rateLimitedErrMsg)))
+	assert.Equal(t, time.Duration(0), p.BlockedTime())
+}
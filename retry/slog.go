@@ -0,0 +1,42 @@
+//go:build go1.21
+// +build go1.21
+
+package retry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// slogLogger is a Logger backed by a slog.Logger
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns a Logger which writes to l. Messages are logged at the Info level. Retry decisions (see LogDecisions) are logged as "retry decision" records with the attempt, decision, backoff_ms, retry_after_ms (only if the error carries RetryAfterMs), rate_limited and path attributes, without formatting a message
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+func (sl slogLogger) Printf(format string, v ...interface{}) {
+	sl.l.Info(fmt.Sprintf(format, v...))
+}
+
+func (sl slogLogger) logDecision(ctx context.Context, d loggedDecision) {
+	const msg = "retry decision"
+	if !sl.l.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+	attempt := slog.Int("attempt", d.attempt)
+	decision := slog.String("decision", d.decision)
+	backOff := slog.Int64("backoff_ms", int64(d.backOff/time.Millisecond))
+	rateLimited := slog.Bool("rate_limited", d.rateLimited)
+	path := slog.String("path", d.path)
+	if d.hinted {
+		sl.l.LogAttrs(ctx, slog.LevelInfo, msg, attempt, decision, backOff, slog.Int64("retry_after_ms", int64(d.retryAfter/time.Millisecond)), rateLimited, path)
+		return
+	}
+	sl.l.LogAttrs(ctx, slog.LevelInfo, msg, attempt, decision, backOff, rateLimited, path)
+}
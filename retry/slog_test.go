@@ -0,0 +1,97 @@
+//go:build go1.21
+// +build go1.21
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingHandler is a slog.Handler which keeps the records it handles
+type recordingHandler struct {
+	mu      sync.Mutex
+	level   slog.Level
+	records []slog.Record
+}
+
+func (rh *recordingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= rh.level
+}
+
+func (rh *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	rh.records = append(rh.records, r.Clone())
+	return nil
+}
+
+func (rh *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return rh }
+
+func (rh *recordingHandler) WithGroup(string) slog.Handler { return rh }
+
+// attrs returns the attributes of r, keyed by their name
+func attrs(r slog.Record) map[string]interface{} {
+	m := map[string]interface{}{}
+	r.Attrs(func(a slog.Attr) bool {
+		m[a.Key] = a.Value.Any()
+		return true
+	})
+	return m
+}
+
+func TestSlogLoggerLogsDecisions(t *testing.T) {
+	type testCase struct {
+		name     string
+		err      error
+		expected map[string]interface{}
+	}
+
+	testCases := []testCase{
+		{"rate limited error with RetryAfterMs", errors.New(rateLimitedErrMsg), map[string]interface{}{"attempt": int64(2), "decision": "retry", "backoff_ms": int64(42), "retry_after_ms": int64(42), "rate_limited": true, "path": DecisionPathServerHinted}},
+		{"error other than rate limiting", errors.New("error: today is not your day!"), map[string]interface{}{"attempt": int64(2), "decision": "rethrow", "backoff_ms": int64(0), "rate_limited": false, "path": DecisionPathNotRetryable}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			h := &recordingHandler{}
+			p := NewCosmosRetryPolicy(5)
+			p.Logger = NewSlogLogger(slog.New(h))
+			p.LogDecisions = true
+			p.sleepFunc = func(time.Duration) {}
+
+			assert.True(te, p.Attempt(attemptsQuery{MockRetryableQuery{}, 2}))
+			p.GetRetryType(tc.err)
+			if assert.Len(te, h.records, 1) {
+				assert.Equal(te, "retry decision", h.records[0].Message)
+				assert.Equal(te, slog.LevelInfo, h.records[0].Level)
+				assert.Equal(te, tc.expected, attrs(h.records[0]))
+			}
+		})
+	}
+}
+
+func TestSlogLoggerPrintf(t *testing.T) {
+	h := &recordingHandler{}
+	p := NewCosmosRetryPolicy(-1)
+	p.SustainedThrottleThreshold = 1
+	p.Logger = NewSlogLogger(slog.New(h))
+	p.sleepFunc = func(time.Duration) {}
+
+	retryTypeOf(p, errors.New(rateLimitedErrMsg))
+	if assert.Len(t, h.records, 1) {
+		assert.Contains(t, h.records[0].Message, "sustained 429s detected")
+	}
+}
+
+func TestSlogLoggerDisabledLevelDoesNotAllocate(t *testing.T) {
+	sl := NewSlogLogger(slog.New(&recordingHandler{level: slog.LevelWarn})).(decisionLogger)
+	d := loggedDecision{attempt: 2, decision: "retry", backOff: 42 * time.Millisecond, retryAfter: 42 * time.Millisecond, hinted: true, rateLimited: true, path: DecisionPathServerHinted}
+	assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() { sl.logDecision(context.Background(), d) }))
+}
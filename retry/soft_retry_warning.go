@@ -0,0 +1,19 @@
+package retry
+
+const softRetryWarning = "query attempted %d times, reaching SoftRetryWarnThreshold (%d): retries may be masking a persistent problem%s"
+
+// warnSoftRetryThreshold logs a warning, once per query, when the attempt count of ex reaches SoftRetryWarnThreshold. Health check queries are not accounted for
+func (crp *CosmosRetryPolicy) warnSoftRetryThreshold(ex *execution) {
+	if crp.SoftRetryWarnThreshold <= 0 || ex.attempts < crp.SoftRetryWarnThreshold || ex.warned || ex.healthCheck {
+		return
+	}
+	ex.warned = true
+	if crp.Logger == nil {
+		return
+	}
+	requestID := ""
+	if ex.requestID != "" {
+		requestID = " (request " + ex.requestID + ")"
+	}
+	crp.Logger.Printf(softRetryWarning, ex.attempts, crp.SoftRetryWarnThreshold, requestID)
+}
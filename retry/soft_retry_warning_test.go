@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftRetryWarnThreshold(t *testing.T) {
+	type testCase struct {
+		name             string
+		threshold        int
+		query            func(attempt int) gocql.RetryableQuery
+		attempts         int
+		expectedWarnings int
+	}
+
+	scoped := WithAttemptScope(context.Background())
+	testCases := []testCase{
+		{"disabled by default", 0, func(attempt int) gocql.RetryableQuery { return attemptsQuery{attempts: attempt} }, 6, 0},
+		{"below the threshold", 5, func(attempt int) gocql.RetryableQuery { return attemptsQuery{attempts: attempt} }, 4, 0},
+		{"warned once past the threshold", 3, func(attempt int) gocql.RetryableQuery { return attemptsQuery{attempts: attempt} }, 6, 1},
+		{"distinct queries are warned about separately", 3, func(attempt int) gocql.RetryableQuery { return &pointerQuery{attempts: attempt} }, 6, 4},
+		{"warned once per scope", 3, func(int) gocql.RetryableQuery { return MockRetryableQuery{ctx: scoped} }, 6, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(10)
+			p.SleepDisabled = true
+			p.SoftRetryWarnThreshold = tc.threshold
+			logger := &capturingLogger{}
+			p.Logger = logger
+
+			for attempt := 1; attempt <= tc.attempts; attempt++ {
+				assert.True(te, p.Attempt(tc.query(attempt)))
+				assert.Equal(te, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+			}
+			assert.Equal(te, tc.expectedWarnings, logger.count("SoftRetryWarnThreshold"))
+		})
+	}
+}
+
+func TestSoftRetryWarnThresholdWarnsAgainForNextQuery(t *testing.T) {
+	p := NewCosmosRetryPolicy(10)
+	p.SleepDisabled = true
+	p.SoftRetryWarnThreshold = 2
+	logger := &capturingLogger{}
+	p.Logger = logger
+
+	ctx := WithRequestID(context.Background(), "request")
+	for query := 0; query < 2; query++ {
+		for attempt := 1; attempt <= 4; attempt++ {
+			assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{ctx: ctx}, attempt}))
+			p.GetRetryType(errors.New(rateLimitedErrMsg))
+		}
+	}
+	assert.Equal(t, 2, logger.count("query attempted 2 times, reaching SoftRetryWarnThreshold (2): retries may be masking a persistent problem (request request)"))
+}
+
+func TestSoftRetryWarnThresholdDoesNotAffectRetries(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.SleepDisabled = true
+	p.SoftRetryWarnThreshold = 1
+	p.Logger = &capturingLogger{}
+
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 3}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsg)))
+	assert.False(t, p.Attempt(attemptsQuery{attempts: 4}))
+}
@@ -0,0 +1,43 @@
+package retry
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// defaultSpeculativeDelay is the delay used when CosmosSpeculativeExecutionPolicy.SpeculativeDelay is not set. It is well above the single digit millisecond latencies of in-region Cosmos DB point reads, so that only slow executions are raced
+const defaultSpeculativeDelay = 100 * time.Millisecond
+
+// CosmosSpeculativeExecutionPolicy implements gocql.SpeculativeExecutionPolicy. If an execution has not completed after SpeculativeDelay, another one is started (on the next host), up to AdditionalExecutions times, which trims tail latencies e.g. against a multi-region account. gocql only executes idempotent queries speculatively (see gocql.Query.Idempotent). Each execution is retried as per the retry policy of the query on its own: speculative executions which are rate limited (429) still back off as per RetryAfterMs, hence keep AdditionalExecutions low, as every speculative execution consumes RUs as well
+//
+//	query.SetSpeculativeExecutionPolicy(retry.NewCosmosSpeculativeExecutionPolicy(1, 50*time.Millisecond))
+type CosmosSpeculativeExecutionPolicy struct {
+	// AdditionalExecutions is the maximum number of executions started on top of the first one. 0 (or less) disables speculative execution
+	AdditionalExecutions int
+	// SpeculativeDelay is the time to wait for an execution to complete before starting the next one. 0 (or less) means 100ms
+	SpeculativeDelay time.Duration
+}
+
+var _ gocql.SpeculativeExecutionPolicy = (*CosmosSpeculativeExecutionPolicy)(nil)
+
+// NewCosmosSpeculativeExecutionPolicy returns a CosmosSpeculativeExecutionPolicy starting up to additionalExecutions executions, delay apart
+func NewCosmosSpeculativeExecutionPolicy(additionalExecutions int, delay time.Duration) *CosmosSpeculativeExecutionPolicy {
+	return &CosmosSpeculativeExecutionPolicy{AdditionalExecutions: additionalExecutions, SpeculativeDelay: delay}
+}
+
+// Attempts returns the maximum number of additional executions
+func (csp *CosmosSpeculativeExecutionPolicy) Attempts() int {
+	if csp.AdditionalExecutions < 0 {
+		return 0
+	}
+	return csp.AdditionalExecutions
+}
+
+// Delay returns the time between executions. It is always positive, as gocql uses it as a ticker interval
+func (csp *CosmosSpeculativeExecutionPolicy) Delay() time.Duration {
+	if csp.SpeculativeDelay <= 0 {
+		return defaultSpeculativeDelay
+	}
+	return csp.SpeculativeDelay
+}
@@ -0,0 +1,34 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosmosSpeculativeExecutionPolicy(t *testing.T) {
+	type testCase struct {
+		name             string
+		policy           *CosmosSpeculativeExecutionPolicy
+		expectedAttempts int
+		expectedDelay    time.Duration
+	}
+
+	testCases := []testCase{
+		{"configured attempts and delay", NewCosmosSpeculativeExecutionPolicy(2, 50*time.Millisecond), 2, 50 * time.Millisecond},
+		{"no additional executions", NewCosmosSpeculativeExecutionPolicy(0, 50*time.Millisecond), 0, 50 * time.Millisecond},
+		{"negative additional executions disable speculative execution", NewCosmosSpeculativeExecutionPolicy(-1, 50*time.Millisecond), 0, 50 * time.Millisecond},
+		{"zero delay falls back to the default", NewCosmosSpeculativeExecutionPolicy(1, 0), 1, defaultSpeculativeDelay},
+		{"negative delay falls back to the default", NewCosmosSpeculativeExecutionPolicy(1, -time.Second), 1, defaultSpeculativeDelay},
+		{"zero value", &CosmosSpeculativeExecutionPolicy{}, 0, defaultSpeculativeDelay},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expectedAttempts, tc.policy.Attempts())
+			assert.Equal(te, tc.expectedDelay, tc.policy.Delay())
+			assert.True(te, tc.policy.Delay() > 0, "delay must be positive to be used as a ticker interval")
+		})
+	}
+}
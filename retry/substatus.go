@@ -0,0 +1,68 @@
+package retry
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subStatusKeys precede the Cosmos DB substatus code in gateway and direct mode error messages respectively
+var subStatusKeys = []string{"Substatus: ", "SubStatusCode: "}
+
+// parseSubStatus extracts the Cosmos DB substatus code (e.g. 3200 for rate limiting) from an error message. ok is false if it is not available
+func parseSubStatus(errMsg string) (subStatus int, ok bool) {
+	for _, key := range subStatusKeys {
+		i := strings.Index(errMsg, key)
+		if i == -1 {
+			continue
+		}
+		code := errMsg[i+len(key):]
+		if end := strings.IndexAny(code, ",; '"); end != -1 {
+			code = code[:end]
+		}
+		if s, err := strconv.Atoi(code); err == nil {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// ParseCosmosError extracts RetryAfterMs and the substatus code (e.g. 3200 for provisioned throughput throttling) from a Cosmos DB error message, so that callers can log or branch on them. Either is 0 if the message does not carry it. ok is false if errMsg is not a Cosmos DB error
+func ParseCosmosError(errMsg string) (retryAfterMs time.Duration, substatus int, ok bool) {
+	ce := parseCosmosError(errMsg)
+	return ce.retryAfter, ce.subStatus, ce.ok
+}
+
+// subStatusBackOff returns the back-off configured in SubStatusBackOff for subStatus. ok is false if there is none
+func (crp *CosmosRetryPolicy) subStatusBackOff(ex *execution, subStatus int) (backOff time.Duration, ok bool) {
+	if subStatus == 0 {
+		return 0, false
+	}
+	if backOff, ok = crp.SubStatusBackOff[subStatus]; ok {
+		crp.recordPath(ex, DecisionPathSubStatusOverride)
+	}
+	return backOff, ok
+}
+
+// cosmosError holds what is extracted from a Cosmos DB error message
+type cosmosError struct {
+	retryAfter time.Duration
+	// hinted is set if the message carries RetryAfterMs, which may be 0
+	hinted    bool
+	subStatus int
+	ok        bool
+}
+
+func parseCosmosError(errMsg string) cosmosError {
+	return cosmosErrorOf(nil, errMsg)
+}
+
+// cosmosErrorOf is like parseCosmosError, but RetryAfterMs is taken from the structured fields of err, the error errMsg is the message of, if available (see RetryAfterError). err may be nil
+func cosmosErrorOf(err error, errMsg string) cosmosError {
+	var ce cosmosError
+	ce.retryAfter, ce.hinted = retryAfterOf(err, errMsg)
+	subStatus, hasSubStatus := parseSubStatus(errMsg)
+	ce.subStatus = subStatus
+	ce.ok = ce.hinted || hasSubStatus || isRateLimited(errMsg) || parseActivityID(errMsg) != ""
+	return ce
+}
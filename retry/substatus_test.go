@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCosmosError(t *testing.T) {
+	type testCase struct {
+		name       string
+		errMsg     string
+		retryAfter time.Duration
+		substatus  int
+		ok         bool
+	}
+
+	testCases := []testCase{
+		{"gateway error with RetryAfterMs and substatus", rateLimitedErrMsg, 42 * time.Millisecond, 3200, true},
+		{"gateway error without RetryAfterMs", rateLimitedErrMsgWithoutRetryAfterMs, 0, 3200, true},
+		{"direct error with substatus", directRateLimitedErrMsg, 0, 3200, true},
+		{"direct error with RetryAfterMs", directRateLimitedErrMsgWithRetryAfterMs, 120 * time.Millisecond, 3200, true},
+		{"error without substatus", "Request rate is large: ActivityID=abc, RetryAfterMs=7", 7 * time.Millisecond, 0, true},
+		{"rate limited error with nothing else", "TooManyRequests (429)", 0, 0, true},
+		{"non Cosmos DB error", "gocql: no response received from cassandra within timeout period", 0, 0, false},
+		{"empty message", "", 0, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			retryAfter, substatus, ok := ParseCosmosError(tc.errMsg)
+			assert.Equal(te, tc.retryAfter, retryAfter)
+			assert.Equal(te, tc.substatus, substatus)
+			assert.Equal(te, tc.ok, ok)
+		})
+	}
+}
+
+func TestSubStatusBackOff(t *testing.T) {
+	type testCase struct {
+		name         string
+		errMsg       string
+		expected     time.Duration
+		expectedPath string
+	}
+
+	p := NewCosmosRetryPolicy(5)
+	p.JitterMode = NoJitter
+	p.SubStatusBackOff = map[int]time.Duration{3200: 250 * time.Millisecond, 3201: 10 * time.Second}
+
+	testCases := []testCase{
+		{"RU throttling (3200) overrides RetryAfterMs", rateLimitedErrMsg, 250 * time.Millisecond, DecisionPathSubStatusOverride},
+		{"RU throttling (3200) without RetryAfterMs", rateLimitedErrMsgWithoutRetryAfterMs, 250 * time.Millisecond, DecisionPathSubStatusOverride},
+		{"direct mode RU throttling (3200)", directRateLimitedErrMsg, 250 * time.Millisecond, DecisionPathSubStatusOverride},
+		{"mapped system throttling substatus", strings.Replace(rateLimitedErrMsg, "Substatus: 3200", "Substatus: 3201", 1), 10 * time.Second, DecisionPathSubStatusOverride},
+		{"unmapped substatus honors RetryAfterMs", strings.Replace(rateLimitedErrMsg, "Substatus: 3200", "Substatus: 3092", 1), 42 * time.Millisecond, DecisionPathServerHinted},
+		{"unmapped substatus without RetryAfterMs", strings.Replace(rateLimitedErrMsgWithoutRetryAfterMs, "Substatus: 3200", "Substatus: 3092", 1), time.Duration(p.FixedBackOffTimeMs) * time.Millisecond, DecisionPathFixedFallback},
+		{"no substatus", "Request rate is large: ActivityID=abc, RetryAfterMs=7", 7 * time.Millisecond, DecisionPathServerHinted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, retryAfter(te, p, tc.errMsg))
+			assert.Equal(te, tc.expectedPath, p.exec.path)
+		})
+	}
+}
+
+func TestSubStatusBackOffNotSetByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	assert.Equal(t, 42*time.Millisecond, retryAfter(t, p, rateLimitedErrMsg))
+	assert.Equal(t, DecisionPathServerHinted, p.exec.path)
+}
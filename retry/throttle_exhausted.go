@@ -0,0 +1,12 @@
+package retry
+
+// throttleExhausted calls OnThrottleExhausted, if set, provided every error decided for ex has been a rate limited (429) error. A panic in OnThrottleExhausted is recovered from (and ignored), so that it cannot disrupt the query
+func (crp *CosmosRetryPolicy) throttleExhausted(ex *execution) {
+	if crp.OnThrottleExhausted == nil || !ex.rateLimited || ex.notRateLimited {
+		return
+	}
+	defer func() {
+		recover()
+	}()
+	crp.OnThrottleExhausted(ex.attempts, ex.activityID)
+}
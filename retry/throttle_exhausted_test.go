@@ -0,0 +1,112 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+type throttleExhaustedCall struct {
+	attempts   int
+	activityID string
+}
+
+func TestOnThrottleExhausted(t *testing.T) {
+	type testCase struct {
+		name     string
+		errs     []error
+		scoped   bool
+		expected []throttleExhaustedCall
+	}
+
+	rateLimited := errors.New(rateLimitedErrMsg)
+	testCases := []testCase{
+		{"rate limited errors only", []error{rateLimited, rateLimited, rateLimited}, false, []throttleExhaustedCall{{4, "c268afb6-7367-4ff8-b06b-b7e2d1269f55"}}},
+		{"rate limited errors only with attempt scope", []error{rateLimited, rateLimited, rateLimited}, true, []throttleExhaustedCall{{4, "c268afb6-7367-4ff8-b06b-b7e2d1269f55"}}},
+		{"timeout first", []error{&gocql.RequestErrWriteTimeout{}, rateLimited, rateLimited}, false, nil},
+		{"timeout last", []error{rateLimited, rateLimited, &gocql.RequestErrWriteTimeout{}}, false, nil},
+		{"timeouts only", []error{&gocql.RequestErrWriteTimeout{}, &gocql.RequestErrWriteTimeout{}, &gocql.RequestErrWriteTimeout{}}, false, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(len(tc.errs))
+			p.sleepFunc = func(time.Duration) {}
+			var calls []throttleExhaustedCall
+			p.OnThrottleExhausted = func(attempts int, lastActivityID string) {
+				calls = append(calls, throttleExhaustedCall{attempts, lastActivityID})
+			}
+			ctx := WithAttemptScope(context.Background())
+			query := func(attempt int) gocql.RetryableQuery {
+				if tc.scoped {
+					return MockRetryableQuery{ctx: ctx}
+				}
+				return attemptsQuery{attempts: attempt}
+			}
+
+			for i, err := range tc.errs {
+				assert.True(te, p.Attempt(query(i+1)))
+				p.GetRetryType(err)
+			}
+			assert.Empty(te, calls)
+			assert.False(te, p.Attempt(query(len(tc.errs)+1)))
+			assert.Equal(te, tc.expected, calls)
+		})
+	}
+}
+
+func TestOnThrottleExhaustedNotCalledWithinRetries(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.sleepFunc = func(time.Duration) {}
+	called := false
+	p.OnThrottleExhausted = func(int, string) { called = true }
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		assert.True(t, p.Attempt(attemptsQuery{attempts: attempt}))
+		p.GetRetryType(errors.New(rateLimitedErrMsg))
+	}
+	assert.False(t, called)
+}
+
+func TestOnThrottleExhaustedStartsAfresh(t *testing.T) {
+	p := NewCosmosRetryPolicy(1)
+	p.sleepFunc = func(time.Duration) {}
+	calls := 0
+	p.OnThrottleExhausted = func(int, string) { calls++ }
+
+	// a timeout of a previous query does not prevent the callback for the next one
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 1}))
+	p.GetRetryType(&gocql.RequestErrWriteTimeout{})
+	assert.False(t, p.Attempt(attemptsQuery{attempts: 2}))
+	assert.Equal(t, 0, calls)
+
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 1}))
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+	assert.False(t, p.Attempt(attemptsQuery{attempts: 2}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestOnThrottleExhaustedPanicIsRecovered(t *testing.T) {
+	p := NewCosmosRetryPolicy(1)
+	p.sleepFunc = func(time.Duration) {}
+	p.OnThrottleExhausted = func(int, string) { panic("broken hook") }
+
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 1}))
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+	assert.NotPanics(t, func() {
+		assert.False(t, p.Attempt(attemptsQuery{attempts: 2}))
+	})
+}
+
+func TestOnThrottleExhaustedUnset(t *testing.T) {
+	p := NewCosmosRetryPolicy(1)
+	p.sleepFunc = func(time.Duration) {}
+
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 1}))
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+	assert.False(t, p.Attempt(attemptsQuery{attempts: 2}))
+}
@@ -0,0 +1,88 @@
+package retry
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ThrottleMode identifies the connectivity mode a rate limited (429) error originated from
+type ThrottleMode int
+
+const (
+	// NotThrottled means the error is not a rate limited error
+	NotThrottled ThrottleMode = iota
+	// GatewayThrottle is a rate limited error reported by the Cosmos DB gateway (the default for Cassandra API)
+	GatewayThrottle
+	// DirectThrottle is a rate limited error reported in direct connectivity mode
+	DirectThrottle
+)
+
+func (tm ThrottleMode) String() string {
+	switch tm {
+	case GatewayThrottle:
+		return "gateway"
+	case DirectThrottle:
+		return "direct"
+	default:
+		return "none"
+	}
+}
+
+const defaultDirectThrottleMarker = "StatusCode: 429"
+
+// defaultThrottleErrorCode is the CQL protocol Overloaded error code
+const defaultThrottleErrorCode = 0x1001
+
+// ClassifyThrottle reports whether err is a rate limited error and, if so, the connectivity mode it originated from. Errors carrying ThrottleErrorCode are recognized without parsing their message
+func (crp *CosmosRetryPolicy) ClassifyThrottle(err error) ThrottleMode {
+	if err == nil {
+		return NotThrottled
+	}
+	if crp.hasThrottleErrorCode(err) {
+		return GatewayThrottle
+	}
+	return crp.throttleMode(err.Error())
+}
+
+// hasThrottleErrorCode reports whether err is a gocql.RequestError carrying ThrottleErrorCode
+func (crp *CosmosRetryPolicy) hasThrottleErrorCode(err error) bool {
+	var reqErr gocql.RequestError
+	return crp.ThrottleErrorCode != 0 && errors.As(err, &reqErr) && reqErr.Code() == crp.ThrottleErrorCode
+}
+
+func (crp *CosmosRetryPolicy) throttleMode(errMsg string) ThrottleMode {
+	if strings.Contains(errMsg, rateLimitingErrPart) {
+		return GatewayThrottle
+	}
+	// direct mode errors are worded much like gateway ones, hence they are told apart before the lenient gateway detection
+	if crp.DirectThrottleMarker != "" && strings.Contains(errMsg, crp.DirectThrottleMarker) {
+		return DirectThrottle
+	}
+	if isRateLimited(errMsg) {
+		return GatewayThrottle
+	}
+	// errors classified as rate limited by a ClassificationRule deferring to the built-in back-off
+	if rule, ok := crp.matchRule(errMsg); ok && rule.builtIn() {
+		return GatewayThrottle
+	}
+	return NotThrottled
+}
+
+// directBackOff returns the back-off for the given attempt of direct mode throttles which do not carry RetryAfterMs
+func (crp *CosmosRetryPolicy) directBackOff(c gocql.Consistency, attempts int, activityID string) time.Duration {
+	if crp.DirectBackOffTimeMs > 0 {
+		return time.Duration(crp.DirectBackOffTimeMs) * time.Millisecond
+	}
+	return crp.fallbackBackOff(c, attempts, activityID)
+}
+
+// directPath returns the decision path matching the back-off chosen by directBackOff
+func (crp *CosmosRetryPolicy) directPath(c gocql.Consistency) string {
+	if crp.DirectBackOffTimeMs > 0 {
+		return DecisionPathDirectFallback
+	}
+	return crp.fallbackPath(c)
+}
@@ -0,0 +1,156 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+const directRateLimitedErrMsg = `Response status code does not indicate success: StatusCode: 429, SubStatusCode: 3200, ActivityId: 0a3b7c2e-5d1f-4e0a-9a6b-3c2d1e0f9a8b, Reason: Request rate is large. More Request Units may be needed, so no changes were made. Please retry this request later.`
+
+const directRateLimitedErrMsgWithRetryAfterMs = `Response status code does not indicate success: StatusCode: 429, RetryAfterMs=120, SubStatusCode: 3200, ActivityId: 0a3b7c2e-5d1f-4e0a-9a6b-3c2d1e0f9a8b, Reason: Request rate is large.`
+
+func TestClassifyThrottle(t *testing.T) {
+	type testCase struct {
+		name     string
+		err      error
+		expected ThrottleMode
+	}
+
+	testCases := []testCase{
+		{"gateway rate limited error", errors.New(rateLimitedErrMsg), GatewayThrottle},
+		{"gateway rate limited error without RetryAfterMs", errors.New(rateLimitedErrMsgWithoutRetryAfterMs), GatewayThrottle},
+		{"direct mode rate limited error", errors.New(directRateLimitedErrMsg), DirectThrottle},
+		{"error other than rate limiting", errors.New("error: today is not your day!"), NotThrottled},
+		{"nil error", nil, NotThrottled},
+	}
+
+	p := NewCosmosRetryPolicy(5)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, p.ClassifyThrottle(tc.err))
+		})
+	}
+}
+
+func TestRetryDurationPerThrottleMode(t *testing.T) {
+	type testCase struct {
+		name           string
+		errMsg         string
+		expectedResult time.Duration
+	}
+
+	p := NewCosmosRetryPolicy(5)
+	p.JitterMode = NoJitter
+	p.DirectBackOffTimeMs = 750
+
+	testCases := []testCase{
+		{"gateway throttle without RetryAfterMs uses fixed back-off", rateLimitedErrMsgWithoutRetryAfterMs, time.Duration(p.FixedBackOffTimeMs) * time.Millisecond},
+		{"direct throttle without RetryAfterMs uses direct back-off", directRateLimitedErrMsg, 750 * time.Millisecond},
+		{"direct throttle honors RetryAfterMs", directRateLimitedErrMsgWithRetryAfterMs, 120 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expectedResult, retryAfter(te, p, tc.errMsg))
+		})
+	}
+}
+
+func TestDirectThrottleDetectionDisabled(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.DirectThrottleMarker = ""
+
+	// still recognized as rate limited from its wording, albeit not as a direct mode error
+	assert.Equal(t, GatewayThrottle, p.ClassifyThrottle(errors.New(directRateLimitedErrMsg)))
+	assert.Equal(t, NotThrottled, p.ClassifyThrottle(errors.New("Response status code does not indicate success: StatusCode: 429")))
+}
+
+func TestDirectThrottleWithoutDirectBackOffUsesFixedBackOff(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.JitterMode = NoJitter
+	assert.Equal(t, time.Duration(p.FixedBackOffTimeMs)*time.Millisecond, retryAfter(t, p, directRateLimitedErrMsg))
+}
+
+// codeErr is a gocql.RequestError carrying a protocol error code
+type codeErr struct {
+	code    int
+	message string
+}
+
+func (ce codeErr) Code() int       { return ce.code }
+func (ce codeErr) Message() string { return ce.message }
+func (ce codeErr) Error() string   { return ce.message }
+
+func TestThrottleErrorCode(t *testing.T) {
+	type testCase struct {
+		name         string
+		err          error
+		expectedMode ThrottleMode
+		expectedType gocql.RetryType
+		expectedWait time.Duration
+	}
+
+	testCases := []testCase{
+		{"error code recognized without relying on the message", codeErr{defaultThrottleErrorCode, "busy"}, GatewayThrottle, gocql.Retry, 10 * time.Millisecond},
+		{"error code with RetryAfterMs in the message", codeErr{defaultThrottleErrorCode, "busy: ActivityID=abc, RetryAfterMs=7, details"}, GatewayThrottle, gocql.Retry, 7 * time.Millisecond},
+		{"other error codes fall back to the message", codeErr{0x2200, rateLimitedErrMsg}, GatewayThrottle, gocql.Retry, 42 * time.Millisecond},
+		{"other error codes without a rate limited message", codeErr{0x2200, "invalid query"}, NotThrottled, gocql.Rethrow, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.JitterMode = NoJitter
+			p.FixedBackOffTimeMs = 10
+
+			assert.Equal(te, tc.expectedMode, p.ClassifyThrottle(tc.err))
+			assert.Equal(te, tc.expectedType, retryTypeOf(p, tc.err))
+			assert.Equal(te, tc.expectedWait, p.BlockedTime())
+		})
+	}
+}
+
+func TestThrottleErrorCodeDisabled(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.ThrottleErrorCode = 0
+	assert.Equal(t, NotThrottled, p.ClassifyThrottle(codeErr{defaultThrottleErrorCode, "busy"}))
+}
+
+func TestRateLimitedMessageVariants(t *testing.T) {
+	type testCase struct {
+		name     string
+		errMsg   string
+		expected ThrottleMode
+	}
+
+	testCases := []testCase{
+		{"lower case status", "request rate is large: ActivityID=abc, RetryAfterMs=10, Additional details='Response status code does not indicate success: toomanyrequests (429)'", GatewayThrottle},
+		{"status without space", "Response status code does not indicate success: TooManyRequests(429); Substatus: 3200", GatewayThrottle},
+		{"wrapped by gocql", "gocql: error executing query: Server error: REQUEST RATE IS LARGE. More Request Units may be needed", GatewayThrottle},
+		{"status only", "Response status code does not indicate success: (429)", GatewayThrottle},
+		{"unknown wording with RetryAfterMs", "Throttled: ActivityID=abc, RetryAfterMs=10", GatewayThrottle},
+		{"RetryAfterMs without ActivityID", "Service is busy: RetryAfterMs=25; please retry", NotThrottled},
+		{"unrelated error", "line 1:7 no viable alternative at input 'rate'", NotThrottled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			assert.Equal(te, tc.expected, p.ClassifyThrottle(errors.New(tc.errMsg)))
+		})
+	}
+}
+
+func TestContainsFold(t *testing.T) {
+	assert.True(t, containsFold("a REQUEST Rate is large!", "Request rate is large"))
+	assert.True(t, containsFold("(429)", "(429)"))
+	assert.False(t, containsFold("429", "(429)"))
+	assert.False(t, containsFold("", "(429)"))
+	assert.True(t, containsFold("Too many requests: rEQUEST RATE IS LARGE", "Request rate is large"))
+	assert.False(t, containsFold("Request rate is", "Request rate is large"))
+	assert.True(t, containsFold("anything", ""))
+}
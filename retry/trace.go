@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx which tags queries executed with it with requestID. Retries of such queries are traced if tracing has been enabled using EnableTracing
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request id ctx has been tagged with using WithRequestID, or an empty string
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// TraceEntry describes a single retry decision of a traced request
+type TraceEntry struct {
+	Time      time.Time
+	Attempt   int
+	Err       string
+	RetryType gocql.RetryType
+	// DecisionPath is the code path which made the decision (one of the DecisionPath constants)
+	DecisionPath string
+	BackOff      time.Duration
+}
+
+// tracer keeps the trace of up to maxRequests requests. Once full, the oldest request is evicted
+type tracer struct {
+	sync.Mutex
+	maxRequests int
+	order       []string
+	entries     map[string][]TraceEntry
+}
+
+// EnableTracing records the retry decisions of queries tagged using WithRequestID, for up to maxRequests distinct requests (the oldest ones are evicted first). Use Trace to retrieve them. A maxRequests of 0 disables tracing and discards the recorded traces
+func (crp *CosmosRetryPolicy) EnableTracing(maxRequests int) {
+	crp.tracer.Lock()
+	defer crp.tracer.Unlock()
+	crp.tracer.maxRequests = maxRequests
+	crp.tracer.order = nil
+	crp.tracer.entries = nil
+	if maxRequests > 0 {
+		crp.tracer.entries = make(map[string][]TraceEntry, maxRequests)
+	}
+}
+
+// Trace returns the retry decisions recorded for requestID, oldest first. It returns nil if the request has not been traced (or has been evicted)
+func (crp *CosmosRetryPolicy) Trace(requestID string) []TraceEntry {
+	crp.tracer.Lock()
+	defer crp.tracer.Unlock()
+	entries := crp.tracer.entries[requestID]
+	if entries == nil {
+		return nil
+	}
+	return append([]TraceEntry(nil), entries...)
+}
+
+// trace records a retry decision of ex, if it is a traced request
+func (crp *CosmosRetryPolicy) trace(ex *execution, err error, retryType gocql.RetryType, backOff time.Duration) {
+	if ex.requestID == "" {
+		return
+	}
+	t := &crp.tracer
+	t.Lock()
+	defer t.Unlock()
+	if t.maxRequests <= 0 {
+		return
+	}
+	if _, traced := t.entries[ex.requestID]; !traced {
+		if len(t.order) == t.maxRequests {
+			delete(t.entries, t.order[0])
+			t.order = t.order[1:]
+		}
+		t.order = append(t.order, ex.requestID)
+	}
+	t.entries[ex.requestID] = append(t.entries[ex.requestID], TraceEntry{
+		Time:         crp.currentTime(),
+		Attempt:      ex.attempts,
+		Err:          err.Error(),
+		RetryType:    retryType,
+		DecisionPath: ex.path,
+		BackOff:      backOff,
+	})
+}
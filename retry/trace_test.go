@@ -0,0 +1,59 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceCapturesRetriesOfRequest(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	p.FixedBackOffTimeMs = 1
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	p.EnableTracing(10)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	for attempt := 1; attempt <= 3; attempt++ {
+		q := attemptsQuery{MockRetryableQuery{ctx: ctx}, attempt}
+		if p.Attempt(q) {
+			p.GetRetryType(errors.New(rateLimitedErrMsg))
+		}
+	}
+	p.Attempt(MockRetryableQuery{})
+	p.GetRetryType(errors.New(rateLimitedErrMsg))
+
+	expected := []TraceEntry{
+		{Time: now, Attempt: 1, Err: rateLimitedErrMsg, RetryType: gocql.Retry, DecisionPath: DecisionPathServerHinted, BackOff: 42 * time.Millisecond},
+		{Time: now, Attempt: 2, Err: rateLimitedErrMsg, RetryType: gocql.Retry, DecisionPath: DecisionPathServerHinted, BackOff: 42 * time.Millisecond},
+	}
+	assert.Equal(t, expected, p.Trace("req-1"))
+	assert.Nil(t, p.Trace("req-2"))
+}
+
+func TestTraceEvictsOldestRequest(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	p.EnableTracing(2)
+
+	for _, id := range []string{"req-1", "req-2", "req-3"} {
+		p.Attempt(MockRetryableQuery{ctx: WithRequestID(context.Background(), id)})
+		p.GetRetryType(&gocql.RequestErrReadTimeout{})
+	}
+
+	assert.Nil(t, p.Trace("req-1"), "oldest request should have been evicted")
+	assert.Len(t, p.Trace("req-2"), 1)
+	assert.Len(t, p.Trace("req-3"), 1)
+}
+
+func TestTracingDisabledByDefault(t *testing.T) {
+	p := NewCosmosRetryPolicy(2)
+	p.Attempt(MockRetryableQuery{ctx: WithRequestID(context.Background(), "req-1")})
+	p.GetRetryType(&gocql.RequestErrReadTimeout{})
+
+	assert.Nil(t, p.Trace("req-1"))
+	assert.Equal(t, "", RequestID(context.Background()))
+}
@@ -0,0 +1,50 @@
+package retry
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gocql/gocql"
+)
+
+// UnknownErrorPolicy defines how errors which the policy does not classify (neither rate limited, timeouts, unavailable, connection nor not ready errors, e.g. a network blip surfaced as a plain error) are handled
+type UnknownErrorPolicy int
+
+const (
+	// RethrowUnknownErrors rethrows unknown errors (default)
+	RethrowUnknownErrors UnknownErrorPolicy = iota
+	// RetryUnknownErrors retries unknown errors of idempotent queries, up to MaxRetryCount, after the fallback back-off used for rate limited errors without RetryAfterMs
+	RetryUnknownErrors
+)
+
+func (uep UnknownErrorPolicy) String() string {
+	if uep == RetryUnknownErrors {
+		return "retry"
+	}
+	return "rethrow"
+}
+
+// isUnknown reports whether err may be retried as per RetryUnknownErrors. Errors reported by the server (gocql.RequestError, e.g. syntax or authorization errors) and errors of done contexts are never retried
+func isUnknown(err error) bool {
+	var requestErr gocql.RequestError
+	if errors.As(err, &requestErr) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// onUnknownError applies UnknownErrorPolicy to an error that was not classified
+func (crp *CosmosRetryPolicy) onUnknownError(ex *execution, err error) gocql.RetryType {
+	if crp.UnknownErrorPolicy != RetryUnknownErrors || !isUnknown(err) {
+		return gocql.Rethrow
+	}
+	if !ex.idempotent {
+		crp.recordPath(ex, DecisionPathNotIdempotent)
+		return gocql.Rethrow
+	}
+	if !crp.backOff(ex, crp.fallbackBackOff(ex.consistency, ex.attempts, "")) {
+		return gocql.Rethrow
+	}
+	crp.recordPath(ex, DecisionPathUnknownError)
+	return gocql.Retry
+}
@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownErrorPolicy(t *testing.T) {
+	type testCase struct {
+		name            string
+		policy          UnknownErrorPolicy
+		query           gocql.RetryableQuery
+		err             error
+		expectedType    gocql.RetryType
+		expectedPath    string
+		expectedBackOff time.Duration
+	}
+
+	blip := errors.New("read tcp 10.0.0.1:50000->10.0.0.2:10350: i/o timeout")
+	testCases := []testCase{
+		{"rethrow by default", RethrowUnknownErrors, attemptsQuery{attempts: 1}, blip, gocql.Rethrow, DecisionPathNotRetryable, 0},
+		{"retry with back-off", RetryUnknownErrors, attemptsQuery{attempts: 1}, blip, gocql.Retry, DecisionPathUnknownError, 10 * time.Millisecond},
+		{"retry wrapped error with back-off", RetryUnknownErrors, attemptsQuery{attempts: 1}, fmt.Errorf("query failed: %w", blip), gocql.Retry, DecisionPathUnknownError, 10 * time.Millisecond},
+		{"not idempotent query is not retried", RetryUnknownErrors, idempotentQuery{}, blip, gocql.Rethrow, DecisionPathNotIdempotent, 0},
+		{"server error is not retried", RetryUnknownErrors, attemptsQuery{attempts: 1}, structuredRequestError{code: 0x2000, message: "line 1:0 no viable alternative at input 'SELEC'"}, gocql.Rethrow, DecisionPathNotRetryable, 0},
+		{"canceled context is not retried", RetryUnknownErrors, attemptsQuery{attempts: 1}, context.Canceled, gocql.Rethrow, DecisionPathNotRetryable, 0},
+		{"ambiguous error is left to ParseFailureAction", RetryUnknownErrors, attemptsQuery{attempts: 1}, errors.New(ambiguousErrMsg), gocql.Rethrow, DecisionPathNotRetryable, 0},
+		{"rate limited error is unaffected", RetryUnknownErrors, attemptsQuery{attempts: 1}, errors.New(rateLimitedErrMsg), gocql.Retry, DecisionPathServerHinted, 42 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.JitterMode = NoJitter
+			p.FixedBackOffTimeMs = 10
+			p.SleepDisabled = true
+			p.UnknownErrorPolicy = tc.policy
+			var events []RetryEvent
+			p.OnRetry = func(e RetryEvent) { events = append(events, e) }
+
+			assert.True(te, p.Attempt(tc.query))
+			assert.Equal(te, tc.expectedType, p.GetRetryType(tc.err))
+			assert.Equal(te, tc.expectedPath, events[0].DecisionPath)
+			assert.Equal(te, tc.expectedBackOff, events[0].BackOff)
+		})
+	}
+}
+
+func TestUnknownErrorsAreRetriedUpToMaxRetryCount(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	p.SleepDisabled = true
+	p.UnknownErrorPolicy = RetryUnknownErrors
+	blip := errors.New("connection reset by peer")
+
+	retries := 0
+	for attempt := 1; p.Attempt(attemptsQuery{attempts: attempt}); attempt++ {
+		if p.GetRetryType(blip) != gocql.Retry {
+			break
+		}
+		retries++
+	}
+	assert.Equal(t, 3, retries)
+}
+
+func TestUnknownErrorPolicyDefaultsToRethrow(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	assert.Equal(t, RethrowUnknownErrors, p.UnknownErrorPolicy)
+	assert.Equal(t, "rethrow", p.UnknownErrorPolicy.String())
+	assert.Equal(t, "retry", RetryUnknownErrors.String())
+}
@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const negativeBackOffWarning = "negative back-off %v (check the back-off fields of the policy): retrying without delay"
+
+// Validate returns an error describing the first invalid setting of the policy, if any: MaxRetryCount must be -1 (infinite) or more, and back-off (and other time or count based) settings must not be negative. MaxBackOffTimeMs is exempt, since 0 or less means no cap. BuildCosmosRetryPolicy and ParseConfig validate the policies they return, whereas settings changed afterwards are only checked if Validate is called again
+func (crp *CosmosRetryPolicy) Validate() error {
+	if crp.MaxRetryCount < -1 {
+		return fmt.Errorf("invalid MaxRetryCount %d: must be -1 (infinite) or more", crp.MaxRetryCount)
+	}
+	nonNegative := []struct {
+		name  string
+		value int
+	}{
+		{"FixedBackOffTimeMs", crp.FixedBackOffTimeMs},
+		{"GrowingBackOffTimeMs", crp.GrowingBackOffTimeMs},
+		{"MinBackOffTimeMs", crp.MinBackOffTimeMs},
+		{"DirectBackOffTimeMs", crp.DirectBackOffTimeMs},
+		{"SecondaryRegionBackOffMs", crp.SecondaryRegionBackOffMs},
+		{"NotReadyBackOffTimeMs", crp.NotReadyBackOffTimeMs},
+		{"MaxBlockedTimeMs", crp.MaxBlockedTimeMs},
+		{"MaxElapsedTimeMs", crp.MaxElapsedTimeMs},
+		{"AbsoluteMaxSleepMs", crp.AbsoluteMaxSleepMs},
+		{"MaxRateLimitedRetries", crp.MaxRateLimitedRetries},
+		{"ImmediateRetries", crp.ImmediateRetries},
+		{"MaxHostHops", crp.MaxHostHops},
+		{"DowngradeConsistencyAfter", crp.DowngradeConsistencyAfter},
+		{"SustainedThrottleThreshold", crp.SustainedThrottleThreshold},
+		{"SoftRetryWarnThreshold", crp.SoftRetryWarnThreshold},
+	}
+	for _, f := range nonNegative {
+		if f.value < 0 {
+			return fmt.Errorf("invalid %s %d: must not be negative", f.name, f.value)
+		}
+	}
+	if crp.JitterFraction < 0 || crp.JitterFraction > 1 {
+		return fmt.Errorf("invalid JitterFraction %v: must be between 0 and 1", crp.JitterFraction)
+	}
+	for c, d := range crp.ConsistencyBackOff {
+		if d < 0 {
+			return fmt.Errorf("invalid ConsistencyBackOff %v for %v: must not be negative", d, c)
+		}
+	}
+	for code, d := range crp.SubStatusBackOff {
+		if d < 0 {
+			return fmt.Errorf("invalid SubStatusBackOff %v for substatus %d: must not be negative", d, code)
+		}
+	}
+	for i, bound := range crp.RetryAfterBuckets {
+		if bound < 0 || i > 0 && bound <= crp.RetryAfterBuckets[i-1] {
+			return fmt.Errorf("invalid RetryAfterBuckets %v: must be non-negative and increasing", crp.RetryAfterBuckets)
+		}
+	}
+	return nil
+}
+
+// clampBackOff returns d, or 0 if it is negative, which only happens if the policy has been misconfigured after it was validated. A one-time warning is logged in that case
+func (crp *CosmosRetryPolicy) clampBackOff(d time.Duration) time.Duration {
+	if d >= 0 {
+		return d
+	}
+	if crp.Logger != nil && atomic.CompareAndSwapInt32(&crp.warnedNegative, 0, 1) {
+		crp.Logger.Printf(negativeBackOffWarning, d)
+	}
+	return 0
+}
@@ -0,0 +1,139 @@
+package retry
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		name      string
+		configure func(p *CosmosRetryPolicy)
+		expected  string
+	}
+
+	testCases := []testCase{
+		{"default policy", func(p *CosmosRetryPolicy) {}, ""},
+		{"infinite retries", func(p *CosmosRetryPolicy) { p.MaxRetryCount = -1 }, ""},
+		{"no retries", func(p *CosmosRetryPolicy) { p.MaxRetryCount = 0 }, ""},
+		{"negative max back-off means no cap", func(p *CosmosRetryPolicy) { p.MaxBackOffTimeMs = -1 }, ""},
+		{"max retry count below -1", func(p *CosmosRetryPolicy) { p.MaxRetryCount = -2 }, "invalid MaxRetryCount -2: must be -1 (infinite) or more"},
+		{"negative fixed back-off", func(p *CosmosRetryPolicy) { p.FixedBackOffTimeMs = -1 }, "invalid FixedBackOffTimeMs -1: must not be negative"},
+		{"negative growing back-off", func(p *CosmosRetryPolicy) { p.GrowingBackOffTimeMs = -1 }, "invalid GrowingBackOffTimeMs -1: must not be negative"},
+		{"negative min back-off", func(p *CosmosRetryPolicy) { p.MinBackOffTimeMs = -1 }, "invalid MinBackOffTimeMs -1: must not be negative"},
+		{"negative direct back-off", func(p *CosmosRetryPolicy) { p.DirectBackOffTimeMs = -1 }, "invalid DirectBackOffTimeMs -1: must not be negative"},
+		{"negative secondary region back-off", func(p *CosmosRetryPolicy) { p.SecondaryRegionBackOffMs = -1 }, "invalid SecondaryRegionBackOffMs -1: must not be negative"},
+		{"negative not ready back-off", func(p *CosmosRetryPolicy) { p.NotReadyBackOffTimeMs = -1 }, "invalid NotReadyBackOffTimeMs -1: must not be negative"},
+		{"negative max blocked time", func(p *CosmosRetryPolicy) { p.MaxBlockedTimeMs = -1 }, "invalid MaxBlockedTimeMs -1: must not be negative"},
+		{"negative max elapsed time", func(p *CosmosRetryPolicy) { p.MaxElapsedTimeMs = -1 }, "invalid MaxElapsedTimeMs -1: must not be negative"},
+		{"negative absolute max sleep", func(p *CosmosRetryPolicy) { p.AbsoluteMaxSleepMs = -1 }, "invalid AbsoluteMaxSleepMs -1: must not be negative"},
+		{"negative max rate limited retries", func(p *CosmosRetryPolicy) { p.MaxRateLimitedRetries = -1 }, "invalid MaxRateLimitedRetries -1: must not be negative"},
+		{"negative immediate retries", func(p *CosmosRetryPolicy) { p.ImmediateRetries = -1 }, "invalid ImmediateRetries -1: must not be negative"},
+		{"negative max host hops", func(p *CosmosRetryPolicy) { p.MaxHostHops = -1 }, "invalid MaxHostHops -1: must not be negative"},
+		{"negative downgrade threshold", func(p *CosmosRetryPolicy) { p.DowngradeConsistencyAfter = -1 }, "invalid DowngradeConsistencyAfter -1: must not be negative"},
+		{"negative sustained throttle threshold", func(p *CosmosRetryPolicy) { p.SustainedThrottleThreshold = -1 }, "invalid SustainedThrottleThreshold -1: must not be negative"},
+		{"negative soft retry warn threshold", func(p *CosmosRetryPolicy) { p.SoftRetryWarnThreshold = -1 }, "invalid SoftRetryWarnThreshold -1: must not be negative"},
+		{"jitter fraction above 1", func(p *CosmosRetryPolicy) { p.JitterFraction = 1.5 }, "invalid JitterFraction 1.5: must be between 0 and 1"},
+		{"negative jitter fraction", func(p *CosmosRetryPolicy) { p.JitterFraction = -0.1 }, "invalid JitterFraction -0.1: must be between 0 and 1"},
+		{"negative consistency back-off", func(p *CosmosRetryPolicy) {
+			p.ConsistencyBackOff = map[gocql.Consistency]time.Duration{gocql.Quorum: -time.Second}
+		}, "invalid ConsistencyBackOff -1s for QUORUM: must not be negative"},
+		{"negative substatus back-off", func(p *CosmosRetryPolicy) {
+			p.SubStatusBackOff = map[int]time.Duration{3200: -time.Second}
+		}, "invalid SubStatusBackOff -1s for substatus 3200: must not be negative"},
+		{"increasing retry after buckets", func(p *CosmosRetryPolicy) { p.RetryAfterBuckets = []time.Duration{0, time.Millisecond, time.Second} }, ""},
+		{"negative retry after bucket", func(p *CosmosRetryPolicy) { p.RetryAfterBuckets = []time.Duration{-time.Millisecond} }, "invalid RetryAfterBuckets [-1ms]: must be non-negative and increasing"},
+		{"unordered retry after buckets", func(p *CosmosRetryPolicy) {
+			p.RetryAfterBuckets = []time.Duration{time.Second, time.Millisecond}
+		}, "invalid RetryAfterBuckets [1s 1ms]: must be non-negative and increasing"},
+		{"duplicate retry after buckets", func(p *CosmosRetryPolicy) {
+			p.RetryAfterBuckets = []time.Duration{time.Second, time.Second}
+		}, "invalid RetryAfterBuckets [1s 1s]: must be non-negative and increasing"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(5)
+			tc.configure(p)
+			err := p.Validate()
+			if tc.expected == "" {
+				assert.NoError(te, err)
+				return
+			}
+			assert.EqualError(te, err, tc.expected)
+		})
+	}
+}
+
+func TestBuildCosmosRetryPolicyRejectsInvalidMaxRetryCount(t *testing.T) {
+	for _, maxRetryCount := range []int{-2, -100} {
+		p, err := BuildCosmosRetryPolicy(maxRetryCount)
+		assert.Error(t, err)
+		assert.Nil(t, p)
+	}
+	for _, maxRetryCount := range []int{-1, 0, 100} {
+		_, err := BuildCosmosRetryPolicy(maxRetryCount)
+		assert.NoError(t, err)
+	}
+}
+
+func TestNewCosmosRetryPolicyCorrectsMisconfiguration(t *testing.T) {
+	type testCase struct {
+		name          string
+		maxRetryCount int
+		opts          []Option
+		expected      int
+		warning       string
+	}
+
+	testCases := []testCase{
+		{"max retry count below -1", -2, nil, -1, "invalid MaxRetryCount -2: must be -1 (infinite) or more (using -1)"},
+		{"invalid option", 5, []Option{WithFixedBackOff(-time.Second)}, 5, "invalid fixed back-off -1s: must not be negative (ignored)"},
+		{"valid settings", 5, []Option{WithFixedBackOff(time.Second)}, 5, ""},
+	}
+
+	defer log.SetOutput(log.Writer())
+	defer log.SetFlags(log.Flags())
+	log.SetFlags(0)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+
+			var p *CosmosRetryPolicy
+			assert.NotPanics(te, func() { p = NewCosmosRetryPolicy(tc.maxRetryCount, tc.opts...) })
+			assert.Equal(te, tc.expected, p.MaxRetryCount)
+			assert.NoError(te, p.Validate())
+			if tc.warning == "" {
+				assert.Empty(te, buf.String())
+				return
+			}
+			assert.Equal(te, "cosmos retry policy misconfigured: "+tc.warning+"\n", buf.String(), "warning should be logged once")
+		})
+	}
+}
+
+func TestNegativeBackOffClampedWithWarning(t *testing.T) {
+	logger := &capturingLogger{}
+	p := NewCosmosRetryPolicy(-1)
+	p.Logger = logger
+	p.JitterMode = NoJitter
+	// misconfigured after construction
+	p.GrowingBackOffTimeMs = -100
+	var slept []time.Duration
+	p.sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+
+	for i := 1; i <= 3; i++ {
+		assert.True(t, p.Attempt(attemptsQuery{MockRetryableQuery{}, i}))
+		assert.Equal(t, gocql.Retry, p.GetRetryType(errors.New(rateLimitedErrMsgWithoutRetryAfterMs)))
+	}
+	assert.Equal(t, []time.Duration{0, 0, 0}, slept)
+	assert.Equal(t, 1, logger.count("negative back-off"), "warning should be logged once")
+	assert.Error(t, p.Validate())
+}
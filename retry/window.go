@@ -0,0 +1,22 @@
+package retry
+
+import "time"
+
+const day = 24 * time.Hour
+
+// DailyWindow returns a RetryWindow predicate which allows retries between from and to, both expressed as offsets since midnight (in the location of the evaluated time). Windows spanning midnight (from > to) are supported
+func DailyWindow(from, to time.Duration) func(time.Time) bool {
+	return func(t time.Time) bool {
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		offset := t.Sub(midnight) % day
+		if from <= to {
+			return offset >= from && offset < to
+		}
+		return offset >= from || offset < to
+	}
+}
+
+// retryWindowOpen reports whether retries are allowed at the current time as per RetryWindow
+func (crp *CosmosRetryPolicy) retryWindowOpen() bool {
+	return crp.RetryWindow == nil || crp.RetryWindow(crp.currentTime())
+}
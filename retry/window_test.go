@@ -0,0 +1,44 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyWindow(t *testing.T) {
+	type testCase struct {
+		name     string
+		from, to time.Duration
+		at       time.Time
+		expected bool
+	}
+
+	testCases := []testCase{
+		{"inside window", 1 * time.Hour, 5 * time.Hour, time.Date(2020, 6, 1, 3, 0, 0, 0, time.UTC), true},
+		{"at window start", 1 * time.Hour, 5 * time.Hour, time.Date(2020, 6, 1, 1, 0, 0, 0, time.UTC), true},
+		{"at window end", 1 * time.Hour, 5 * time.Hour, time.Date(2020, 6, 1, 5, 0, 0, 0, time.UTC), false},
+		{"outside window", 1 * time.Hour, 5 * time.Hour, time.Date(2020, 6, 1, 14, 0, 0, 0, time.UTC), false},
+		{"inside window spanning midnight", 22 * time.Hour, 6 * time.Hour, time.Date(2020, 6, 1, 23, 30, 0, 0, time.UTC), true},
+		{"inside window spanning midnight after midnight", 22 * time.Hour, 6 * time.Hour, time.Date(2020, 6, 1, 2, 0, 0, 0, time.UTC), true},
+		{"outside window spanning midnight", 22 * time.Hour, 6 * time.Hour, time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, DailyWindow(tc.from, tc.to)(tc.at))
+		})
+	}
+}
+
+func TestRetryOnlyWithinWindow(t *testing.T) {
+	p := NewCosmosRetryPolicy(5)
+	p.RetryWindow = DailyWindow(22*time.Hour, 6*time.Hour)
+
+	p.now = func() time.Time { return time.Date(2020, 6, 1, 23, 0, 0, 0, time.UTC) }
+	assert.True(t, p.Attempt(MockRetryableQuery{}), "query should be retried inside the window")
+
+	p.now = func() time.Time { return time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC) }
+	assert.False(t, p.Attempt(MockRetryableQuery{}), "query should not be retried outside the window")
+}
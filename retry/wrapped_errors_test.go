@@ -0,0 +1,44 @@
+package retry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrappedErrorsClassifiedLikeUnwrapped(t *testing.T) {
+	errs := map[string]error{
+		"RequestErrReadTimeout":  &gocql.RequestErrReadTimeout{},
+		"RequestErrWriteTimeout": &gocql.RequestErrWriteTimeout{WriteType: "SIMPLE"},
+		"RequestErrUnavailable":  &gocql.RequestErrUnavailable{},
+		"throttle error code":    codeErr{code: defaultThrottleErrorCode, message: "overloaded"},
+	}
+	wrappers := map[string]func(error) error{
+		"once":  func(err error) error { return fmt.Errorf("exec: %w", err) },
+		"twice": func(err error) error { return fmt.Errorf("session: %w", fmt.Errorf("exec: %w", err)) },
+	}
+
+	for name, err := range errs {
+		for depth, wrap := range wrappers {
+			t.Run(name+" wrapped "+depth, func(te *testing.T) {
+				unwrapped, wrapped := NewCosmosRetryPolicy(3), NewCosmosRetryPolicy(3)
+				unwrapped.FixedBackOffTimeMs, wrapped.FixedBackOffTimeMs = 1, 1
+
+				expected := retryTypeOf(unwrapped, err)
+				assert.Equal(te, expected, retryTypeOf(wrapped, wrap(err)))
+				assert.Equal(te, unwrapped.LastDecisionPath(), wrapped.LastDecisionPath())
+				assert.Equal(te, unwrapped.ClassifyError(err), wrapped.ClassifyError(wrap(err)))
+			})
+		}
+	}
+}
+
+func TestWrappedTimeoutRetriedImmediately(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+	assert.True(t, p.Attempt(attemptsQuery{attempts: 1}))
+	assert.Equal(t, gocql.Retry, p.GetRetryType(fmt.Errorf("exec: %w", &gocql.RequestErrReadTimeout{})))
+	assert.Equal(t, DecisionPathTimeoutImmediate, p.LastDecisionPath())
+	assert.Equal(t, TransientError, p.ClassifyError(fmt.Errorf("exec: %w", &gocql.RequestErrUnavailable{})))
+}
@@ -0,0 +1,44 @@
+package retry
+
+import "github.com/gocql/gocql"
+
+// CosmosRetryPolicyWrapper implements gocql.RetryPolicy by layering the handling of rate limited (429) errors of a CosmosRetryPolicy over another gocql.RetryPolicy (e.g. gocql.ExponentialBackoffRetryPolicy or gocql.DowngradingConsistencyRetryPolicy), which handles every other error. Every retry is subject to the Attempt of the Cosmos policy (MaxRetryCount, RetryWindow and Limiter), whereas retries of errors other than rate limited ones are also subject to the Attempt of the inner policy, which is consulted once the error is known
+//
+//	cluster.RetryPolicy = retry.WrapRetryPolicy(&gocql.ExponentialBackoffRetryPolicy{NumRetries: 5}, retry.NewCosmosRetryPolicy(-1))
+type CosmosRetryPolicyWrapper struct {
+	// Cosmos handles rate limited errors, as classified by its ClassifyError
+	Cosmos *CosmosRetryPolicy
+	// Inner handles any other error
+	Inner gocql.RetryPolicy
+}
+
+var _ gocql.RetryPolicy = (*CosmosRetryPolicyWrapper)(nil)
+
+// WrapRetryPolicy returns a CosmosRetryPolicyWrapper which handles rate limited errors as per crp, and delegates every other error to inner
+func WrapRetryPolicy(inner gocql.RetryPolicy, crp *CosmosRetryPolicy) *CosmosRetryPolicyWrapper {
+	return &CosmosRetryPolicyWrapper{Cosmos: crp, Inner: inner}
+}
+
+// Attempt decides whether to retry or not as per the Attempt of the Cosmos policy. The inner policy is consulted by GetRetryType, once it is known whether the error is a rate limited one
+func (w *CosmosRetryPolicyWrapper) Attempt(q gocql.RetryableQuery) bool {
+	return w.Cosmos.Attempt(q)
+}
+
+// GetRetryType determines the RetryType of rate limited errors as per the Cosmos policy, and of any other error as per the inner policy. The latter is rethrown if the Attempt of the inner policy does not allow another attempt
+func (w *CosmosRetryPolicyWrapper) GetRetryType(err error) gocql.RetryType {
+	ex := w.Cosmos.claimExecution()
+	if w.Cosmos.ClassifyError(err) == RateLimitedError {
+		return w.Cosmos.decide(ex, err)
+	}
+
+	retryType := gocql.Rethrow
+	if ex.query == nil || w.Inner.Attempt(ex.query) {
+		retryType = w.Inner.GetRetryType(err)
+	}
+	ex.decision = retryType
+	if retryType != gocql.Retry && retryType != gocql.RetryNextHost {
+		w.Cosmos.finish(ex, false)
+	}
+	w.Cosmos.storeExecution(ex)
+	return retryType
+}
@@ -0,0 +1,101 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockPolicy is a gocql.RetryPolicy recording the calls made to it
+type mockPolicy struct {
+	allow     bool
+	retryType gocql.RetryType
+	attempts  int32
+	errs      int32
+}
+
+func (mp *mockPolicy) Attempt(gocql.RetryableQuery) bool {
+	atomic.AddInt32(&mp.attempts, 1)
+	return mp.allow
+}
+
+func (mp *mockPolicy) GetRetryType(error) gocql.RetryType {
+	atomic.AddInt32(&mp.errs, 1)
+	return mp.retryType
+}
+
+func TestCosmosRetryPolicyWrapper(t *testing.T) {
+	type testCase struct {
+		name             string
+		inner            *mockPolicy
+		err              error
+		expected         gocql.RetryType
+		expectedDelegate bool
+	}
+
+	testCases := []testCase{
+		{"rate limited error handled by the Cosmos policy", &mockPolicy{allow: true, retryType: gocql.Rethrow}, errors.New(rateLimitedErrMsg), gocql.Retry, false},
+		{"direct mode rate limited error handled by the Cosmos policy", &mockPolicy{allow: true, retryType: gocql.Rethrow}, errors.New(directRateLimitedErrMsgWithRetryAfterMs), gocql.Retry, false},
+		{"timeout delegated", &mockPolicy{allow: true, retryType: gocql.RetryNextHost}, &gocql.RequestErrReadTimeout{}, gocql.RetryNextHost, true},
+		{"other error delegated", &mockPolicy{allow: true, retryType: gocql.Ignore}, errors.New("error: today is not your day!"), gocql.Ignore, true},
+		{"other error rethrown if the inner policy does not allow another attempt", &mockPolicy{allow: false, retryType: gocql.Retry}, &gocql.RequestErrUnavailable{}, gocql.Rethrow, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+			p.sleepFunc = func(time.Duration) {}
+			w := WrapRetryPolicy(tc.inner, p)
+
+			assert.True(te, w.Attempt(MockRetryableQuery{}))
+			assert.Equal(te, tc.expected, w.GetRetryType(tc.err))
+
+			rateLimited := p.ClassifyError(tc.err) == RateLimitedError
+			assert.Equal(te, !rateLimited, tc.inner.attempts == 1, "inner Attempt should only be consulted for other errors")
+			assert.Equal(te, tc.expectedDelegate, tc.inner.errs == 1)
+		})
+	}
+}
+
+func TestCosmosRetryPolicyWrapperAttempt(t *testing.T) {
+	inner := &mockPolicy{allow: true}
+	p := NewCosmosRetryPolicy(1)
+	w := WrapRetryPolicy(inner, p)
+
+	// the Cosmos policy bounds every retry
+	assert.True(t, w.Attempt(attemptsQuery{MockRetryableQuery{}, 1}))
+	w.GetRetryType(&gocql.RequestErrWriteTimeout{})
+	assert.False(t, w.Attempt(attemptsQuery{MockRetryableQuery{}, 2}))
+	assert.Equal(t, int32(1), inner.attempts)
+}
+
+func TestCosmosRetryPolicyWrapperConcurrentQueries(t *testing.T) {
+	inner := &mockPolicy{allow: true, retryType: gocql.Retry}
+	p := NewCosmosRetryPolicy(5)
+	p.sleepFunc = func(time.Duration) {}
+	w := WrapRetryPolicy(inner, p)
+
+	const queries = 16
+	var wg sync.WaitGroup
+	for i := 0; i < queries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			q := MockRetryableQuery{ctx: WithAttemptScope(context.Background())}
+			err := error(&gocql.RequestErrWriteTimeout{})
+			if i%2 == 0 {
+				err = errors.New(rateLimitedErrMsg)
+			}
+			assert.True(t, w.Attempt(q))
+			assert.Equal(t, gocql.Retry, w.GetRetryType(err))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(queries/2), atomic.LoadInt32(&inner.errs))
+}
@@ -0,0 +1,40 @@
+package retry
+
+import (
+	"regexp"
+	"sync/atomic"
+
+	"github.com/gocql/gocql"
+)
+
+// writeForbiddenHostHops bounds the number of hosts a write forbidden error is retried on if MaxHostHops is not set, so that the write is given up on (rather than bouncing across read regions forever) if no other host accepts it
+const writeForbiddenHostHops = 3
+
+const writeForbiddenAdvisory = "write forbidden (403) by the region of the coordinator, retrying on the next host: route writes to the write region of the account, e.g. using a DC aware host selection policy"
+
+// writeForbiddenPattern matches the 403 (substatus 3, WriteForbidden) errors returned, in gateway or direct mode, for writes sent to a read region of an account with a single write region
+var writeForbiddenPattern = regexp.MustCompile(`(?i)writeforbidden|(forbidden \(403\)|statuscode: 403)\s*[;,]\s*substatus(code)?: 3\b`)
+
+// isWriteForbidden reports whether errMsg is a write forbidden (403) error
+func isWriteForbidden(errMsg string) bool {
+	return writeForbiddenPattern.MatchString(errMsg)
+}
+
+// retryWriteForbidden retries a write forbidden error on the next host, since retrying it on the same host is bound to fail again. It is rethrown once the query has hopped across MaxHostHops hosts or, if not set, writeForbiddenHostHops hosts. A one-time advisory is logged, since the error points at the routing of writes
+func (crp *CosmosRetryPolicy) retryWriteForbidden(ex *execution) gocql.RetryType {
+	crp.recordThrottle(ex, false)
+	if crp.Logger != nil && atomic.CompareAndSwapInt32(&crp.warnedWriteForbidden, 0, 1) {
+		crp.Logger.Printf(writeForbiddenAdvisory)
+	}
+	maxHops := crp.MaxHostHops
+	if maxHops <= 0 {
+		maxHops = writeForbiddenHostHops
+	}
+	if ex.hostHops >= maxHops {
+		crp.recordPath(ex, DecisionPathHostHopsExhausted)
+		return gocql.Rethrow
+	}
+	ex.hostHops++
+	crp.recordPath(ex, DecisionPathWriteForbidden)
+	return gocql.RetryNextHost
+}
@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+const writeForbiddenErrMsg = `Response status code does not indicate success: Forbidden (403); Substatus: 3; ActivityId: 5b1e2c3d-4f5a-6b7c-8d9e-0f1a2b3c4d5e; Reason: ({"Errors":["The requested operation cannot be performed at this region"]})`
+
+const directWriteForbiddenErrMsg = `Response status code does not indicate success: StatusCode: 403, SubStatusCode: 3, ActivityId: 5b1e2c3d-4f5a-6b7c-8d9e-0f1a2b3c4d5e, Reason: WriteForbidden`
+
+func TestIsWriteForbidden(t *testing.T) {
+	type testCase struct {
+		name     string
+		errMsg   string
+		expected bool
+	}
+
+	testCases := []testCase{
+		{"gateway mode", writeForbiddenErrMsg, true},
+		{"direct mode", directWriteForbiddenErrMsg, true},
+		{"status name only", "server error: WriteForbidden", true},
+		{"other 403 substatus", `Response status code does not indicate success: Forbidden (403); Substatus: 3200; ActivityId: 5b1e2c3d-4f5a-6b7c-8d9e-0f1a2b3c4d5e`, false},
+		{"rate limited error", rateLimitedErrMsg, false},
+		{"unrelated error", "error: today is not your day!", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			assert.Equal(te, tc.expected, isWriteForbidden(tc.errMsg))
+		})
+	}
+}
+
+func TestWriteForbiddenRetriesOnNextHost(t *testing.T) {
+	type testCase struct {
+		name         string
+		err          error
+		maxHostHops  int
+		expectedType []gocql.RetryType
+	}
+
+	hops := []gocql.RetryType{gocql.RetryNextHost, gocql.RetryNextHost, gocql.RetryNextHost, gocql.Rethrow, gocql.Rethrow}
+	testCases := []testCase{
+		{"gateway mode", errors.New(writeForbiddenErrMsg), 0, hops},
+		{"direct mode", errors.New(directWriteForbiddenErrMsg), 0, hops},
+		{"wrapped error", fmt.Errorf("insert failed: %w", errors.New(writeForbiddenErrMsg)), 0, hops},
+		{"bounded by MaxHostHops", errors.New(writeForbiddenErrMsg), 1, []gocql.RetryType{gocql.RetryNextHost, gocql.Rethrow, gocql.Rethrow, gocql.Rethrow, gocql.Rethrow}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(10)
+			p.MaxHostHops = tc.maxHostHops
+
+			var decisions []gocql.RetryType
+			for attempt := 1; attempt <= 5; attempt++ {
+				assert.True(te, p.Attempt(attemptsQuery{attempts: attempt}))
+				decision := p.GetRetryType(tc.err)
+				decisions = append(decisions, decision)
+				if decision == gocql.RetryNextHost {
+					assert.Equal(te, DecisionPathWriteForbidden, p.LastDecisionPath())
+				} else {
+					assert.Equal(te, DecisionPathHostHopsExhausted, p.LastDecisionPath())
+				}
+			}
+			assert.Equal(te, tc.expectedType, decisions)
+		})
+	}
+}
+
+func TestWriteForbiddenAdvisoryIsLoggedOnce(t *testing.T) {
+	p := NewCosmosRetryPolicy(10)
+	logger := &capturingLogger{}
+	p.Logger = logger
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		assert.True(t, p.Attempt(attemptsQuery{attempts: attempt}))
+		assert.Equal(t, gocql.RetryNextHost, p.GetRetryType(errors.New(writeForbiddenErrMsg)))
+	}
+	assert.Equal(t, 1, logger.count("write forbidden (403)"))
+	assert.Equal(t, uint64(0), p.Metrics().RateLimitedRetries)
+}
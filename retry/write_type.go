@@ -0,0 +1,24 @@
+package retry
+
+import "github.com/gocql/gocql"
+
+// safeWriteTypes are the write types of RequestErrWriteTimeout errors which are safe to retry whether or not the query is idempotent. A BATCH_LOG timeout means the batch was not applied yet, and will be replayed from the batch log if it was written
+var safeWriteTypes = map[string]bool{"BATCH_LOG": true}
+
+// retryWriteTimeout retries a write timeout like other timeouts, provided doing so cannot apply the write twice: its write type is safe or the query is idempotent
+func (crp *CosmosRetryPolicy) retryWriteTimeout(ex *execution, err *gocql.RequestErrWriteTimeout) gocql.RetryType {
+	if !safeWriteTypes[err.WriteType] && !ex.idempotent {
+		crp.recordPath(ex, DecisionPathUnsafeWrite)
+		return gocql.Rethrow
+	}
+	return crp.retryTimeout(ex)
+}
+
+// retryReadTimeout retries a read timeout like other timeouts, provided the query is idempotent
+func (crp *CosmosRetryPolicy) retryReadTimeout(ex *execution) gocql.RetryType {
+	if !ex.idempotent {
+		crp.recordPath(ex, DecisionPathNotIdempotent)
+		return gocql.Rethrow
+	}
+	return crp.retryTimeout(ex)
+}
@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTimeoutRetriesByWriteType(t *testing.T) {
+	type testCase struct {
+		name         string
+		writeType    string
+		idempotent   bool
+		expectedType gocql.RetryType
+		expectedPath string
+	}
+
+	testCases := []testCase{
+		{"batch log write is retried", "BATCH_LOG", false, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"counter write is rethrown", "COUNTER", false, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"simple write is rethrown", "SIMPLE", false, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"batch write is rethrown", "BATCH", false, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"idempotent counter write is retried", "COUNTER", true, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"idempotent simple write is retried", "SIMPLE", true, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"write type not reported is rethrown", "", false, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"idempotent write type not reported is retried", "", true, gocql.Retry, DecisionPathTimeoutImmediate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+
+			assert.True(te, p.Attempt(idempotentQuery{idempotent: tc.idempotent}))
+			assert.Equal(te, tc.expectedType, p.GetRetryType(&gocql.RequestErrWriteTimeout{WriteType: tc.writeType}))
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+		})
+	}
+}
+
+func TestTimeoutsRetriedForIdempotentQueriesOnly(t *testing.T) {
+	type testCase struct {
+		name         string
+		err          error
+		idempotent   bool
+		expectedType gocql.RetryType
+		expectedPath string
+	}
+
+	testCases := []testCase{
+		{"read timeout of idempotent query", &gocql.RequestErrReadTimeout{}, true, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"read timeout of non idempotent query", &gocql.RequestErrReadTimeout{}, false, gocql.Rethrow, DecisionPathNotIdempotent},
+		{"write timeout of idempotent query", &gocql.RequestErrWriteTimeout{WriteType: "SIMPLE"}, true, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"write timeout of non idempotent query", &gocql.RequestErrWriteTimeout{WriteType: "SIMPLE"}, false, gocql.Rethrow, DecisionPathUnsafeWrite},
+		{"unavailable error of idempotent query", &gocql.RequestErrUnavailable{}, true, gocql.Retry, DecisionPathTimeoutImmediate},
+		{"unavailable error of non idempotent query", &gocql.RequestErrUnavailable{}, false, gocql.Retry, DecisionPathTimeoutImmediate},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(te *testing.T) {
+			p := NewCosmosRetryPolicy(3)
+
+			assert.True(te, p.Attempt(idempotentQuery{idempotent: tc.idempotent}))
+			assert.Equal(te, tc.expectedType, p.GetRetryType(tc.err))
+			assert.Equal(te, tc.expectedPath, p.LastDecisionPath())
+		})
+	}
+}
+
+func TestTimeoutOfQueryWithoutIdempotencyInfoRethrown(t *testing.T) {
+	p := NewCosmosRetryPolicy(3)
+
+	assert.True(t, p.Attempt(MockRetryableQuery{}))
+	assert.Equal(t, gocql.Rethrow, p.GetRetryType(&gocql.RequestErrReadTimeout{}))
+}